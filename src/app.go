@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kihw/multy/src/config"
+	"github.com/kihw/multy/src/services"
+)
+
+// app holds the services and applies the configuration to them, at startup
+// and whenever the configuration file changes.
+type app struct {
+	// mu serializes applying the configuration and saving the changes made
+	// at runtime.
+	mu      sync.Mutex
+	config  *config.Store
+	applied *config.Config
+
+	windowService     *services.WindowService
+	wheelClickService *services.WheelClickService
+	shortcutService   *services.ShortcutService
+	startTurnService  *services.StartTurnService
+	dofusCheckService *services.DofusCheckService
+	characterService  *services.CharacterService
+	keyBroadcast      *services.KeyBroadcastService
+	serviceManager    *services.ServiceManager
+	macroService      *services.MacroService
+	layoutService     *services.LayoutService
+}
+
+// registerShortcutActions makes the actions other than focusing a window
+// available to shortcuts.
+func (a *app) registerShortcutActions() {
+	a.shortcutService.RegisterAction("focusNext", func(string) error {
+		_, err := a.characterService.FocusNext()
+		return err
+	})
+	a.shortcutService.RegisterAction("focusPrevious", func(string) error {
+		_, err := a.characterService.FocusPrevious()
+		return err
+	})
+	a.shortcutService.RegisterAction("toggleKeyBroadcast", func(string) error {
+		state, err := a.serviceManager.Toggle(a.keyBroadcast.Name())
+		if err == nil {
+			log.Printf("Key broadcast %s", state)
+		}
+		return err
+	})
+	// The argument of the macro actions is the name of the macro.
+	a.shortcutService.RegisterAction("playMacro", func(name string) error {
+		_, err := a.macroService.Play(name, services.PlayOptions{})
+		return err
+	})
+	a.shortcutService.RegisterAction("recordMacro", func(name string) error {
+		if _, recording := a.macroService.Recording(); recording {
+			_, err := a.macroService.StopRecording()
+			return err
+		}
+		return a.macroService.StartRecording(name)
+	})
+	a.shortcutService.RegisterAction("stopMacro", func(string) error {
+		return a.macroService.StopPlayback()
+	})
+	// The argument is tile, cascade or stack, optionally followed by the
+	// monitor number, e.g. "tile 1".
+	a.shortcutService.RegisterAction("arrangeWindows", func(argument string) error {
+		arrangement, monitor, err := parseArrangement(argument)
+		if err != nil {
+			return err
+		}
+		_, err = a.layoutService.Arrange(arrangement, monitor)
+		return err
+	})
+	// The argument is the name of a saved layout.
+	a.shortcutService.RegisterAction("applyLayout", func(name string) error {
+		_, err := a.layoutService.ApplyLayout(name)
+		return err
+	})
+}
+
+// persistShortcuts saves the shortcuts registered at runtime to the
+// configuration file, so that they survive a restart.
+func (a *app) persistShortcuts(shortcuts []services.Shortcut) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.config.Update(func(cfg *config.Config) {
+		cfg.Shortcuts = toConfigShortcuts(shortcuts)
+	})
+	if err != nil {
+		log.Printf("Failed to save shortcuts to %s: %v", a.config.Path(), err)
+	}
+}
+
+// persistKeyBroadcast saves the key broadcast settings changed at runtime to
+// the configuration file.
+func (a *app) persistKeyBroadcast(settings services.KeyBroadcastSettings) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.config.Update(func(cfg *config.Config) {
+		cfg.KeyBroadcast = config.KeyBroadcast{
+			Keys:     settings.Keys,
+			Leader:   settings.Leader,
+			Excluded: settings.Excluded,
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to save key broadcast settings to %s: %v", a.config.Path(), err)
+	}
+}
+
+// persistLayouts saves the layouts changed at runtime to the configuration
+// file.
+func (a *app) persistLayouts(layouts []services.Layout) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.config.Update(func(cfg *config.Config) {
+		cfg.Layouts = toConfigLayouts(layouts)
+	})
+	if err != nil {
+		log.Printf("Failed to save layouts to %s: %v", a.config.Path(), err)
+	}
+}
+
+// applyConfig brings the services in line with the configuration.
+func (a *app) applyConfig(cfg *config.Config) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	previous := a.applied
+	if previous == nil {
+		previous = &config.Config{Listen: cfg.Listen, Backend: cfg.Backend}
+	}
+	if cfg.Listen != previous.Listen || cfg.Backend != previous.Backend {
+		log.Println("Changes to listen and backend take effect after a restart.")
+	}
+
+	team := make([]services.Character, 0, len(cfg.Team))
+	for _, member := range cfg.Team {
+		team = append(team, services.Character{Name: member.Name, Slot: member.Slot})
+	}
+	a.characterService.SetTeam(team)
+
+	if !reflect.DeepEqual(toConfigShortcuts(a.shortcutService.Shortcuts()), cfg.Shortcuts) {
+		shortcuts := make([]services.Shortcut, 0, len(cfg.Shortcuts))
+		for _, shortcut := range cfg.Shortcuts {
+			shortcuts = append(shortcuts, services.Shortcut{
+				Key:        shortcut.Key,
+				Action:     shortcut.Action,
+				WindowName: shortcut.Window,
+				Argument:   shortcut.Argument,
+			})
+		}
+		if err := a.shortcutService.SetShortcuts(shortcuts); err != nil {
+			log.Printf("Failed to register shortcuts: %v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(toConfigLayouts(a.layoutService.Layouts()), cfg.Layouts) {
+		layouts := make([]services.Layout, 0, len(cfg.Layouts))
+		for _, layout := range cfg.Layouts {
+			windows := make([]services.LayoutWindow, 0, len(layout.Windows))
+			for _, window := range layout.Windows {
+				windows = append(windows, services.LayoutWindow{
+					Character: window.Character,
+					Rect: services.Rect{
+						Left:   window.Left,
+						Top:    window.Top,
+						Right:  window.Left + window.Width,
+						Bottom: window.Top + window.Height,
+					},
+				})
+			}
+			layouts = append(layouts, services.Layout{Name: layout.Name, Windows: windows})
+		}
+		if err := a.layoutService.SetLayouts(layouts); err != nil {
+			log.Printf("Failed to load layouts: %v", err)
+		}
+	}
+
+	a.wheelClickService.SetBroadcastSettings(services.BroadcastSettings{
+		TitleFilter:   cfg.Broadcast.TitleFilter,
+		Targets:       cfg.Broadcast.Targets,
+		ExcludeSource: cfg.Broadcast.ExcludeSource,
+		Mode:          cfg.Broadcast.Mode,
+		CursorDelay:   cfg.Broadcast.CursorDelay,
+		Order:         cfg.Broadcast.Order,
+		Leader:        cfg.Broadcast.Leader,
+		Delay:         cfg.Broadcast.Delay,
+		Jitter:        cfg.Broadcast.Jitter,
+		Parallel:      cfg.Broadcast.Parallel,
+	})
+
+	err := a.keyBroadcast.LoadSettings(services.KeyBroadcastSettings{
+		Keys:     cfg.KeyBroadcast.Keys,
+		Leader:   cfg.KeyBroadcast.Leader,
+		Excluded: cfg.KeyBroadcast.Excluded,
+	})
+	if err != nil {
+		log.Printf("Failed to apply key broadcast settings: %v", err)
+	}
+
+	a.serviceManager.SetRestartPolicy(services.RestartPolicy{
+		MaxAttempts: cfg.Services.Restart.MaxAttempts,
+		Backoff:     cfg.Services.Restart.Backoff,
+		MaxBackoff:  cfg.Services.Restart.MaxBackoff,
+	})
+	a.applyServices(previous.Services, cfg.Services)
+	a.applied = cfg
+}
+
+// applyServices starts and stops the services whose automatic start changed.
+func (a *app) applyServices(previous, current config.Services) {
+	if current.WheelClick != previous.WheelClick {
+		a.setServiceRunning(a.wheelClickService, current.WheelClick)
+	}
+
+	if current.DofusCheck != previous.DofusCheck {
+		a.setServiceRunning(a.dofusCheckService, current.DofusCheck)
+	}
+
+	if current.KeyBroadcast != previous.KeyBroadcast {
+		a.setServiceRunning(a.keyBroadcast, current.KeyBroadcast)
+	}
+
+	if !reflect.DeepEqual(current.StartTurn, previous.StartTurn) {
+		if previous.StartTurn.Enabled {
+			a.setServiceRunning(a.startTurnService, false)
+		}
+		a.startTurnService.SetWindows(current.StartTurn.WindowList())
+		if current.StartTurn.Enabled {
+			a.setServiceRunning(a.startTurnService, true)
+		}
+	}
+}
+
+// setServiceRunning starts or stops a service, logging the failures.
+func (a *app) setServiceRunning(service services.Service, running bool) {
+	var err error
+	if running {
+		err = a.serviceManager.Start(service.Name())
+	} else {
+		err = a.serviceManager.Stop(service.Name())
+	}
+	if err != nil {
+		log.Printf("Failed to apply the state of service %s: %v", service.Name(), err)
+	}
+}
+
+func toConfigShortcuts(shortcuts []services.Shortcut) []config.Shortcut {
+	var result []config.Shortcut
+	for _, shortcut := range shortcuts {
+		result = append(result, config.Shortcut{
+			Key:      shortcut.Key,
+			Action:   shortcut.Action,
+			Window:   shortcut.WindowName,
+			Argument: shortcut.Argument,
+		})
+	}
+	return result
+}
+
+func toConfigLayouts(layouts []services.Layout) []config.Layout {
+	var result []config.Layout
+	for _, layout := range layouts {
+		var windows []config.LayoutWindow
+		for _, window := range layout.Windows {
+			windows = append(windows, config.LayoutWindow{
+				Character: window.Character,
+				Left:      window.Rect.Left,
+				Top:       window.Rect.Top,
+				Width:     window.Rect.Width(),
+				Height:    window.Rect.Height(),
+			})
+		}
+		result = append(result, config.Layout{Name: layout.Name, Windows: windows})
+	}
+	return result
+}
+
+// parseArrangement splits the argument of the arrangeWindows action.
+func parseArrangement(argument string) (string, int, error) {
+	fields := strings.Fields(argument)
+	switch len(fields) {
+	case 1:
+		return fields[0], 0, nil
+	case 2:
+		monitor, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid monitor number: %q", fields[1])
+		}
+		return fields[0], monitor, nil
+	default:
+		return "", 0, fmt.Errorf("invalid arrangement: %q", argument)
+	}
+}
@@ -0,0 +1,339 @@
+// Package config loads, validates and persists the Multy configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file used when none is given.
+const DefaultPath = "multy.yaml"
+
+// Config is the content of the configuration file.
+type Config struct {
+	// Listen is the address of the HTTP server, e.g. ":8080".
+	Listen string `yaml:"listen"`
+	// Backend is the window backend: auto, win32, x11 or fake.
+	Backend   string       `yaml:"backend"`
+	Team      []TeamMember `yaml:"team"`
+	Shortcuts []Shortcut   `yaml:"shortcuts"`
+	Broadcast Broadcast    `yaml:"broadcast"`
+	// KeyBroadcast configures the keys broadcast to the team.
+	KeyBroadcast KeyBroadcast `yaml:"keyBroadcast"`
+	// Layouts are the saved arrangements of the team windows.
+	Layouts  []Layout `yaml:"layouts"`
+	Services Services `yaml:"services"`
+}
+
+// TeamMember is a character of the team and its slot number.
+type TeamMember struct {
+	Name string `yaml:"name"`
+	Slot int    `yaml:"slot"`
+}
+
+// Shortcut is a hotkey focusing a window, or running another action such as
+// focusNext or playMacro, with an optional argument such as a macro name.
+type Shortcut struct {
+	Key      string `yaml:"key"`
+	Action   string `yaml:"action,omitempty"`
+	Window   string `yaml:"window,omitempty"`
+	Argument string `yaml:"argument,omitempty"`
+}
+
+// Layout is a named arrangement of the team windows.
+type Layout struct {
+	Name    string         `yaml:"name"`
+	Windows []LayoutWindow `yaml:"windows"`
+}
+
+// LayoutWindow is the position and size of the window of a character,
+// decorations included.
+type LayoutWindow struct {
+	Character string `yaml:"character"`
+	Left      int    `yaml:"left"`
+	Top       int    `yaml:"top"`
+	Width     int    `yaml:"width"`
+	Height    int    `yaml:"height"`
+}
+
+// Broadcast holds the click broadcasting settings.
+type Broadcast struct {
+	// TitleFilter selects the windows receiving broadcast clicks, unless
+	// Targets is set.
+	TitleFilter string `yaml:"titleFilter"`
+	// Targets lists the windows receiving broadcast clicks, by character
+	// name, slot or part of the title.
+	Targets []string `yaml:"targets,omitempty"`
+	// ExcludeSource leaves out the window clicked.
+	ExcludeSource bool `yaml:"excludeSource,omitempty"`
+	// Mode is the click delivery: foreground, moving the cursor and
+	// activating each window, or background, posting mouse messages.
+	Mode string `yaml:"mode"`
+	// CursorDelay is the pause between moving the cursor and clicking.
+	CursorDelay time.Duration `yaml:"cursorDelay"`
+	// Order is the order of the windows clicked: windows, in z-order, or
+	// team, by slot.
+	Order string `yaml:"order,omitempty"`
+	// Leader moves the window clicked first or last.
+	Leader string `yaml:"leader,omitempty"`
+	// Delay is the pause before clicking each window after the first, and
+	// Jitter the upper bound of a random pause added to it.
+	Delay  time.Duration `yaml:"delay,omitempty"`
+	Jitter time.Duration `yaml:"jitter,omitempty"`
+	// Parallel clicks every window at once, in the background mode.
+	Parallel bool `yaml:"parallel,omitempty"`
+}
+
+// KeyBroadcast holds the keyboard broadcasting settings.
+type KeyBroadcast struct {
+	// Keys is the allow-list of keys broadcast, such as "1" or "Enter".
+	Keys []string `yaml:"keys"`
+	// Leader is the character whose keys are broadcast, or any team member
+	// when empty.
+	Leader string `yaml:"leader,omitempty"`
+	// Excluded lists the characters not receiving the keys.
+	Excluded []string `yaml:"excluded"`
+}
+
+// Services lists the services started automatically.
+type Services struct {
+	WheelClick   bool      `yaml:"wheelClick"`
+	DofusCheck   bool      `yaml:"dofusCheck"`
+	KeyBroadcast bool      `yaml:"keyBroadcast"`
+	StartTurn    StartTurn `yaml:"startTurn"`
+	Restart      Restart   `yaml:"restart"`
+}
+
+// Restart configures the restart of the services failing to start or while
+// they run. The delay between attempts doubles from Backoff up to MaxBackoff.
+type Restart struct {
+	// MaxAttempts is the number of restarts before giving up, 0 disables
+	// the restarts.
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+// StartTurn configures the automatic start of the turn detection service.
+// Window and Windows list the windows monitored, by character name or part
+// of the title. The whole team is monitored when none is given.
+type StartTurn struct {
+	Enabled bool     `yaml:"enabled"`
+	Window  string   `yaml:"window,omitempty"`
+	Windows []string `yaml:"windows,omitempty"`
+}
+
+// WindowList returns the windows to monitor, from Window and Windows.
+func (s StartTurn) WindowList() []string {
+	var windows []string
+	if s.Window != "" {
+		windows = append(windows, s.Window)
+	}
+	return append(windows, s.Windows...)
+}
+
+// Default returns the configuration used when no file exists.
+func Default() *Config {
+	return &Config{
+		Listen:  ":8080",
+		Backend: "auto",
+		Broadcast: Broadcast{
+			TitleFilter: "Dofus",
+			Mode:        "foreground",
+			CursorDelay: 50 * time.Millisecond,
+			Order:       "windows",
+		},
+		Services: Services{
+			Restart: Restart{
+				MaxAttempts: 5,
+				Backoff:     time.Second,
+				MaxBackoff:  time.Minute,
+			},
+		},
+	}
+}
+
+// Validate checks the configuration and returns every problem found.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		errs = append(errs, fmt.Errorf("listen: invalid address %q: %v", c.Listen, err))
+	}
+
+	switch c.Backend {
+	case "", "auto", "win32", "x11", "fake":
+	default:
+		errs = append(errs, fmt.Errorf("backend: unknown window backend %q", c.Backend))
+	}
+
+	names := make(map[string]bool)
+	slots := make(map[int]bool)
+	for i, member := range c.Team {
+		name := strings.ToLower(strings.TrimSpace(member.Name))
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("team[%d]: name is empty", i))
+		case names[name]:
+			errs = append(errs, fmt.Errorf("team[%d]: duplicate character %q", i, member.Name))
+		}
+		names[name] = true
+
+		switch {
+		case member.Slot < 0:
+			errs = append(errs, fmt.Errorf("team[%d]: invalid slot %d", i, member.Slot))
+		case member.Slot > 0 && slots[member.Slot]:
+			errs = append(errs, fmt.Errorf("team[%d]: slot %d is already assigned", i, member.Slot))
+		}
+		slots[member.Slot] = true
+	}
+
+	keys := make(map[string]bool)
+	for i, shortcut := range c.Shortcuts {
+		switch {
+		case shortcut.Key == "":
+			errs = append(errs, fmt.Errorf("shortcuts[%d]: key is empty", i))
+		case keys[shortcut.Key]:
+			errs = append(errs, fmt.Errorf("shortcuts[%d]: key %q is already bound", i, shortcut.Key))
+		}
+		keys[shortcut.Key] = true
+		if (shortcut.Action == "" || shortcut.Action == "focus") && shortcut.Window == "" {
+			errs = append(errs, fmt.Errorf("shortcuts[%d]: window is empty", i))
+		}
+	}
+
+	layouts := make(map[string]bool)
+	for i, layout := range c.Layouts {
+		name := strings.ToLower(strings.TrimSpace(layout.Name))
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("layouts[%d]: name is empty", i))
+		case layouts[name]:
+			errs = append(errs, fmt.Errorf("layouts[%d]: layout %q is already defined", i, layout.Name))
+		}
+		layouts[name] = true
+		for j, window := range layout.Windows {
+			if strings.TrimSpace(window.Character) == "" {
+				errs = append(errs, fmt.Errorf("layouts[%d].windows[%d]: character is empty", i, j))
+			}
+			if window.Width <= 0 || window.Height <= 0 {
+				errs = append(errs, fmt.Errorf("layouts[%d].windows[%d]: width and height must be positive", i, j))
+			}
+		}
+	}
+
+	switch c.Broadcast.Mode {
+	case "", "foreground", "background":
+	default:
+		errs = append(errs, fmt.Errorf("broadcast.mode: unknown click delivery mode %q", c.Broadcast.Mode))
+	}
+	if c.Broadcast.CursorDelay < 0 {
+		errs = append(errs, fmt.Errorf("broadcast.cursorDelay: must not be negative"))
+	}
+	for i, target := range c.Broadcast.Targets {
+		if strings.TrimSpace(target) == "" {
+			errs = append(errs, fmt.Errorf("broadcast.targets[%d]: target is empty", i))
+		}
+	}
+	switch c.Broadcast.Order {
+	case "", "windows", "team":
+	default:
+		errs = append(errs, fmt.Errorf("broadcast.order: unknown order %q", c.Broadcast.Order))
+	}
+	switch c.Broadcast.Leader {
+	case "", "first", "last":
+	default:
+		errs = append(errs, fmt.Errorf("broadcast.leader: must be first or last, got %q", c.Broadcast.Leader))
+	}
+	if c.Broadcast.Delay < 0 {
+		errs = append(errs, fmt.Errorf("broadcast.delay: must not be negative"))
+	}
+	if c.Broadcast.Jitter < 0 {
+		errs = append(errs, fmt.Errorf("broadcast.jitter: must not be negative"))
+	}
+	if c.Broadcast.Parallel && c.Broadcast.Mode != "background" {
+		errs = append(errs, fmt.Errorf("broadcast.parallel: requires the background mode"))
+	}
+
+	for i, key := range c.KeyBroadcast.Keys {
+		if strings.TrimSpace(key) == "" {
+			errs = append(errs, fmt.Errorf("keyBroadcast.keys[%d]: key is empty", i))
+		}
+	}
+
+	restart := c.Services.Restart
+	if restart.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("services.restart.maxAttempts: must not be negative"))
+	}
+	if restart.Backoff < 0 || restart.MaxBackoff < 0 {
+		errs = append(errs, fmt.Errorf("services.restart: delays must not be negative"))
+	} else if restart.MaxBackoff < restart.Backoff {
+		errs = append(errs, fmt.Errorf("services.restart.maxBackoff: must not be less than backoff"))
+	} else if restart.MaxBackoff == 0 && restart.MaxAttempts > 0 {
+		errs = append(errs, fmt.Errorf("services.restart.maxBackoff: must be positive when restarts are enabled"))
+	}
+
+	for i, window := range c.Services.StartTurn.Windows {
+		if strings.TrimSpace(window) == "" {
+			errs = append(errs, fmt.Errorf("services.startTurn.windows[%d]: window is empty", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Load reads and validates the configuration file. Missing settings keep
+// their default value.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, err)
+	}
+	return cfg.Clone(), nil
+}
+
+// Save writes the configuration file.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// Write then rename, so that the watcher never reads a partial file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clone returns a deep copy of the configuration. Empty lists are copied as
+// nil, so that configurations can be compared with reflect.DeepEqual.
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Team = append([]TeamMember(nil), c.Team...)
+	clone.Shortcuts = append([]Shortcut(nil), c.Shortcuts...)
+	clone.Layouts = nil
+	for _, layout := range c.Layouts {
+		layout.Windows = append([]LayoutWindow(nil), layout.Windows...)
+		clone.Layouts = append(clone.Layouts, layout)
+	}
+	clone.Broadcast.Targets = append([]string(nil), c.Broadcast.Targets...)
+	clone.KeyBroadcast.Keys = append([]string(nil), c.KeyBroadcast.Keys...)
+	clone.KeyBroadcast.Excluded = append([]string(nil), c.KeyBroadcast.Excluded...)
+	clone.Services.StartTurn.Windows = append([]string(nil), c.Services.StartTurn.Windows...)
+	return &clone
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		// want is part of the error expected, or empty for a valid
+		// configuration.
+		want string
+	}{
+		{"default", func(cfg *Config) {}, ""},
+		{"listen", func(cfg *Config) { cfg.Listen = "8080" }, "listen: invalid address"},
+		{"backend", func(cfg *Config) { cfg.Backend = "wayland" }, "unknown window backend"},
+		{"team without a name", func(cfg *Config) {
+			cfg.Team = []TeamMember{{Name: " ", Slot: 1}}
+		}, "team[0]: name is empty"},
+		{"duplicate character", func(cfg *Config) {
+			cfg.Team = []TeamMember{{Name: "Iop", Slot: 1}, {Name: "iop", Slot: 2}}
+		}, `team[1]: duplicate character "iop"`},
+		{"duplicate slot", func(cfg *Config) {
+			cfg.Team = []TeamMember{{Name: "Iop", Slot: 1}, {Name: "Cra", Slot: 1}}
+		}, "team[1]: slot 1 is already assigned"},
+		{"unslotted members", func(cfg *Config) {
+			cfg.Team = []TeamMember{{Name: "Iop"}, {Name: "Cra"}}
+		}, ""},
+		{"duplicate shortcut", func(cfg *Config) {
+			cfg.Shortcuts = []Shortcut{{Key: "F1", Window: "Iop"}, {Key: "F1", Window: "Cra"}}
+		}, `shortcuts[1]: key "F1" is already bound`},
+		{"focus without a window", func(cfg *Config) {
+			cfg.Shortcuts = []Shortcut{{Key: "F1", Action: "focus"}}
+		}, "shortcuts[0]: window is empty"},
+		{"action without a window", func(cfg *Config) {
+			cfg.Shortcuts = []Shortcut{{Key: "F1", Action: "focusNext"}}
+		}, ""},
+		{"layout window", func(cfg *Config) {
+			cfg.Layouts = []Layout{{Name: "grid", Windows: []LayoutWindow{{Character: "Iop"}}}}
+		}, "layouts[0].windows[0]: width and height must be positive"},
+		{"duplicate layout", func(cfg *Config) {
+			cfg.Layouts = []Layout{{Name: "Grid"}, {Name: "grid"}}
+		}, `layouts[1]: layout "grid" is already defined`},
+		{"broadcast mode", func(cfg *Config) { cfg.Broadcast.Mode = "sideways" }, "unknown click delivery mode"},
+		{"parallel in the foreground", func(cfg *Config) { cfg.Broadcast.Parallel = true }, "requires the background mode"},
+		{"negative delay", func(cfg *Config) { cfg.Broadcast.Delay = -time.Second }, "broadcast.delay"},
+		{"empty key", func(cfg *Config) { cfg.KeyBroadcast.Keys = []string{"1", ""} }, "keyBroadcast.keys[1]"},
+		{"negative attempts", func(cfg *Config) { cfg.Services.Restart.MaxAttempts = -1 }, "maxAttempts: must not be negative"},
+		{"backoff above the maximum", func(cfg *Config) {
+			cfg.Services.Restart.Backoff = time.Hour
+		}, "maxBackoff: must not be less than backoff"},
+		{"restarts without a delay", func(cfg *Config) {
+			cfg.Services.Restart = Restart{MaxAttempts: 3}
+		}, "maxBackoff: must be positive"},
+		{"restarts disabled", func(cfg *Config) {
+			cfg.Services.Restart = Restart{}
+		}, ""},
+		{"empty monitored window", func(cfg *Config) {
+			cfg.Services.StartTurn.Windows = []string{""}
+		}, "services.startTurn.windows[0]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			test.modify(cfg)
+			err := cfg.Validate()
+			switch {
+			case test.want == "" && err != nil:
+				t.Fatalf("Validate: %v, want no error", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Fatalf("Validate: %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Listen = "8080"
+	cfg.Backend = "wayland"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "listen") || !strings.Contains(err.Error(), "backend") {
+		t.Fatalf("Validate: %v, want both the listen and backend errors", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "multy.yaml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Load: %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "multy.yaml")
+	if err := os.WriteFile(path, []byte("listen: \":9090\"\nteam:\n  - name: Iop\n    slot: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Default()
+	want.Listen = ":9090"
+	want.Team = []TeamMember{{Name: "Iop", Slot: 1}}
+	if !reflect.DeepEqual(cfg, want.Clone()) {
+		t.Fatalf("Load = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"syntax":  "team: [",
+		"invalid": "backend: wayland\n",
+	} {
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("Load(%s): %v, want an error naming the file", name, err)
+		}
+	}
+}
+
+func TestSaveThenLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "multy.yaml")
+	cfg := Default()
+	cfg.Team = []TeamMember{{Name: "Iop", Slot: 1}, {Name: "Cra", Slot: 2}}
+	cfg.Shortcuts = []Shortcut{{Key: "F1", Window: "Iop"}}
+	cfg.Layouts = []Layout{{Name: "grid", Windows: []LayoutWindow{{Character: "Iop", Width: 800, Height: 600}}}}
+	cfg.Broadcast.Delay = 150 * time.Millisecond
+
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg.Clone()) {
+		t.Fatalf("Load = %+v, want %+v", loaded, cfg)
+	}
+
+	// The temporary file is renamed over the configuration file.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "multy.yaml" {
+		t.Fatalf("directory holds %v, want only multy.yaml", entries)
+	}
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	cfg := Default()
+	cfg.Team = []TeamMember{{Name: "Iop", Slot: 1}}
+	cfg.Layouts = []Layout{{Name: "grid", Windows: []LayoutWindow{{Character: "Iop", Width: 800, Height: 600}}}}
+
+	clone := cfg.Clone()
+	clone.Team[0].Name = "Cra"
+	clone.Layouts[0].Windows[0].Width = 1
+
+	if cfg.Team[0].Name != "Iop" || cfg.Layouts[0].Windows[0].Width != 800 {
+		t.Fatalf("changing the clone changed the configuration: %+v", cfg)
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the bursts of events editors produce when saving.
+const reloadDelay = 200 * time.Millisecond
+
+// Store holds the current configuration, persists changes made at runtime and
+// reloads the file when it is edited.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	current   *Config
+	listeners []func(*Config)
+}
+
+// Open loads the configuration file at path, creating it with the default
+// configuration if it does not exist.
+func Open(path string) (*Store, error) {
+	cfg, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Configuration file %s not found, creating it with defaults", path)
+		cfg = Default()
+		err = cfg.Save(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, current: cfg}, nil
+}
+
+// Path returns the path of the configuration file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Current returns a copy of the current configuration.
+func (s *Store) Current() *Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Clone()
+}
+
+// OnChange registers a function called with the new configuration whenever
+// the file is reloaded.
+func (s *Store) OnChange(listener func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Update modifies the configuration and saves it to the file.
+func (s *Store) Update(update func(cfg *Config)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.current.Clone()
+	update(cfg)
+	if reflect.DeepEqual(cfg, s.current) {
+		return nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Save(s.path); err != nil {
+		return err
+	}
+	s.current = cfg
+	return nil
+}
+
+// reload reads the file again and notifies the listeners if it changed. An
+// invalid file is reported and the current configuration kept.
+func (s *Store) reload() {
+	cfg, err := Load(s.path)
+	if err != nil {
+		log.Printf("Configuration not reloaded: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	if reflect.DeepEqual(cfg, s.current) {
+		s.mu.Unlock()
+		return
+	}
+	s.current = cfg
+	listeners := append([]func(*Config){}, s.listeners...)
+	s.mu.Unlock()
+
+	log.Printf("Configuration reloaded from %s", s.path)
+	for _, listener := range listeners {
+		listener(cfg.Clone())
+	}
+}
+
+// Watch reloads the configuration whenever the file changes, until stop is
+// closed. The directory is watched since editors often replace the file.
+func (s *Store) Watch(stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		name := filepath.Clean(s.path)
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != name || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, s.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Configuration watcher error: %v", err)
+			case <-stop:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), DefaultPath))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return store
+}
+
+// writeTeam writes a configuration file with a team of one character.
+func writeTeam(t *testing.T, path, name string) {
+	t.Helper()
+	cfg := Default()
+	cfg.Team = []TeamMember{{Name: name, Slot: 1}}
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listener records the configurations it is called with.
+type listener struct {
+	mu      sync.Mutex
+	configs []*Config
+}
+
+func (l *listener) onChange(cfg *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.configs = append(l.configs, cfg)
+}
+
+func (l *listener) calls() []*Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*Config(nil), l.configs...)
+}
+
+func TestOpenCreatesTheDefaultConfiguration(t *testing.T) {
+	store := openStore(t)
+
+	cfg, err := Load(store.Path())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Listen != Default().Listen || store.Current().Listen != Default().Listen {
+		t.Fatalf("configuration listens on %q, want the default", cfg.Listen)
+	}
+}
+
+func TestUpdatePersistsChanges(t *testing.T) {
+	store := openStore(t)
+
+	err := store.Update(func(cfg *Config) {
+		cfg.Team = append(cfg.Team, TeamMember{Name: "Iop", Slot: 1})
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	cfg, err := Load(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Team) != 1 || cfg.Team[0].Name != "Iop" {
+		t.Fatalf("file holds the team %+v, want Iop", cfg.Team)
+	}
+	if team := store.Current().Team; len(team) != 1 || team[0].Name != "Iop" {
+		t.Fatalf("Current().Team = %+v, want Iop", team)
+	}
+}
+
+func TestUpdateSkipsUnchangedConfigurations(t *testing.T) {
+	store := openStore(t)
+	if err := os.Remove(store.Path()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Update(func(cfg *Config) { cfg.Listen = Default().Listen }); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := os.Stat(store.Path()); !os.IsNotExist(err) {
+		t.Fatalf("the file was written for an unchanged configuration: %v", err)
+	}
+}
+
+func TestUpdateRejectsInvalidConfigurations(t *testing.T) {
+	store := openStore(t)
+
+	if err := store.Update(func(cfg *Config) { cfg.Backend = "wayland" }); err == nil {
+		t.Fatal("Update: no error for an unknown backend")
+	}
+	if backend := store.Current().Backend; backend != Default().Backend {
+		t.Fatalf("Current().Backend = %q, want the previous one", backend)
+	}
+	cfg, err := Load(store.Path())
+	if err != nil || cfg.Backend != Default().Backend {
+		t.Fatalf("Load = %+v, %v, want the previous file", cfg, err)
+	}
+}
+
+func TestReloadNotifiesChanges(t *testing.T) {
+	store := openStore(t)
+	var changes listener
+	store.OnChange(changes.onChange)
+
+	// An unchanged file is not reported.
+	store.reload()
+	if calls := changes.calls(); len(calls) != 0 {
+		t.Fatalf("listener called %d times for an unchanged file", len(calls))
+	}
+
+	writeTeam(t, store.Path(), "Iop")
+	store.reload()
+	calls := changes.calls()
+	if len(calls) != 1 || len(calls[0].Team) != 1 || calls[0].Team[0].Name != "Iop" {
+		t.Fatalf("listener called with %+v, want the team Iop", calls)
+	}
+	if team := store.Current().Team; len(team) != 1 || team[0].Name != "Iop" {
+		t.Fatalf("Current().Team = %+v, want Iop", team)
+	}
+
+	// An invalid file keeps the current configuration.
+	if err := os.WriteFile(store.Path(), []byte("backend: wayland\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store.reload()
+	if calls := changes.calls(); len(calls) != 1 {
+		t.Fatalf("listener called %d times, want no call for an invalid file", len(calls))
+	}
+	if team := store.Current().Team; len(team) != 1 {
+		t.Fatalf("Current().Team = %+v, want the team kept", team)
+	}
+}
+
+func TestWatchGroupsWrites(t *testing.T) {
+	store := openStore(t)
+	var changes listener
+	store.OnChange(changes.onChange)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := store.Watch(stop); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	for _, name := range []string{"Iop", "Cra", "Eni"} {
+		writeTeam(t, store.Path(), name)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(changes.calls()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("configuration not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(2 * reloadDelay)
+
+	calls := changes.calls()
+	if len(calls) != 1 {
+		t.Fatalf("configuration reloaded %d times, want once", len(calls))
+	}
+	if team := calls[0].Team; len(team) != 1 || team[0].Name != "Eni" {
+		t.Fatalf("reloaded the team %+v, want the last one written", team)
+	}
+}
@@ -20,90 +20,127 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/dofus-check/start": {
-            "post": {
-                "description": "Starts the DofusCheckService which monitors the Dofus window state",
+        "/characters": {
+            "get": {
+                "description": "Discovers the running game clients and lists the registered characters ordered by slot",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "DofusCheck"
+                    "Characters"
                 ],
-                "summary": "Start monitoring Dofus window",
+                "summary": "List characters",
                 "responses": {
                     "200": {
-                        "description": "DofusCheck service started successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Character"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Error occurred while starting the service",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/dofus-check/stop": {
+            },
             "post": {
-                "description": "Stops the DofusCheckService which monitors the Dofus window state",
+                "description": "Registers a character ahead of its client, in the given slot or the first free one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "DofusCheck"
+                    "Characters"
+                ],
+                "summary": "Register a character",
+                "parameters": [
+                    {
+                        "description": "Character name and optional slot",
+                        "name": "character",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CharacterRequest"
+                        }
+                    }
                 ],
-                "summary": "Stop monitoring Dofus window",
                 "responses": {
-                    "200": {
-                        "description": "DofusCheck service stopped successfully",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/services.Character"
                         }
                     },
-                    "500": {
-                        "description": "Error occurred while stopping the service",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/focus/{keyword}": {
-            "post": {
-                "description": "Met en avant une fenêtre qui contient un mot-clé spécifique dans son titre",
+        "/characters/{id}": {
+            "get": {
+                "description": "Returns the character designated by its name or slot number",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Windows"
+                    "Characters"
                 ],
-                "summary": "Met en avant une fenêtre spécifique",
+                "summary": "Get a character",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Mot-clé pour identifier la fenêtre",
-                        "name": "keyword",
+                        "description": "Character name or slot number",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Fenêtre mise en avant avec succès",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/services.Character"
                         }
                     },
-                    "500": {
-                        "description": "Erreur lors de la mise en avant de la fenêtre",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/shortcut/register/{key}/{windowName}": {
-            "post": {
-                "description": "Registers a hotkey to focus on a window",
+            },
+            "put": {
+                "description": "Moves the character designated by its name or slot number to another slot",
                 "consumes": [
                     "application/json"
                 ],
@@ -111,28 +148,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Shortcut"
+                    "Characters"
                 ],
-                "summary": "Register a hotkey",
+                "summary": "Update a character",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Key to register",
-                        "name": "key",
+                        "description": "Character name or slot number",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Name of the window to focus",
-                        "name": "windowName",
-                        "in": "path",
-                        "required": true
+                        "description": "New slot",
+                        "name": "character",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CharacterRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Character"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -140,8 +185,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -150,56 +204,69 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/shortcut/unregister": {
+            },
             "delete": {
-                "description": "Unregisters a previously registered keyboard shortcut",
+                "description": "Removes the character designated by its name or slot number from the registry",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Shortcut"
+                    "Characters"
+                ],
+                "summary": "Remove a character",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Character name or slot number",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Unregister an existing hotkey",
                 "responses": {
                     "200": {
-                        "description": "Raccourci désenregistré avec succès",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "500": {
-                        "description": "Failed to unregister shortcut",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/start-turn/start": {
-            "get": {
-                "description": "Démarre le service pour écouter les événements d'une fenêtre spécifiée par son titre",
-                "consumes": [
-                    "application/json"
-                ],
+        "/characters/{id}/focus": {
+            "post": {
+                "description": "Brings the client of the character designated by its name or slot number to the foreground",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "StartTurn"
+                    "Characters"
                 ],
-                "summary": "Démarrer le service de détection d'événements pour une fenêtre spécifique",
+                "summary": "Focus a character",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Titre de la fenêtre à surveiller",
-                        "name": "windowTitle",
-                        "in": "query",
+                        "description": "Character name or slot number",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Service démarré avec succès",
+                        "description": "OK",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -207,8 +274,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Erreur si le service est déjà en cours ou si windowTitle est manquant",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Character has no open client",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -219,19 +304,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/start-turn/stop": {
-            "get": {
-                "description": "Arrête le service d'écoute des événements sur une fenêtre",
-                "produces": [
-                    "application/json"
-                ],
+        "/dofus-check/start": {
+            "post": {
+                "description": "Starts the DofusCheckService which monitors the Dofus window state",
                 "tags": [
-                    "StartTurn"
+                    "DofusCheck"
                 ],
-                "summary": "Arrêter le service de détection d'événements",
+                "summary": "Start monitoring Dofus window",
                 "responses": {
                     "200": {
-                        "description": "Service arrêté avec succès",
+                        "description": "DofusCheck service started successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Service already running",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -239,8 +327,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Erreur si le service n'est pas en cours",
+                    "500": {
+                        "description": "Error occurred while starting the service",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -251,19 +339,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/wheelclick/start": {
+        "/dofus-check/stop": {
             "post": {
-                "description": "Listens for middle mouse clicks and triggers click simulation on Dofus windows.",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Stops the DofusCheckService which monitors the Dofus window state",
                 "tags": [
-                    "WheelClick"
+                    "DofusCheck"
                 ],
-                "summary": "Start middle mouse click detection",
+                "summary": "Stop monitoring Dofus window",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "DofusCheck service stopped successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Service not running",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -272,7 +363,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Error occurred while stopping the service",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -283,28 +374,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/wheelclick/stop": {
-            "post": {
-                "description": "Stops the detection of middle mouse clicks.",
+        "/events": {
+            "get": {
+                "description": "Streams window, turn, hotkey, click broadcast and service state events as Server-Sent Events, each a JSON services.Event named after its type",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "WheelClick"
+                    "Events"
+                ],
+                "summary": "Stream events",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Event types or type prefixes, e.g. window.flash or window",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Window handle",
+                        "name": "window",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Stop middle mouse click detection",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/services.Event"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -315,34 +421,2719 @@ const docTemplate = `{
                 }
             }
         },
-        "/windows": {
-            "get": {
-                "description": "Obtient la liste des fenêtres actuellement ouvertes sur le système",
+        "/focus/{keyword}": {
+            "post": {
+                "description": "Met en avant une fenêtre qui contient un mot-clé spécifique dans son titre",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Windows"
                 ],
-                "summary": "Retourne la liste des fenêtres ouvertes",
+                "summary": "Met en avant une fenêtre spécifique",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mot-clé pour identifier la fenêtre",
+                        "name": "keyword",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Liste des fenêtres ouvertes",
+                        "description": "Fenêtre mise en avant avec succès",
                         "schema": {
-                            "type": "array",
-                            "items": {
+                            "type": "object",
+                            "additionalProperties": {
                                 "type": "string"
                             }
                         }
                     },
                     "500": {
-                        "description": "Erreur lors de la récupération des fenêtres",
+                        "description": "Erreur lors de la mise en avant de la fenêtre",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
+        },
+        "/key-broadcast": {
+            "get": {
+                "description": "Returns the allowed keys, the leader and the excluded characters of the key broadcast",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "KeyBroadcast"
+                ],
+                "summary": "Get key broadcast settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.KeyBroadcastSettings"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces the allowed keys, the leader and the excluded characters of the key broadcast. The keys pressed in the leader window are sent to the other team windows while the keyBroadcast service runs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "KeyBroadcast"
+                ],
+                "summary": "Update key broadcast settings",
+                "parameters": [
+                    {
+                        "description": "Key broadcast settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.KeyBroadcastSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.KeyBroadcastSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/key-broadcast/excluded/{name}": {
+            "put": {
+                "description": "Stops sending the broadcast keys to the client of a character",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "KeyBroadcast"
+                ],
+                "summary": "Exclude a window from the key broadcast",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Character name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.KeyBroadcastSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Sends the broadcast keys to the client of an excluded character again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "KeyBroadcast"
+                ],
+                "summary": "Include a window in the key broadcast",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Character name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.KeyBroadcastSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/key-broadcast/toggle": {
+            "post": {
+                "description": "Starts the keyBroadcast service if it does not run, stops it otherwise",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "KeyBroadcast"
+                ],
+                "summary": "Toggle key broadcast",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.ServiceStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/layouts": {
+            "get": {
+                "description": "Returns the saved layouts with the window rectangle of each character",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "List layouts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Layout"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/layouts/arrange/{arrangement}": {
+            "post": {
+                "description": "Tiles, cascades or stacks the clients of the online team members over the work area of a monitor, in slot order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Arrange the team windows",
+                "parameters": [
+                    {
+                        "enum": [
+                            "tile",
+                            "cascade",
+                            "stack"
+                        ],
+                        "type": "string",
+                        "description": "Arrangement",
+                        "name": "arrangement",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Monitor number, as listed by /monitors (0, the primary monitor, by default)",
+                        "name": "monitor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.LayoutResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown monitor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "No team member is online",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/layouts/capture": {
+            "post": {
+                "description": "Saves the window rectangles of the online team members as a layout, replacing the one with the same name. Minimized windows are left out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Capture a layout",
+                "parameters": [
+                    {
+                        "description": "Layout name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CaptureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.Layout"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "No team member is online",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/layouts/{name}": {
+            "get": {
+                "description": "Returns the window rectangle of each character of a layout",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Get a layout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Layout name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Layout"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Creates or replaces the layout with the given name. Rectangles are in screen coordinates, decorations included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Save a layout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Layout name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Window rectangles",
+                        "name": "layout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.Layout"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Layout"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the layout with the given name",
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Delete a layout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Layout name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/layouts/{name}/apply": {
+            "post": {
+                "description": "Moves and resizes the client of each character of the layout. Characters without an open client are reported with an error",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Layouts"
+                ],
+                "summary": "Apply a layout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Layout name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.LayoutResult"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros": {
+            "get": {
+                "description": "Returns the name, the recording window, the number of steps and the duration of each macro",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "List macros",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.MacroInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros/playback": {
+            "get": {
+                "description": "Returns the macro playing, its targets and its current iteration",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Get the macro playback",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Playback"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancels the playback in progress, releasing the keys it holds down",
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Stop the macro playback",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros/record": {
+            "post": {
+                "description": "Records the key presses and the presses and releases of every mouse button, with their timing, until the recording is stopped. Mouse positions are recorded relative to the client area of the foreground window",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Record a macro",
+                "parameters": [
+                    {
+                        "description": "Macro name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RecordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros/record/stop": {
+            "post": {
+                "description": "Stops the recording in progress and stores the macro, replacing the one with the same name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Stop recording a macro",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Macro"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros/{name}": {
+            "get": {
+                "description": "Returns the steps of a macro, with their delays and the mouse positions relative to the client area",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Get a macro",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Macro name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Macro"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Creates or replaces the macro with the given name, e.g. to edit the delays of a recorded macro",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Save a macro",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Macro name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Macro steps",
+                        "name": "macro",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.Macro"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Macro"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the macro with the given name",
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Delete a macro",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Macro name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/macros/{name}/play": {
+            "post": {
+                "description": "Replays a macro in the background on the given characters, or the whole team, without activating their windows. The speed scales the delays, and the macro is repeated or looped until the playback is stopped. Macros without delays between their steps cannot loop",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Macros"
+                ],
+                "summary": "Play a macro",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Macro name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Playback options",
+                        "name": "options",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/services.PlayOptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/services.Playback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/monitors": {
+            "get": {
+                "description": "Returns the bounds, the work area and the DPI of each monitor, the primary one first. Coordinates are physical pixels of the virtual screen, negative left of or above the primary monitor",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Windows"
+                ],
+                "summary": "List monitors",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Monitor"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/services": {
+            "get": {
+                "description": "Reports the state, uptime, last error and restarts of every background service. Services failing to start or while they run are restarted with backoff, up to services.restart.maxAttempts times",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Services"
+                ],
+                "summary": "List services",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.ServiceStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{name}": {
+            "get": {
+                "description": "Reports the state, uptime and last error of a background service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Services"
+                ],
+                "summary": "Get a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.ServiceStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{name}/start": {
+            "post": {
+                "description": "Starts a background service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Services"
+                ],
+                "summary": "Start a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.ServiceStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Service already running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{name}/stop": {
+            "post": {
+                "description": "Stops a background service, or cancels the pending restart of a failed one",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Services"
+                ],
+                "summary": "Stop a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.ServiceStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Service not running",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shortcut/register/{key}/{windowName}": {
+            "post": {
+                "description": "Registers a hotkey to focus on a window",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Register a hotkey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key combination to register, e.g. F1 or Ctrl+Alt+F3",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the window to focus",
+                        "name": "windowName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Shortcut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shortcut/unregister/{id}": {
+            "delete": {
+                "description": "Unregisters a previously registered keyboard shortcut",
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Unregister an existing hotkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shortcut ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Raccourci désenregistré avec succès",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shortcuts": {
+            "get": {
+                "description": "Lists the registered keyboard shortcuts ordered by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "List hotkeys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Shortcut"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers a keyboard shortcut focusing the given window, or running an action such as focusNext, focusPrevious, toggleKeyBroadcast, playMacro, recordMacro, stopMacro, arrangeWindows or applyLayout with its argument",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Create a hotkey",
+                "parameters": [
+                    {
+                        "description": "Key, and window to focus or action to run",
+                        "name": "shortcut",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortcutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.Shortcut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/shortcuts/{id}": {
+            "get": {
+                "description": "Returns the keyboard shortcut with the given ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Get a hotkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shortcut ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Shortcut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Changes the key and the window focused or action run by a keyboard shortcut",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Update a hotkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shortcut ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Key, and window to focus or action to run",
+                        "name": "shortcut",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShortcutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Shortcut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Unregisters the keyboard shortcut with the given ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Shortcut"
+                ],
+                "summary": "Delete a hotkey",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shortcut ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/start-turn/start": {
+            "get": {
+                "description": "Démarre le service pour écouter les demandes d'attention des fenêtres données, ou de toute l'équipe si aucune n'est donnée",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Démarrer le service de détection des débuts de tour",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Nom de personnage ou titre de la fenêtre à surveiller",
+                        "name": "windowTitle",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Service démarré avec succès",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Service déjà démarré",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/start-turn/status": {
+            "get": {
+                "description": "Indique si le service tourne et l'état des fenêtres surveillées, y compris leur fenêtre actuelle après un redémarrage du client",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "État du service de détection des débuts de tour",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.TurnStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/start-turn/stop": {
+            "get": {
+                "description": "Arrête le service d'écoute des événements sur une fenêtre",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Arrêter le service de détection d'événements",
+                "responses": {
+                    "200": {
+                        "description": "Service arrêté avec succès",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Erreur si le service n'est pas en cours",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/start-turn/windows": {
+            "get": {
+                "description": "Liste les fenêtres surveillées par le service de détection des débuts de tour",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Lister les fenêtres surveillées",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.TurnWindow"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Ajoute une fenêtre, désignée par un nom de personnage ou une partie de son titre, y compris pendant que le service tourne",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Surveiller une fenêtre",
+                "parameters": [
+                    {
+                        "description": "Nom de la fenêtre",
+                        "name": "window",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TurnWindowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/services.TurnWindow"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Fenêtre déjà surveillée",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/start-turn/windows/{name}": {
+            "put": {
+                "description": "Active ou désactive la mise au premier plan d'une fenêtre surveillée, sans l'enlever",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Activer ou désactiver une fenêtre surveillée",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Nom de la fenêtre",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "État de la fenêtre",
+                        "name": "window",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TurnWindowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.TurnWindow"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Fenêtre non surveillée",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Enlève une fenêtre des fenêtres surveillées, y compris pendant que le service tourne",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "StartTurn"
+                ],
+                "summary": "Ne plus surveiller une fenêtre",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Nom de la fenêtre",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Fenêtre non surveillée",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/team/focus": {
+            "get": {
+                "description": "Returns the character whose client is in the foreground",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Characters"
+                ],
+                "summary": "Get the focused character",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Character"
+                        }
+                    },
+                    "404": {
+                        "description": "No team member has focus",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/team/next": {
+            "post": {
+                "description": "Brings the client of the next character in slot order to the foreground, skipping closed clients and wrapping around",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Characters"
+                ],
+                "summary": "Focus the next character",
+                "responses": {
+                    "200": {
+                        "description": "The character focused",
+                        "schema": {
+                            "$ref": "#/definitions/services.Character"
+                        }
+                    },
+                    "404": {
+                        "description": "The team is empty",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "No team member is online",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/team/previous": {
+            "post": {
+                "description": "Brings the client of the previous character in slot order to the foreground, skipping closed clients and wrapping around",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Characters"
+                ],
+                "summary": "Focus the previous character",
+                "responses": {
+                    "200": {
+                        "description": "The character focused",
+                        "schema": {
+                            "$ref": "#/definitions/services.Character"
+                        }
+                    },
+                    "404": {
+                        "description": "The team is empty",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "No team member is online",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wheelclick/report": {
+            "get": {
+                "description": "Returns the windows clicked by the last broadcast, in order, with the delay, start and duration of each click",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WheelClick"
+                ],
+                "summary": "Get the last click broadcast report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.BroadcastReport"
+                        }
+                    },
+                    "404": {
+                        "description": "No click broadcast yet",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wheelclick/start": {
+            "post": {
+                "description": "Listens for middle mouse clicks and triggers click simulation on Dofus windows.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WheelClick"
+                ],
+                "summary": "Start middle mouse click detection",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Detection already started",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/wheelclick/stop": {
+            "post": {
+                "description": "Stops the detection of middle mouse clicks.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WheelClick"
+                ],
+                "summary": "Stop middle mouse click detection",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Detection not started",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/windows": {
+            "get": {
+                "description": "Obtient la liste des fenêtres actuellement ouvertes sur le système, éventuellement filtrée",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Windows"
+                ],
+                "summary": "Retourne la liste des fenêtres ouvertes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Jeu recherché dans le titre ou l'exécutable (ex: dofus)",
+                        "name": "game",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Partie du titre de la fenêtre",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Fenêtres visibles uniquement (true) ou cachées (false)",
+                        "name": "visible",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Fenêtres réduites uniquement (true) ou non (false)",
+                        "name": "minimized",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Liste des fenêtres ouvertes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.Window"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Filtre invalide",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Erreur lors de la récupération des fenêtres",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/windows/{id}/screenshot": {
+            "get": {
+                "description": "Captures the client area of a window, even when it is covered by other windows, optionally cropped then scaled. Minimized windows cannot be captured",
+                "produces": [
+                    "image/png",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Windows"
+                ],
+                "summary": "Screenshot a window",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Window handle, as listed by /windows",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "jpeg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 90,
+                        "description": "JPEG quality, from 1 to 100",
+                        "name": "quality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Rectangle of the client area as x,y,width,height",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "default": 1,
+                        "description": "Scale factor applied after cropping, up to 4",
+                        "name": "scale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image of the client area",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown window",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Window is minimized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.CaptureRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CharacterRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "slot": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.RecordRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ShortcutRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "argument": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "windowName": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TurnWindowRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.BroadcastReport": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "description": "DurationMs is the time taken by the whole broadcast.",
+                    "type": "number"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "string"
+                },
+                "parallel": {
+                    "type": "boolean"
+                },
+                "relative": {
+                    "$ref": "#/definitions/services.RelativePoint"
+                },
+                "source": {
+                    "description": "Source and Relative are the window clicked and the position relative to\nits client area, when the click is over a window receiving it.",
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "targets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.TargetTiming"
+                    }
+                },
+                "x": {
+                    "description": "X and Y are the screen coordinates of the click broadcast.",
+                    "type": "integer"
+                },
+                "y": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.Character": {
+            "type": "object",
+            "properties": {
+                "handle": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "online": {
+                    "type": "boolean"
+                },
+                "slot": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.Event": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "time": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/services.EventType"
+                },
+                "window": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.EventType": {
+            "type": "string",
+            "enum": [
+                "window.activated",
+                "window.flash",
+                "window.created",
+                "window.destroyed",
+                "turn.start",
+                "turn.bound",
+                "turn.lost",
+                "hotkey",
+                "click.broadcast",
+                "click.report",
+                "key.broadcast",
+                "service.state",
+                "macro.recording",
+                "macro.recorded",
+                "macro.playing",
+                "macro.played",
+                "layout.applied"
+            ],
+            "x-enum-varnames": [
+                "EventWindowActivated",
+                "EventWindowFlash",
+                "EventWindowCreated",
+                "EventWindowDestroyed",
+                "EventTurnStart",
+                "EventTurnBound",
+                "EventTurnLost",
+                "EventHotkey",
+                "EventClickBroadcast",
+                "EventClickReport",
+                "EventKeyBroadcast",
+                "EventServiceState",
+                "EventMacroRecording",
+                "EventMacroRecorded",
+                "EventMacroPlaying",
+                "EventMacroPlayed",
+                "EventLayoutApplied"
+            ]
+        },
+        "services.KeyBroadcastSettings": {
+            "type": "object",
+            "properties": {
+                "excluded": {
+                    "description": "Excluded lists the characters not receiving the keys.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "keys": {
+                    "description": "Keys is the allow-list of keys broadcast, such as \"1\", \"F2\" or \"Enter\".",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "leader": {
+                    "description": "Leader is the character whose keys are broadcast, or any team member\nwhen empty, as long as its client is in the foreground.",
+                    "type": "string"
+                }
+            }
+        },
+        "services.Layout": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.LayoutWindow"
+                    }
+                }
+            }
+        },
+        "services.LayoutResult": {
+            "type": "object",
+            "properties": {
+                "layout": {
+                    "type": "string"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.Placement"
+                    }
+                }
+            }
+        },
+        "services.LayoutWindow": {
+            "type": "object",
+            "properties": {
+                "character": {
+                    "type": "string"
+                },
+                "rect": {
+                    "$ref": "#/definitions/services.Rect"
+                }
+            }
+        },
+        "services.Macro": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "recordedAt": {
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.MacroStep"
+                    }
+                },
+                "window": {
+                    "description": "Window is the character the macro was recorded on.",
+                    "type": "string"
+                }
+            }
+        },
+        "services.MacroInfo": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.MacroStep": {
+            "type": "object",
+            "properties": {
+                "button": {
+                    "description": "Button is the button of the mouse events.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/services.MouseButton"
+                        }
+                    ]
+                },
+                "delayMs": {
+                    "description": "DelayMs is the time since the previous step, in milliseconds.",
+                    "type": "integer"
+                },
+                "keychar": {
+                    "type": "integer"
+                },
+                "keycode": {
+                    "description": "Keycode, Rawcode and Keychar are those of the key events recorded.",
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "position": {
+                    "description": "Position is the position of the mouse events relative to the client\narea.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/services.RelativePoint"
+                        }
+                    ]
+                },
+                "rawcode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.Monitor": {
+            "type": "object",
+            "properties": {
+                "bounds": {
+                    "$ref": "#/definitions/services.Rect"
+                },
+                "dpi": {
+                    "description": "DPI is the effective resolution, BaseDPI at 100% scaling.",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "primary": {
+                    "type": "boolean"
+                },
+                "scale": {
+                    "type": "number"
+                },
+                "workArea": {
+                    "description": "WorkArea is the part of the monitor not covered by task bars and docks.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/services.Rect"
+                        }
+                    ]
+                }
+            }
+        },
+        "services.MouseButton": {
+            "type": "integer",
+            "enum": [
+                1,
+                2,
+                3,
+                4,
+                5
+            ],
+            "x-enum-varnames": [
+                "MouseLeft",
+                "MouseRight",
+                "MouseMiddle",
+                "MouseX1",
+                "MouseX2"
+            ]
+        },
+        "services.Placement": {
+            "type": "object",
+            "properties": {
+                "character": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "handle": {
+                    "type": "integer"
+                },
+                "rect": {
+                    "$ref": "#/definitions/services.Rect"
+                }
+            }
+        },
+        "services.PlayOptions": {
+            "type": "object",
+            "properties": {
+                "loop": {
+                    "description": "Loop plays the macro until the playback is stopped. Macros without\ndelays between their steps cannot loop.",
+                    "type": "boolean"
+                },
+                "repeat": {
+                    "description": "Repeat is the number of times the macro is played, at least once.",
+                    "type": "integer"
+                },
+                "speed": {
+                    "description": "Speed scales the playback, 2 playing twice as fast. Defaults to 1.",
+                    "type": "number"
+                },
+                "windows": {
+                    "description": "Windows lists the characters the macro is played on, the whole team\nwhen empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "services.Playback": {
+            "type": "object",
+            "properties": {
+                "cancelled": {
+                    "description": "Cancelled and Error report how a finished playback ended.",
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "iteration": {
+                    "type": "integer"
+                },
+                "loop": {
+                    "type": "boolean"
+                },
+                "macro": {
+                    "type": "string"
+                },
+                "repeat": {
+                    "type": "integer"
+                },
+                "speed": {
+                    "type": "number"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "services.Rect": {
+            "type": "object",
+            "properties": {
+                "bottom": {
+                    "type": "integer"
+                },
+                "left": {
+                    "type": "integer"
+                },
+                "right": {
+                    "type": "integer"
+                },
+                "top": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.RelativePoint": {
+            "type": "object",
+            "properties": {
+                "x": {
+                    "type": "number"
+                },
+                "y": {
+                    "type": "number"
+                }
+            }
+        },
+        "services.ServiceState": {
+            "type": "string",
+            "enum": [
+                "stopped",
+                "starting",
+                "running",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "ServiceStopped",
+                "ServiceStarting",
+                "ServiceRunning",
+                "ServiceFailed"
+            ]
+        },
+        "services.ServiceStatus": {
+            "type": "object",
+            "properties": {
+                "lastError": {
+                    "type": "string"
+                },
+                "lastErrorAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nextRestartAt": {
+                    "description": "NextRestartAt is set while a failed service waits to be restarted.",
+                    "type": "string"
+                },
+                "restarts": {
+                    "description": "Restarts is the number of restarts since the service last failed.",
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/services.ServiceState"
+                },
+                "uptime": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.Shortcut": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "argument": {
+                    "description": "Argument is passed to the action, e.g. the name of a macro.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "windowName": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.TargetTiming": {
+            "type": "object",
+            "properties": {
+                "delayMs": {
+                    "description": "DelayMs is the pause before the click, jitter included.",
+                    "type": "number"
+                },
+                "durationMs": {
+                    "description": "DurationMs is the time taken to deliver the click.",
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "startMs": {
+                    "description": "StartMs is the time from the start of the broadcast to the click.",
+                    "type": "number"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "window": {
+                    "type": "integer"
+                },
+                "x": {
+                    "description": "X and Y are the screen coordinates clicked in the window.",
+                    "type": "integer"
+                },
+                "y": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.TurnStatus": {
+            "type": "object",
+            "properties": {
+                "running": {
+                    "type": "boolean"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.TurnWindow"
+                    }
+                }
+            }
+        },
+        "services.TurnWindow": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "handle": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "online": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "services.Window": {
+            "type": "object",
+            "properties": {
+                "className": {
+                    "type": "string"
+                },
+                "clientHeight": {
+                    "type": "integer"
+                },
+                "clientWidth": {
+                    "type": "integer"
+                },
+                "exeName": {
+                    "type": "string"
+                },
+                "foreground": {
+                    "type": "boolean"
+                },
+                "handle": {
+                    "type": "integer"
+                },
+                "minimized": {
+                    "type": "boolean"
+                },
+                "pid": {
+                    "type": "integer"
+                },
+                "rect": {
+                    "$ref": "#/definitions/services.Rect"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "visible": {
+                    "type": "boolean"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptureHandler contains the CaptureService instance.
+type CaptureHandler struct {
+	captureService *services.CaptureService
+}
+
+// NewCaptureHandler creates a new instance of CaptureHandler.
+func NewCaptureHandler(cs *services.CaptureService) *CaptureHandler {
+	return &CaptureHandler{captureService: cs}
+}
+
+// GetScreenshot returns a screenshot of the client area of a window.
+// @Summary Screenshot a window
+// @Description Captures the client area of a window, even when it is covered by other windows, optionally cropped then scaled. Minimized windows cannot be captured
+// @Tags Windows
+// @Produce png
+// @Produce jpeg
+// @Param id path int true "Window handle, as listed by /windows"
+// @Param format query string false "Image format" Enums(png, jpeg) default(png)
+// @Param quality query int false "JPEG quality, from 1 to 100" default(90)
+// @Param crop query string false "Rectangle of the client area as x,y,width,height"
+// @Param scale query number false "Scale factor applied after cropping, up to 4" default(1)
+// @Success 200 {file} binary "Image of the client area"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string "Unknown window"
+// @Failure 409 {object} map[string]string "Window is minimized"
+// @Failure 500 {object} map[string]string
+// @Router /windows/{id}/screenshot [get]
+func (h *CaptureHandler) GetScreenshot(c *gin.Context) {
+	hwnd, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid window handle: %q", c.Param("id"))})
+		return
+	}
+
+	options := services.CaptureOptions{Format: c.DefaultQuery("format", services.CaptureFormatPNG)}
+	if raw, ok := c.GetQuery("quality"); ok {
+		if options.Quality, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid value for quality: %q", raw)})
+			return
+		}
+	}
+	if raw, ok := c.GetQuery("scale"); ok {
+		if options.Scale, err = strconv.ParseFloat(raw, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid value for scale: %q", raw)})
+			return
+		}
+	}
+	if raw, ok := c.GetQuery("crop"); ok {
+		if options.Crop, err = parseCrop(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var image bytes.Buffer
+	if err := h.captureService.Screenshot(&image, services.WindowHandle(hwnd), options); err != nil {
+		c.JSON(captureErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/"+options.Format, image.Bytes())
+}
+
+// parseCrop parses a rectangle given as x,y,width,height.
+func parseCrop(raw string) (services.Rect, error) {
+	fields := strings.Split(raw, ",")
+	if len(fields) != 4 {
+		return services.Rect{}, fmt.Errorf("invalid value for crop: %q, expected x,y,width,height", raw)
+	}
+	var values [4]int
+	for i, field := range fields {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return services.Rect{}, fmt.Errorf("invalid value for crop: %q, expected x,y,width,height", raw)
+		}
+		values[i] = value
+	}
+	if values[2] <= 0 || values[3] <= 0 {
+		return services.Rect{}, fmt.Errorf("invalid value for crop: %q, width and height must be positive", raw)
+	}
+	return services.Rect{Left: values[0], Top: values[1], Right: values[0] + values[2], Bottom: values[1] + values[3]}, nil
+}
+
+func captureErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrWindowNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidCapture):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrWindowMinimized):
+		return http.StatusConflict
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCaptureRouter serves the screenshots of an 800x600 window of a fake
+// backend.
+func newCaptureRouter(t *testing.T) (*gin.Engine, *services.FakeWindowBackend, services.WindowHandle) {
+	t.Helper()
+	fake := services.NewFakeWindowBackend()
+	hwnd := fake.AddWindow("Alpha - Dofus 2.70.5")
+	handler := NewCaptureHandler(services.NewCaptureService(services.NewWindowService(fake)))
+
+	r := gin.New()
+	r.GET("/windows/:id/screenshot", handler.GetScreenshot)
+	return r, fake, hwnd
+}
+
+func TestGetScreenshot(t *testing.T) {
+	r, _, hwnd := newCaptureRouter(t)
+
+	tests := []struct {
+		query       string
+		contentType string
+		decode      func(*http.Response) (image.Image, error)
+		size        image.Point
+	}{
+		{"", "image/png", decodePNG, image.Pt(800, 600)},
+		{"?format=png&crop=10,20,100,50", "image/png", decodePNG, image.Pt(100, 50)},
+		{"?format=jpeg", "image/jpeg", decodeJPEG, image.Pt(800, 600)},
+		{"?format=jpeg&quality=1&scale=0.5", "image/jpeg", decodeJPEG, image.Pt(400, 300)},
+		{"?format=jpeg&quality=100", "image/jpeg", decodeJPEG, image.Pt(800, 600)},
+	}
+	for _, test := range tests {
+		w := serve(r, http.MethodGet, fmt.Sprintf("/windows/%d/screenshot%s", hwnd, test.query), "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d (%s), want 200", test.query, w.Code, w.Body)
+			continue
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s: content type %q, want %q", test.query, contentType, test.contentType)
+		}
+		img, err := test.decode(w.Result())
+		if err != nil {
+			t.Errorf("%s: decoding the screenshot: %v", test.query, err)
+			continue
+		}
+		if size := img.Bounds().Size(); size != test.size {
+			t.Errorf("%s: screenshot of %v, want %v", test.query, size, test.size)
+		}
+	}
+}
+
+func decodePNG(resp *http.Response) (image.Image, error) {
+	return png.Decode(resp.Body)
+}
+
+func decodeJPEG(resp *http.Response) (image.Image, error) {
+	return jpeg.Decode(resp.Body)
+}
+
+func TestGetScreenshotErrors(t *testing.T) {
+	r, fake, hwnd := newCaptureRouter(t)
+	minimized := fake.AddWindow("Beta - Dofus 2.70.5")
+	fake.MinimizeWindow(minimized)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{fmt.Sprintf("/windows/%d/screenshot?format=gif", hwnd), http.StatusBadRequest},
+		// A zero quality selects the default one.
+		{fmt.Sprintf("/windows/%d/screenshot?format=jpeg&quality=0", hwnd), http.StatusOK},
+		{fmt.Sprintf("/windows/%d/screenshot?format=jpeg&quality=101", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?format=jpeg&quality=-5", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?quality=high", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?scale=5", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?scale=big", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?crop=10,20,100", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?crop=10,20,0,50", hwnd), http.StatusBadRequest},
+		{fmt.Sprintf("/windows/%d/screenshot?crop=900,0,100,100", hwnd), http.StatusBadRequest},
+		{"/windows/alpha/screenshot", http.StatusBadRequest},
+		{"/windows/4242/screenshot", http.StatusNotFound},
+		{fmt.Sprintf("/windows/%d/screenshot", minimized), http.StatusConflict},
+	}
+	for _, test := range tests {
+		if w := serve(r, http.MethodGet, test.path, ""); w.Code != test.status {
+			t.Errorf("GET %s: status %d (%s), want %d", test.path, w.Code, w.Body, test.status)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CharacterHandler contains the CharacterService instance.
+type CharacterHandler struct {
+	characterService *services.CharacterService
+}
+
+// NewCharacterHandler creates a new instance of CharacterHandler.
+func NewCharacterHandler(cs *services.CharacterService) *CharacterHandler {
+	return &CharacterHandler{characterService: cs}
+}
+
+// CharacterRequest is the body of character creation and update requests.
+type CharacterRequest struct {
+	Name string `json:"name"`
+	Slot int    `json:"slot"`
+}
+
+// GetCharacters lists the registered characters.
+// @Summary List characters
+// @Description Discovers the running game clients and lists the registered characters ordered by slot
+// @Tags Characters
+// @Produce json
+// @Success 200 {array} services.Character
+// @Failure 500 {object} map[string]string
+// @Router /characters [get]
+func (h *CharacterHandler) GetCharacters(c *gin.Context) {
+	if err := h.characterService.Refresh(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.characterService.GetCharacters())
+}
+
+// GetCharacter returns a single character.
+// @Summary Get a character
+// @Description Returns the character designated by its name or slot number
+// @Tags Characters
+// @Produce json
+// @Param id path string true "Character name or slot number"
+// @Success 200 {object} services.Character
+// @Failure 404 {object} map[string]string
+// @Router /characters/{id} [get]
+func (h *CharacterHandler) GetCharacter(c *gin.Context) {
+	if err := h.characterService.Refresh(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	character, err := h.characterService.GetCharacter(c.Param("id"))
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, character)
+}
+
+// CreateCharacter registers a character.
+// @Summary Register a character
+// @Description Registers a character ahead of its client, in the given slot or the first free one
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param character body CharacterRequest true "Character name and optional slot"
+// @Success 201 {object} services.Character
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /characters [post]
+func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
+	var req CharacterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	character, err := h.characterService.AddCharacter(req.Name, req.Slot)
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, character)
+}
+
+// UpdateCharacter changes the slot of a character.
+// @Summary Update a character
+// @Description Moves the character designated by its name or slot number to another slot
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param id path string true "Character name or slot number"
+// @Param character body CharacterRequest true "New slot"
+// @Success 200 {object} services.Character
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /characters/{id} [put]
+func (h *CharacterHandler) UpdateCharacter(c *gin.Context) {
+	var req CharacterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	character, err := h.characterService.SetSlot(c.Param("id"), req.Slot)
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, character)
+}
+
+// DeleteCharacter removes a character.
+// @Summary Remove a character
+// @Description Removes the character designated by its name or slot number from the registry
+// @Tags Characters
+// @Produce json
+// @Param id path string true "Character name or slot number"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /characters/{id} [delete]
+func (h *CharacterHandler) DeleteCharacter(c *gin.Context) {
+	if err := h.characterService.RemoveCharacter(c.Param("id")); err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Character removed successfully"})
+}
+
+// FocusCharacter brings a character's client to the foreground.
+// @Summary Focus a character
+// @Description Brings the client of the character designated by its name or slot number to the foreground
+// @Tags Characters
+// @Produce json
+// @Param id path string true "Character name or slot number"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Character has no open client"
+// @Failure 500 {object} map[string]string
+// @Router /characters/{id}/focus [post]
+func (h *CharacterHandler) FocusCharacter(c *gin.Context) {
+	if err := h.characterService.FocusCharacter(c.Param("id")); err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Character focused successfully"})
+}
+
+// GetFocusedCharacter returns the team member that has focus.
+// @Summary Get the focused character
+// @Description Returns the character whose client is in the foreground
+// @Tags Characters
+// @Produce json
+// @Success 200 {object} services.Character
+// @Failure 404 {object} map[string]string "No team member has focus"
+// @Failure 500 {object} map[string]string
+// @Router /team/focus [get]
+func (h *CharacterHandler) GetFocusedCharacter(c *gin.Context) {
+	character, err := h.characterService.FocusedCharacter()
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, character)
+}
+
+// FocusNextCharacter focuses the next team member.
+// @Summary Focus the next character
+// @Description Brings the client of the next character in slot order to the foreground, skipping closed clients and wrapping around
+// @Tags Characters
+// @Produce json
+// @Success 200 {object} services.Character "The character focused"
+// @Failure 404 {object} map[string]string "The team is empty"
+// @Failure 409 {object} map[string]string "No team member is online"
+// @Failure 500 {object} map[string]string
+// @Router /team/next [post]
+func (h *CharacterHandler) FocusNextCharacter(c *gin.Context) {
+	character, err := h.characterService.FocusNext()
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, character)
+}
+
+// FocusPreviousCharacter focuses the previous team member.
+// @Summary Focus the previous character
+// @Description Brings the client of the previous character in slot order to the foreground, skipping closed clients and wrapping around
+// @Tags Characters
+// @Produce json
+// @Success 200 {object} services.Character "The character focused"
+// @Failure 404 {object} map[string]string "The team is empty"
+// @Failure 409 {object} map[string]string "No team member is online"
+// @Failure 500 {object} map[string]string
+// @Router /team/previous [post]
+func (h *CharacterHandler) FocusPreviousCharacter(c *gin.Context) {
+	character, err := h.characterService.FocusPrevious()
+	if err != nil {
+		c.JSON(characterErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, character)
+}
+
+// characterErrorStatus maps registry errors to HTTP statuses, other errors to fallback.
+func characterErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrCharacterNotFound), errors.Is(err, services.ErrNoCharacterFocus):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCharacterExists), errors.Is(err, services.ErrSlotTaken), errors.Is(err, services.ErrCharacterOffline):
+		return http.StatusConflict
+	default:
+		return fallback
+	}
+}
@@ -12,11 +12,12 @@ import (
 // DofusCheckHandler contains the DofusCheckService instance.
 type DofusCheckHandler struct {
 	dofusCheckService *services.DofusCheckService
+	serviceManager    *services.ServiceManager
 }
 
 // NewDofusCheckHandler creates a new instance of DofusCheckHandler.
-func NewDofusCheckHandler(dcs *services.DofusCheckService) *DofusCheckHandler {
-	return &DofusCheckHandler{dofusCheckService: dcs}
+func NewDofusCheckHandler(dcs *services.DofusCheckService, sm *services.ServiceManager) *DofusCheckHandler {
+	return &DofusCheckHandler{dofusCheckService: dcs, serviceManager: sm}
 }
 
 // StartDofusCheck starts the monitoring of the Dofus window.
@@ -24,10 +25,14 @@ func NewDofusCheckHandler(dcs *services.DofusCheckService) *DofusCheckHandler {
 // @Description Starts the DofusCheckService which monitors the Dofus window state
 // @Tags DofusCheck
 // @Success 200 {string} string "DofusCheck service started successfully"
-// @Failure 500 {object} string "Error occurred while starting the service"
+// @Failure 409 {object} map[string]string "Service already running"
+// @Failure 500 {object} map[string]string "Error occurred while starting the service"
 // @Router /dofus-check/start [post]
 func (h *DofusCheckHandler) StartDofusCheck(c *gin.Context) {
-	go h.dofusCheckService.StartMonitoring()
+	if err := h.serviceManager.Start(h.dofusCheckService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	log.Println("DofusCheck service started.")
 	c.JSON(http.StatusOK, "DofusCheck service started successfully")
 }
@@ -37,10 +42,14 @@ func (h *DofusCheckHandler) StartDofusCheck(c *gin.Context) {
 // @Description Stops the DofusCheckService which monitors the Dofus window state
 // @Tags DofusCheck
 // @Success 200 {string} string "DofusCheck service stopped successfully"
-// @Failure 500 {object} string "Error occurred while stopping the service"
+// @Failure 409 {object} map[string]string "Service not running"
+// @Failure 500 {object} map[string]string "Error occurred while stopping the service"
 // @Router /dofus-check/stop [post]
 func (h *DofusCheckHandler) StopDofusCheck(c *gin.Context) {
-	h.dofusCheckService.StopMonitoring()
+	if err := h.serviceManager.Stop(h.dofusCheckService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	log.Println("DofusCheck service stopped.")
 	c.JSON(http.StatusOK, "DofusCheck service stopped successfully")
 }
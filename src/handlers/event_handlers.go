@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventKeepAlive is the interval of the comments sent to keep idle streams open.
+const eventKeepAlive = 15 * time.Second
+
+// EventHandler contains the EventBus instance.
+type EventHandler struct {
+	eventBus *services.EventBus
+}
+
+// NewEventHandler creates a new instance of EventHandler.
+func NewEventHandler(eb *services.EventBus) *EventHandler {
+	return &EventHandler{eventBus: eb}
+}
+
+// StreamEvents streams the events as Server-Sent Events.
+// @Summary Stream events
+// @Description Streams window, turn, hotkey, click broadcast and service state events as Server-Sent Events, each a JSON services.Event named after its type
+// @Tags Events
+// @Produce text/event-stream
+// @Param type query []string false "Event types or type prefixes, e.g. window.flash or window" collectionFormat(multi)
+// @Param window query int false "Window handle"
+// @Success 200 {object} services.Event
+// @Failure 400 {object} map[string]string
+// @Router /events [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	var filter services.EventFilter
+	for _, t := range c.QueryArray("type") {
+		filter.Types = append(filter.Types, services.EventType(t))
+	}
+	if window := c.Query("window"); window != "" {
+		hwnd, err := strconv.ParseUint(window, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window handle: " + window})
+			return
+		}
+		filter.Window = services.WindowHandle(hwnd)
+	}
+
+	subscription := h.eventBus.Subscribe(filter)
+	defer subscription.Unsubscribe()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-subscription.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
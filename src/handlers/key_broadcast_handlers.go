@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyBroadcastHandler contains the KeyBroadcastService instance.
+type KeyBroadcastHandler struct {
+	keyBroadcastService *services.KeyBroadcastService
+	serviceManager      *services.ServiceManager
+}
+
+// NewKeyBroadcastHandler creates a new instance of KeyBroadcastHandler.
+func NewKeyBroadcastHandler(kbs *services.KeyBroadcastService, sm *services.ServiceManager) *KeyBroadcastHandler {
+	return &KeyBroadcastHandler{keyBroadcastService: kbs, serviceManager: sm}
+}
+
+// GetSettings returns the key broadcast settings.
+// @Summary Get key broadcast settings
+// @Description Returns the allowed keys, the leader and the excluded characters of the key broadcast
+// @Tags KeyBroadcast
+// @Produce json
+// @Success 200 {object} services.KeyBroadcastSettings
+// @Router /key-broadcast [get]
+func (h *KeyBroadcastHandler) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.keyBroadcastService.Settings())
+}
+
+// UpdateSettings replaces the key broadcast settings.
+// @Summary Update key broadcast settings
+// @Description Replaces the allowed keys, the leader and the excluded characters of the key broadcast. The keys pressed in the leader window are sent to the other team windows while the keyBroadcast service runs
+// @Tags KeyBroadcast
+// @Accept json
+// @Produce json
+// @Param settings body services.KeyBroadcastSettings true "Key broadcast settings"
+// @Success 200 {object} services.KeyBroadcastSettings
+// @Failure 400 {object} map[string]string
+// @Router /key-broadcast [put]
+func (h *KeyBroadcastHandler) UpdateSettings(c *gin.Context) {
+	var settings services.KeyBroadcastSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.keyBroadcastService.SetSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.keyBroadcastService.Settings())
+}
+
+// Toggle starts or stops the key broadcast.
+// @Summary Toggle key broadcast
+// @Description Starts the keyBroadcast service if it does not run, stops it otherwise
+// @Tags KeyBroadcast
+// @Produce json
+// @Success 200 {object} services.ServiceStatus
+// @Failure 500 {object} map[string]string
+// @Router /key-broadcast/toggle [post]
+func (h *KeyBroadcastHandler) Toggle(c *gin.Context) {
+	name := h.keyBroadcastService.Name()
+	if _, err := h.serviceManager.Toggle(name); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.serviceManager.Status(name)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ExcludeWindow stops broadcasting keys to a character.
+// @Summary Exclude a window from the key broadcast
+// @Description Stops sending the broadcast keys to the client of a character
+// @Tags KeyBroadcast
+// @Produce json
+// @Param name path string true "Character name"
+// @Success 200 {object} services.KeyBroadcastSettings
+// @Failure 400 {object} map[string]string
+// @Router /key-broadcast/excluded/{name} [put]
+func (h *KeyBroadcastHandler) ExcludeWindow(c *gin.Context) {
+	h.setExcluded(c, true)
+}
+
+// IncludeWindow broadcasts keys to an excluded character again.
+// @Summary Include a window in the key broadcast
+// @Description Sends the broadcast keys to the client of an excluded character again
+// @Tags KeyBroadcast
+// @Produce json
+// @Param name path string true "Character name"
+// @Success 200 {object} services.KeyBroadcastSettings
+// @Failure 400 {object} map[string]string
+// @Router /key-broadcast/excluded/{name} [delete]
+func (h *KeyBroadcastHandler) IncludeWindow(c *gin.Context) {
+	h.setExcluded(c, false)
+}
+
+func (h *KeyBroadcastHandler) setExcluded(c *gin.Context, excluded bool) {
+	if err := h.keyBroadcastService.SetExcluded(c.Param("name"), excluded); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.keyBroadcastService.Settings())
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LayoutHandler contains the LayoutService instance.
+type LayoutHandler struct {
+	layoutService *services.LayoutService
+}
+
+// NewLayoutHandler creates a new instance of LayoutHandler.
+func NewLayoutHandler(ls *services.LayoutService) *LayoutHandler {
+	return &LayoutHandler{layoutService: ls}
+}
+
+// CaptureRequest names the layout capturing the current arrangement.
+type CaptureRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListLayouts returns the saved layouts.
+// @Summary List layouts
+// @Description Returns the saved layouts with the window rectangle of each character
+// @Tags Layouts
+// @Produce json
+// @Success 200 {array} services.Layout
+// @Router /layouts [get]
+func (h *LayoutHandler) ListLayouts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.layoutService.Layouts())
+}
+
+// GetLayout returns a saved layout.
+// @Summary Get a layout
+// @Description Returns the window rectangle of each character of a layout
+// @Tags Layouts
+// @Produce json
+// @Param name path string true "Layout name"
+// @Success 200 {object} services.Layout
+// @Failure 404 {object} map[string]string
+// @Router /layouts/{name} [get]
+func (h *LayoutHandler) GetLayout(c *gin.Context) {
+	layout, err := h.layoutService.GetLayout(c.Param("name"))
+	if err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+// SaveLayout creates or replaces a layout.
+// @Summary Save a layout
+// @Description Creates or replaces the layout with the given name. Rectangles are in screen coordinates, decorations included
+// @Tags Layouts
+// @Accept json
+// @Produce json
+// @Param name path string true "Layout name"
+// @Param layout body services.Layout true "Window rectangles"
+// @Success 200 {object} services.Layout
+// @Failure 400 {object} map[string]string
+// @Router /layouts/{name} [put]
+func (h *LayoutHandler) SaveLayout(c *gin.Context) {
+	var layout services.Layout
+	if err := c.ShouldBindJSON(&layout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	layout.Name = c.Param("name")
+	layout, err := h.layoutService.SaveLayout(layout)
+	if err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+// DeleteLayout removes a layout.
+// @Summary Delete a layout
+// @Description Removes the layout with the given name
+// @Tags Layouts
+// @Param name path string true "Layout name"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /layouts/{name} [delete]
+func (h *LayoutHandler) DeleteLayout(c *gin.Context) {
+	if err := h.layoutService.DeleteLayout(c.Param("name")); err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ApplyLayout moves the team windows as saved in a layout.
+// @Summary Apply a layout
+// @Description Moves and resizes the client of each character of the layout. Characters without an open client are reported with an error
+// @Tags Layouts
+// @Produce json
+// @Param name path string true "Layout name"
+// @Success 200 {object} services.LayoutResult
+// @Failure 404 {object} map[string]string
+// @Router /layouts/{name}/apply [post]
+func (h *LayoutHandler) ApplyLayout(c *gin.Context) {
+	result, err := h.layoutService.ApplyLayout(c.Param("name"))
+	if err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CaptureLayout saves the current arrangement as a layout.
+// @Summary Capture a layout
+// @Description Saves the window rectangles of the online team members as a layout, replacing the one with the same name. Minimized windows are left out
+// @Tags Layouts
+// @Accept json
+// @Produce json
+// @Param request body CaptureRequest true "Layout name"
+// @Success 201 {object} services.Layout
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "No team member is online"
+// @Router /layouts/capture [post]
+func (h *LayoutHandler) CaptureLayout(c *gin.Context) {
+	var req CaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	layout, err := h.layoutService.CaptureLayout(req.Name)
+	if err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, layout)
+}
+
+// Arrange tiles, cascades or stacks the team windows.
+// @Summary Arrange the team windows
+// @Description Tiles, cascades or stacks the clients of the online team members over the work area of a monitor, in slot order
+// @Tags Layouts
+// @Produce json
+// @Param arrangement path string true "Arrangement" Enums(tile, cascade, stack)
+// @Param monitor query int false "Monitor number, as listed by /monitors (0, the primary monitor, by default)"
+// @Success 200 {object} services.LayoutResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string "Unknown monitor"
+// @Failure 409 {object} map[string]string "No team member is online"
+// @Router /layouts/arrange/{arrangement} [post]
+func (h *LayoutHandler) Arrange(c *gin.Context) {
+	monitor := 0
+	if raw, ok := c.GetQuery("monitor"); ok {
+		var err error
+		if monitor, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid value for monitor: %q", raw)})
+			return
+		}
+	}
+	result, err := h.layoutService.Arrange(c.Param("arrangement"), monitor)
+	if err != nil {
+		c.JSON(layoutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func layoutErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrLayoutNotFound), errors.Is(err, services.ErrMonitorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidLayout):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCharacterOffline):
+		return http.StatusConflict
+	default:
+		return fallback
+	}
+}
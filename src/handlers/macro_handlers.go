@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MacroHandler contains the MacroService instance.
+type MacroHandler struct {
+	macroService *services.MacroService
+}
+
+// NewMacroHandler creates a new instance of MacroHandler.
+func NewMacroHandler(ms *services.MacroService) *MacroHandler {
+	return &MacroHandler{macroService: ms}
+}
+
+// RecordRequest names the macro to record.
+type RecordRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListMacros returns a summary of the stored macros.
+// @Summary List macros
+// @Description Returns the name, the recording window, the number of steps and the duration of each macro
+// @Tags Macros
+// @Produce json
+// @Success 200 {array} services.MacroInfo
+// @Router /macros [get]
+func (h *MacroHandler) ListMacros(c *gin.Context) {
+	c.JSON(http.StatusOK, h.macroService.Macros())
+}
+
+// GetMacro returns a macro with its steps.
+// @Summary Get a macro
+// @Description Returns the steps of a macro, with their delays and the mouse positions relative to the client area
+// @Tags Macros
+// @Produce json
+// @Param name path string true "Macro name"
+// @Success 200 {object} services.Macro
+// @Failure 404 {object} map[string]string
+// @Router /macros/{name} [get]
+func (h *MacroHandler) GetMacro(c *gin.Context) {
+	macro, err := h.macroService.GetMacro(c.Param("name"))
+	if err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, macro)
+}
+
+// SaveMacro creates or replaces a macro.
+// @Summary Save a macro
+// @Description Creates or replaces the macro with the given name, e.g. to edit the delays of a recorded macro
+// @Tags Macros
+// @Accept json
+// @Produce json
+// @Param name path string true "Macro name"
+// @Param macro body services.Macro true "Macro steps"
+// @Success 200 {object} services.Macro
+// @Failure 400 {object} map[string]string
+// @Router /macros/{name} [put]
+func (h *MacroHandler) SaveMacro(c *gin.Context) {
+	var macro services.Macro
+	if err := c.ShouldBindJSON(&macro); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	macro.Name = c.Param("name")
+	if err := h.macroService.SaveMacro(macro); err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	macro, err := h.macroService.GetMacro(macro.Name)
+	if err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, macro)
+}
+
+// DeleteMacro removes a macro.
+// @Summary Delete a macro
+// @Description Removes the macro with the given name
+// @Tags Macros
+// @Param name path string true "Macro name"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /macros/{name} [delete]
+func (h *MacroHandler) DeleteMacro(c *gin.Context) {
+	if err := h.macroService.DeleteMacro(c.Param("name")); err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// StartRecording starts recording a macro.
+// @Summary Record a macro
+// @Description Records the key presses and the presses and releases of every mouse button, with their timing, until the recording is stopped. Mouse positions are recorded relative to the client area of the foreground window
+// @Tags Macros
+// @Accept json
+// @Produce json
+// @Param request body RecordRequest true "Macro name"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /macros/record [post]
+func (h *MacroHandler) StartRecording(c *gin.Context) {
+	var req RecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.macroService.StartRecording(req.Name); err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"recording": req.Name})
+}
+
+// StopRecording stops the recording and stores the macro.
+// @Summary Stop recording a macro
+// @Description Stops the recording in progress and stores the macro, replacing the one with the same name
+// @Tags Macros
+// @Produce json
+// @Success 200 {object} services.Macro
+// @Failure 409 {object} map[string]string
+// @Router /macros/record/stop [post]
+func (h *MacroHandler) StopRecording(c *gin.Context) {
+	macro, err := h.macroService.StopRecording()
+	if err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, macro)
+}
+
+// PlayMacro replays a macro on team windows.
+// @Summary Play a macro
+// @Description Replays a macro in the background on the given characters, or the whole team, without activating their windows. The speed scales the delays, and the macro is repeated or looped until the playback is stopped. Macros without delays between their steps cannot loop
+// @Tags Macros
+// @Accept json
+// @Produce json
+// @Param name path string true "Macro name"
+// @Param options body services.PlayOptions false "Playback options"
+// @Success 202 {object} services.Playback
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /macros/{name}/play [post]
+func (h *MacroHandler) PlayMacro(c *gin.Context) {
+	var options services.PlayOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&options); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	playback, err := h.macroService.Play(c.Param("name"), options)
+	if err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, playback)
+}
+
+// GetPlayback returns the playback in progress.
+// @Summary Get the macro playback
+// @Description Returns the macro playing, its targets and its current iteration
+// @Tags Macros
+// @Produce json
+// @Success 200 {object} services.Playback
+// @Failure 404 {object} map[string]string
+// @Router /macros/playback [get]
+func (h *MacroHandler) GetPlayback(c *gin.Context) {
+	playback, ok := h.macroService.PlaybackStatus()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrNotPlaying.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, playback)
+}
+
+// StopPlayback cancels the playback in progress.
+// @Summary Stop the macro playback
+// @Description Cancels the playback in progress, releasing the keys it holds down
+// @Tags Macros
+// @Success 204
+// @Failure 409 {object} map[string]string
+// @Router /macros/playback [delete]
+func (h *MacroHandler) StopPlayback(c *gin.Context) {
+	if err := h.macroService.StopPlayback(); err != nil {
+		c.JSON(macroErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func macroErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrMacroNotFound), errors.Is(err, services.ErrCharacterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrMacroRecording), errors.Is(err, services.ErrNotRecording),
+		errors.Is(err, services.ErrMacroPlaying), errors.Is(err, services.ErrNotPlaying),
+		errors.Is(err, services.ErrCharacterOffline):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidMacro):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceHandler contains the ServiceManager instance.
+type ServiceHandler struct {
+	serviceManager *services.ServiceManager
+}
+
+// NewServiceHandler creates a new instance of ServiceHandler.
+func NewServiceHandler(sm *services.ServiceManager) *ServiceHandler {
+	return &ServiceHandler{serviceManager: sm}
+}
+
+// ListServices lists the background services.
+// @Summary List services
+// @Description Reports the state, uptime, last error and restarts of every background service. Services failing to start or while they run are restarted with backoff, up to services.restart.maxAttempts times
+// @Tags Services
+// @Produce json
+// @Success 200 {array} services.ServiceStatus
+// @Router /services [get]
+func (h *ServiceHandler) ListServices(c *gin.Context) {
+	c.JSON(http.StatusOK, h.serviceManager.Statuses())
+}
+
+// GetService returns the status of a background service.
+// @Summary Get a service
+// @Description Reports the state, uptime and last error of a background service
+// @Tags Services
+// @Produce json
+// @Param name path string true "Service name"
+// @Success 200 {object} services.ServiceStatus
+// @Failure 404 {object} map[string]string
+// @Router /services/{name} [get]
+func (h *ServiceHandler) GetService(c *gin.Context) {
+	status, err := h.serviceManager.Status(c.Param("name"))
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// StartService starts a background service.
+// @Summary Start a service
+// @Description Starts a background service
+// @Tags Services
+// @Produce json
+// @Param name path string true "Service name"
+// @Success 200 {object} services.ServiceStatus
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Service already running"
+// @Failure 500 {object} map[string]string
+// @Router /services/{name}/start [post]
+func (h *ServiceHandler) StartService(c *gin.Context) {
+	h.transition(c, c.Param("name"), h.serviceManager.Start)
+}
+
+// StopService stops a background service.
+// @Summary Stop a service
+// @Description Stops a background service, or cancels the pending restart of a failed one
+// @Tags Services
+// @Produce json
+// @Param name path string true "Service name"
+// @Success 200 {object} services.ServiceStatus
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Service not running"
+// @Failure 500 {object} map[string]string
+// @Router /services/{name}/stop [post]
+func (h *ServiceHandler) StopService(c *gin.Context) {
+	h.transition(c, c.Param("name"), h.serviceManager.Stop)
+}
+
+// transition starts or stops the named service and responds with its status.
+func (h *ServiceHandler) transition(c *gin.Context, name string, transition func(string) error) {
+	if err := transition(name); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.serviceManager.Status(name)
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// serviceErrorStatus maps service lifecycle errors to HTTP status codes.
+func serviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrServiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrServiceRunning), errors.Is(err, services.ErrServiceNotRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
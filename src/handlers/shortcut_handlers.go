@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/kihw/multy/src/services"
 
@@ -13,44 +15,182 @@ type HandlersService struct {
 	ShortcutService *services.ShortcutService
 }
 
+// ShortcutRequest is the body of shortcut creation and update requests.
+type ShortcutRequest struct {
+	Key        string `json:"key"`
+	Action     string `json:"action"`
+	WindowName string `json:"windowName"`
+	Argument   string `json:"argument"`
+}
+
 // @Summary Register a hotkey
 // @Description Registers a hotkey to focus on a window
 // @Tags Shortcut
 // @Accept json
 // @Produce json
-// @Param key path string true "Key to register"
+// @Param key path string true "Key combination to register, e.g. F1 or Ctrl+Alt+F3"
 // @Param windowName path string true "Name of the window to focus"
-// @Success 200 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} services.Shortcut
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /shortcut/register/{key}/{windowName} [post]
 func (hs *HandlersService) RegisterHotKeyHandler(c *gin.Context) {
-	key := c.Param("key")
-	windowName := c.Param("windowName") // This should come from the request
-
-	shortcut := services.Shortcut{
-		ID:         hs.ShortcutService.GenerateHotkeyID(),
-		Key:        key,
-		WindowName: windowName, // Set the window name here
-	}
-
-	err := hs.ShortcutService.RegisterShortcut(shortcut)
+	shortcut, err := hs.ShortcutService.RegisterShortcut(services.Shortcut{
+		Key:        c.Param("key"),
+		WindowName: c.Param("windowName"),
+	})
 	if err != nil {
 		log.Printf("Error registering shortcut: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(shortcutErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Shortcut registered successfully"})
+	c.JSON(http.StatusOK, shortcut)
 }
 
 // UnregisterHotKeyHandler handles the unregistration of an existing hotkey.
 // @Summary Unregister an existing hotkey
 // @Description Unregisters a previously registered keyboard shortcut
 // @Tags Shortcut
-// @Success 200 {string} string "Raccourci désenregistré avec succès"
-// @Failure 500 {string} string "Failed to unregister shortcut"
-// @Router /shortcut/unregister [delete]
+// @Param id path int true "Shortcut ID"
+// @Success 200 {object} map[string]string "Raccourci désenregistré avec succès"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /shortcut/unregister/{id} [delete]
 func (hs *HandlersService) UnregisterHotKeyHandler(c *gin.Context) {
-	hs.ShortcutService.UnregisterShortcut()
+	hs.DeleteShortcut(c)
+}
+
+// ListShortcuts lists the registered shortcuts.
+// @Summary List hotkeys
+// @Description Lists the registered keyboard shortcuts ordered by ID
+// @Tags Shortcut
+// @Produce json
+// @Success 200 {array} services.Shortcut
+// @Router /shortcuts [get]
+func (hs *HandlersService) ListShortcuts(c *gin.Context) {
+	c.JSON(http.StatusOK, hs.ShortcutService.Shortcuts())
+}
+
+// GetShortcut returns a single shortcut.
+// @Summary Get a hotkey
+// @Description Returns the keyboard shortcut with the given ID
+// @Tags Shortcut
+// @Produce json
+// @Param id path int true "Shortcut ID"
+// @Success 200 {object} services.Shortcut
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /shortcuts/{id} [get]
+func (hs *HandlersService) GetShortcut(c *gin.Context) {
+	id, ok := shortcutID(c)
+	if !ok {
+		return
+	}
+	shortcut, err := hs.ShortcutService.GetShortcut(id)
+	if err != nil {
+		c.JSON(shortcutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shortcut)
+}
+
+// CreateShortcut registers a shortcut.
+// @Summary Create a hotkey
+// @Description Registers a keyboard shortcut focusing the given window, or running an action such as focusNext, focusPrevious, toggleKeyBroadcast, playMacro, recordMacro, stopMacro, arrangeWindows or applyLayout with its argument
+// @Tags Shortcut
+// @Accept json
+// @Produce json
+// @Param shortcut body ShortcutRequest true "Key, and window to focus or action to run"
+// @Success 201 {object} services.Shortcut
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /shortcuts [post]
+func (hs *HandlersService) CreateShortcut(c *gin.Context) {
+	var req ShortcutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	shortcut, err := hs.ShortcutService.RegisterShortcut(services.Shortcut{Key: req.Key, Action: req.Action, WindowName: req.WindowName, Argument: req.Argument})
+	if err != nil {
+		c.JSON(shortcutErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, shortcut)
+}
+
+// UpdateShortcut changes the key and window of a shortcut.
+// @Summary Update a hotkey
+// @Description Changes the key and the window focused or action run by a keyboard shortcut
+// @Tags Shortcut
+// @Accept json
+// @Produce json
+// @Param id path int true "Shortcut ID"
+// @Param shortcut body ShortcutRequest true "Key, and window to focus or action to run"
+// @Success 200 {object} services.Shortcut
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /shortcuts/{id} [put]
+func (hs *HandlersService) UpdateShortcut(c *gin.Context) {
+	id, ok := shortcutID(c)
+	if !ok {
+		return
+	}
+	var req ShortcutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	shortcut, err := hs.ShortcutService.UpdateShortcut(id, services.Shortcut{Key: req.Key, Action: req.Action, WindowName: req.WindowName, Argument: req.Argument})
+	if err != nil {
+		c.JSON(shortcutErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shortcut)
+}
+
+// DeleteShortcut unregisters a shortcut.
+// @Summary Delete a hotkey
+// @Description Unregisters the keyboard shortcut with the given ID
+// @Tags Shortcut
+// @Produce json
+// @Param id path int true "Shortcut ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /shortcuts/{id} [delete]
+func (hs *HandlersService) DeleteShortcut(c *gin.Context) {
+	id, ok := shortcutID(c)
+	if !ok {
+		return
+	}
+	if err := hs.ShortcutService.UnregisterShortcut(id); err != nil {
+		c.JSON(shortcutErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Raccourci désenregistré avec succès"})
 }
+
+// shortcutID parses the :id parameter, answering 400 when it is invalid.
+func shortcutID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shortcut id: " + c.Param("id")})
+		return 0, false
+	}
+	return id, true
+}
+
+// shortcutErrorStatus maps the shortcut service errors to HTTP status codes.
+func shortcutErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrShortcutNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrShortcutExists):
+		return http.StatusConflict
+	default:
+		return fallback
+	}
+}
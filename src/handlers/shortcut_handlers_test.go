@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newShortcutRouter serves the shortcut routes on a fake window backend,
+// counting the calls to the change handler.
+func newShortcutRouter(t *testing.T) (*gin.Engine, *int) {
+	t.Helper()
+	windowService := services.NewWindowService(services.NewFakeWindowBackend())
+	shortcutService := services.NewShortcutService(windowService, services.NewInputDispatcher(), services.NewEventBus())
+	changes := 0
+	shortcutService.SetChangeHandler(func([]services.Shortcut) { changes++ })
+
+	hs := &HandlersService{ShortcutService: shortcutService}
+	r := gin.New()
+	r.POST("/shortcut/register/:key/:windowName", hs.RegisterHotKeyHandler)
+	r.DELETE("/shortcut/unregister/:id", hs.UnregisterHotKeyHandler)
+	r.GET("/shortcuts", hs.ListShortcuts)
+	r.POST("/shortcuts", hs.CreateShortcut)
+	r.GET("/shortcuts/:id", hs.GetShortcut)
+	r.PUT("/shortcuts/:id", hs.UpdateShortcut)
+	r.DELETE("/shortcuts/:id", hs.DeleteShortcut)
+	return r, &changes
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestShortcutHandlers(t *testing.T) {
+	r, changes := newShortcutRouter(t)
+
+	steps := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/shortcuts", `{"key": "F1", "windowName": "Alpha"}`, http.StatusCreated},
+		{http.MethodPost, "/shortcut/register/F2/Beta", "", http.StatusOK},
+		{http.MethodGet, "/shortcuts/1", "", http.StatusOK},
+		{http.MethodPut, "/shortcuts/1", `{"key": "F3", "windowName": "Alpha"}`, http.StatusOK},
+		{http.MethodDelete, "/shortcut/unregister/2", "", http.StatusOK},
+	}
+	for _, step := range steps {
+		if w := serve(r, step.method, step.path, step.body); w.Code != step.status {
+			t.Fatalf("%s %s: status %d (%s), want %d", step.method, step.path, w.Code, w.Body, step.status)
+		}
+	}
+
+	w := serve(r, http.MethodGet, "/shortcuts", "")
+	var shortcuts []services.Shortcut
+	if err := json.Unmarshal(w.Body.Bytes(), &shortcuts); err != nil {
+		t.Fatalf("GET /shortcuts: %v", err)
+	}
+	if len(shortcuts) != 1 || shortcuts[0].ID != 1 || shortcuts[0].Key != "F3" {
+		t.Fatalf("GET /shortcuts = %+v, want the shortcut 1 on F3", shortcuts)
+	}
+	if *changes != 4 {
+		t.Fatalf("change handler called %d times, want once per change", *changes)
+	}
+}
+
+func TestShortcutHandlerErrors(t *testing.T) {
+	r, changes := newShortcutRouter(t)
+	if w := serve(r, http.MethodPost, "/shortcuts", `{"key": "F1", "windowName": "Alpha"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST /shortcuts: status %d (%s)", w.Code, w.Body)
+	}
+	*changes = 0
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/shortcuts", `{"key": "F1", "windowName": "Beta"}`, http.StatusConflict},
+		{http.MethodPost, "/shortcuts", `{"key": "Hyper+F2", "windowName": "Beta"}`, http.StatusBadRequest},
+		{http.MethodPost, "/shortcuts", `{"key": "F2", "action": "dance"}`, http.StatusBadRequest},
+		{http.MethodPost, "/shortcuts", `{"key": `, http.StatusBadRequest},
+		{http.MethodPost, "/shortcut/register/F1/Beta", "", http.StatusConflict},
+		{http.MethodGet, "/shortcuts/42", "", http.StatusNotFound},
+		{http.MethodGet, "/shortcuts/one", "", http.StatusBadRequest},
+		{http.MethodPut, "/shortcuts/42", `{"key": "F2", "windowName": "Beta"}`, http.StatusNotFound},
+		{http.MethodPut, "/shortcuts/1", `{"key": "F2"}`, http.StatusBadRequest},
+		{http.MethodDelete, "/shortcuts/42", "", http.StatusNotFound},
+		{http.MethodDelete, "/shortcut/unregister/one", "", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := serve(r, test.method, test.path, test.body)
+		if w.Code != test.status {
+			t.Errorf("%s %s %s: status %d, want %d", test.method, test.path, test.body, w.Code, test.status)
+			continue
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s %s %s: body %s, want an error", test.method, test.path, test.body, w.Body)
+		}
+	}
+	if *changes != 0 {
+		t.Fatalf("change handler called %d times for rejected requests", *changes)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,37 +11,150 @@ import (
 // StartTurnServiceHandler gère les requêtes liées à StartTurnService.
 type StartTurnServiceHandler struct {
 	StartTurnService *services.StartTurnService
+	ServiceManager   *services.ServiceManager
 }
 
 // NewStartTurnServiceHandler crée un nouveau gestionnaire pour StartTurnService.
-func NewStartTurnServiceHandler(startTurnService *services.StartTurnService) *StartTurnServiceHandler {
+func NewStartTurnServiceHandler(startTurnService *services.StartTurnService, serviceManager *services.ServiceManager) *StartTurnServiceHandler {
 	return &StartTurnServiceHandler{
 		StartTurnService: startTurnService,
+		ServiceManager:   serviceManager,
 	}
 }
 
+// TurnWindowRequest est le corps des requêtes d'ajout et de modification d'une fenêtre surveillée.
+type TurnWindowRequest struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
 // StartService démarre StartTurnService.
-// @Summary Démarrer le service de détection d'événements pour une fenêtre spécifique
-// @Description Démarre le service pour écouter les événements d'une fenêtre spécifiée par son titre
+// @Summary Démarrer le service de détection des débuts de tour
+// @Description Démarre le service pour écouter les demandes d'attention des fenêtres données, ou de toute l'équipe si aucune n'est donnée
 // @Tags StartTurn
 // @Accept  json
 // @Produce  json
-// @Param windowTitle query string true "Titre de la fenêtre à surveiller"
+// @Param windowTitle query []string false "Nom de personnage ou titre de la fenêtre à surveiller" collectionFormat(multi)
 // @Success 200 {object} map[string]string "Service démarré avec succès"
-// @Failure 400 {object} map[string]string "Erreur si le service est déjà en cours ou si windowTitle est manquant"
+// @Failure 409 {object} map[string]string "Service déjà démarré"
+// @Failure 500 {object} map[string]string
 // @Router /start-turn/start [get]
 func (h *StartTurnServiceHandler) StartService(c *gin.Context) {
 
-	// Récupérer le titre de la fenêtre depuis les paramètres de la requête
-	windowTitle := c.Query("windowTitle")
-	if windowTitle == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "windowTitle is required"})
+	// Récupérer les fenêtres depuis les paramètres de la requête
+	windows := c.QueryArray("windowTitle")
+
+	// Démarrer le service avec les fenêtres spécifiées
+	if h.StartTurnService.Status().State != services.ServiceRunning {
+		h.StartTurnService.SetWindows(windows)
+	}
+	if err := h.ServiceManager.Start(h.StartTurnService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "StartTurnService started", "windows": h.StartTurnService.Windows()})
+}
+
+// GetStatus renvoie l'état du service.
+// @Summary État du service de détection des débuts de tour
+// @Description Indique si le service tourne et l'état des fenêtres surveillées, y compris leur fenêtre actuelle après un redémarrage du client
+// @Tags StartTurn
+// @Produce  json
+// @Success 200 {object} services.TurnStatus
+// @Router /start-turn/status [get]
+func (h *StartTurnServiceHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.StartTurnService.TurnStatus())
+}
+
+// GetWindows liste les fenêtres surveillées.
+// @Summary Lister les fenêtres surveillées
+// @Description Liste les fenêtres surveillées par le service de détection des débuts de tour
+// @Tags StartTurn
+// @Produce  json
+// @Success 200 {array} services.TurnWindow
+// @Router /start-turn/windows [get]
+func (h *StartTurnServiceHandler) GetWindows(c *gin.Context) {
+	c.JSON(http.StatusOK, h.StartTurnService.Windows())
+}
+
+// AddWindow ajoute une fenêtre surveillée.
+// @Summary Surveiller une fenêtre
+// @Description Ajoute une fenêtre, désignée par un nom de personnage ou une partie de son titre, y compris pendant que le service tourne
+// @Tags StartTurn
+// @Accept  json
+// @Produce  json
+// @Param window body TurnWindowRequest true "Nom de la fenêtre"
+// @Success 201 {object} services.TurnWindow
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "Fenêtre déjà surveillée"
+// @Router /start-turn/windows [post]
+func (h *StartTurnServiceHandler) AddWindow(c *gin.Context) {
+	var req TurnWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window, err := h.StartTurnService.AddWindow(req.Name)
+	if err != nil {
+		c.JSON(turnWindowErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, window)
+}
+
+// UpdateWindow active ou désactive une fenêtre surveillée.
+// @Summary Activer ou désactiver une fenêtre surveillée
+// @Description Active ou désactive la mise au premier plan d'une fenêtre surveillée, sans l'enlever
+// @Tags StartTurn
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Nom de la fenêtre"
+// @Param window body TurnWindowRequest true "État de la fenêtre"
+// @Success 200 {object} services.TurnWindow
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string "Fenêtre non surveillée"
+// @Router /start-turn/windows/{name} [put]
+func (h *StartTurnServiceHandler) UpdateWindow(c *gin.Context) {
+	var req TurnWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window, err := h.StartTurnService.SetWindowEnabled(c.Param("name"), req.Enabled)
+	if err != nil {
+		c.JSON(turnWindowErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, window)
+}
+
+// RemoveWindow enlève une fenêtre surveillée.
+// @Summary Ne plus surveiller une fenêtre
+// @Description Enlève une fenêtre des fenêtres surveillées, y compris pendant que le service tourne
+// @Tags StartTurn
+// @Produce  json
+// @Param name path string true "Nom de la fenêtre"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string "Fenêtre non surveillée"
+// @Router /start-turn/windows/{name} [delete]
+func (h *StartTurnServiceHandler) RemoveWindow(c *gin.Context) {
+	if err := h.StartTurnService.RemoveWindow(c.Param("name")); err != nil {
+		c.JSON(turnWindowErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Window removed"})
+}
 
-	// Démarrer le service avec le titre de la fenêtre spécifié
-	h.StartTurnService.Start(windowTitle)
-	c.JSON(http.StatusOK, gin.H{"message": "StartTurnService started for window", "windowTitle": windowTitle})
+// turnWindowErrorStatus associe les erreurs du service aux codes HTTP.
+func turnWindowErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrTurnWindowNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrTurnWindowExists):
+		return http.StatusConflict
+	default:
+		return fallback
+	}
 }
 
 // StopService arrête StartTurnService.
@@ -49,11 +163,14 @@ func (h *StartTurnServiceHandler) StartService(c *gin.Context) {
 // @Tags StartTurn
 // @Produce  json
 // @Success 200 {object} map[string]string "Service arrêté avec succès"
-// @Failure 400 {object} map[string]string "Erreur si le service n'est pas en cours"
+// @Failure 409 {object} map[string]string "Erreur si le service n'est pas en cours"
 // @Router /start-turn/stop [get]
 func (h *StartTurnServiceHandler) StopService(c *gin.Context) {
 
 	// Arrêter le service
-	h.StartTurnService.Stop()
+	if err := h.ServiceManager.Stop(h.StartTurnService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "StartTurnService stopped"})
 }
@@ -10,6 +10,7 @@ import (
 
 type WheelClickHandler struct {
 	WheelClickService *services.WheelClickService
+	ServiceManager    *services.ServiceManager
 }
 
 // StartWheelClick listens for middle mouse clicks.
@@ -18,10 +19,14 @@ type WheelClickHandler struct {
 // @Tags WheelClick
 // @Produce json
 // @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string "Detection already started"
 // @Failure 500 {object} map[string]string
 // @Router /wheelclick/start [post]
 func (h *WheelClickHandler) StartWheelClick(c *gin.Context) {
-	h.WheelClickService.Start()
+	if err := h.ServiceManager.Start(h.WheelClickService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Wheel click detection started"})
 }
 
@@ -31,9 +36,30 @@ func (h *WheelClickHandler) StartWheelClick(c *gin.Context) {
 // @Tags WheelClick
 // @Produce json
 // @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string "Detection not started"
 // @Failure 500 {object} map[string]string
 // @Router /wheelclick/stop [post]
 func (h *WheelClickHandler) StopWheelClick(c *gin.Context) {
-	h.WheelClickService.Stop()
+	if err := h.ServiceManager.Stop(h.WheelClickService.Name()); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Wheel click detection stopped"})
 }
+
+// GetReport returns the report of the last broadcast click.
+// @Summary Get the last click broadcast report
+// @Description Returns the windows clicked by the last broadcast, in order, with the delay, start and duration of each click
+// @Tags WheelClick
+// @Produce json
+// @Success 200 {object} services.BroadcastReport
+// @Failure 404 {object} map[string]string "No click broadcast yet"
+// @Router /wheelclick/report [get]
+func (h *WheelClickHandler) GetReport(c *gin.Context) {
+	report, ok := h.WheelClickService.LastReport()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no click was broadcast yet"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
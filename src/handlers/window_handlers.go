@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kihw/multy/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WindowHandler contains the WindowService instance.
+type WindowHandler struct {
+	windowService *services.WindowService
+}
+
+// NewWindowHandler creates a new instance of WindowHandler.
+func NewWindowHandler(ws *services.WindowService) *WindowHandler {
+	return &WindowHandler{windowService: ws}
+}
+
+// GetWindows retourne la liste des fenêtres ouvertes.
+// @Summary Retourne la liste des fenêtres ouvertes
+// @Description Obtient la liste des fenêtres actuellement ouvertes sur le système, éventuellement filtrée
+// @Tags Windows
+// @Produce json
+// @Param game query string false "Jeu recherché dans le titre ou l'exécutable (ex: dofus)"
+// @Param title query string false "Partie du titre de la fenêtre"
+// @Param visible query bool false "Fenêtres visibles uniquement (true) ou cachées (false)"
+// @Param minimized query bool false "Fenêtres réduites uniquement (true) ou non (false)"
+// @Success 200 {array} services.Window "Liste des fenêtres ouvertes"
+// @Failure 400 {object} map[string]string "Filtre invalide"
+// @Failure 500 {object} map[string]string "Erreur lors de la récupération des fenêtres"
+// @Router /windows [get]
+func (h *WindowHandler) GetWindows(c *gin.Context) {
+	filter := services.WindowFilter{
+		Game:  c.Query("game"),
+		Title: c.Query("title"),
+	}
+
+	var err error
+	if filter.Visible, err = queryBool(c, "visible"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Minimized, err = queryBool(c, "minimized"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	windows, err := h.windowService.ListWindows(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if windows == nil {
+		windows = []services.Window{}
+	}
+	c.JSON(http.StatusOK, windows)
+}
+
+// FocusWindow met au premier plan une fenêtre.
+// @Summary Met en avant une fenêtre spécifique
+// @Description Met en avant une fenêtre qui contient un mot-clé spécifique dans son titre
+// @Tags Windows
+// @Produce json
+// @Param keyword path string true "Mot-clé pour identifier la fenêtre"
+// @Success 200 {object} map[string]string "Fenêtre mise en avant avec succès"
+// @Failure 500 {object} map[string]string "Erreur lors de la mise en avant de la fenêtre"
+// @Router /focus/{keyword} [post]
+func (h *WindowHandler) FocusWindow(c *gin.Context) {
+	keyword := c.Param("keyword")
+	err := h.windowService.FocusWindowWithTitle(keyword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Window focused successfully"})
+}
+
+// GetMonitors returns the monitors.
+// @Summary List monitors
+// @Description Returns the bounds, the work area and the DPI of each monitor, the primary one first. Coordinates are physical pixels of the virtual screen, negative left of or above the primary monitor
+// @Tags Windows
+// @Produce json
+// @Success 200 {array} services.Monitor
+// @Failure 500 {object} map[string]string
+// @Router /monitors [get]
+func (h *WindowHandler) GetMonitors(c *gin.Context) {
+	monitors, err := h.windowService.Monitors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, monitors)
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(c *gin.Context, name string) (*bool, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %q", name, raw)
+	}
+	return &value, nil
+}
@@ -1,12 +1,17 @@
 package main
 
-// #cgo CFLAGS: -IC:/opencv_gw/include
-// #cgo LDFLAGS: -LC:/opencv_gw/x64/mingw/lib -lopencv_core455 -lopencv_imgproc455 -lopencv_imgcodecs455 -lopencv_highgui455 -lopencv_videoio455
-import "C"
-
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kihw/multy/src/config"
 	_ "github.com/kihw/multy/src/docs"
 	"github.com/kihw/multy/src/handlers"
 	"github.com/kihw/multy/src/routes"
@@ -27,27 +32,97 @@ import (
 // @host localhost:8080
 // @BasePath /
 func main() {
+	configPath := flag.String("config", config.DefaultPath, "configuration file")
+	backendName := flag.String("backend", "", "window backend: auto, win32, x11 or fake (overrides the configuration)")
+	macrosPath := flag.String("macros", "macros.json", "file storing the recorded macros")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "time given to in-flight requests on shutdown")
+	flag.Parse()
+
+	// Ctrl+C and SIGTERM shut the server down gracefully.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Load the configuration, created with defaults on first run.
+	configStore, err := config.Open(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg := configStore.Current()
+	if *backendName != "" {
+		cfg.Backend = *backendName
+	}
+
 	r := gin.Default()
 
-	// Initialize services (without starting StartTurnService).
-	wheelClickService := &services.WheelClickService{}
-	shortcutService := services.NewShortcutService()
-	windowService := &services.WindowService{}
-	startTurnService := services.NewStartTurnService(windowService) // Pas de démarrage automatique
-	dofusCheckService := services.NewDofusCheckService(windowService)
+	// Initialize services, started according to the configuration.
+	windowBackend, err := services.NewWindowBackend(cfg.Backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	windowService := services.NewWindowService(windowBackend)
+	inputDispatcher := services.NewInputDispatcher()
+	eventBus := services.NewEventBus()
+	// Closed on shutdown to end the background watchers.
+	stop := make(chan struct{})
+	if err := eventBus.WatchWindows(windowService, stop); err != nil {
+		log.Printf("Window events will not be streamed: %v", err)
+	}
+	characterService := services.NewCharacterService(windowService)
+	wheelClickService := services.NewWheelClickService(windowService, characterService, inputDispatcher, eventBus)
+	shortcutService := services.NewShortcutService(windowService, inputDispatcher, eventBus)
+	startTurnService := services.NewStartTurnService(windowService, characterService, eventBus)
+	dofusCheckService := services.NewDofusCheckService(windowService, eventBus)
+	keyBroadcastService := services.NewKeyBroadcastService(windowService, characterService, inputDispatcher, eventBus)
+	macroService := services.NewMacroService(windowService, characterService, inputDispatcher, eventBus, *macrosPath)
+	if err := macroService.Load(); err != nil {
+		log.Printf("Failed to load the macros: %v", err)
+	}
+	layoutService := services.NewLayoutService(windowService, characterService, eventBus)
+	captureService := services.NewCaptureService(windowService)
+	servicesCtx, cancelServices := context.WithCancel(context.Background())
+	serviceManager := services.NewServiceManager(servicesCtx, eventBus,
+		shortcutService, wheelClickService, keyBroadcastService, dofusCheckService, startTurnService)
+
+	multy := &app{
+		config:            configStore,
+		windowService:     windowService,
+		wheelClickService: wheelClickService,
+		shortcutService:   shortcutService,
+		startTurnService:  startTurnService,
+		dofusCheckService: dofusCheckService,
+		characterService:  characterService,
+		keyBroadcast:      keyBroadcastService,
+		serviceManager:    serviceManager,
+		macroService:      macroService,
+		layoutService:     layoutService,
+	}
+	multy.registerShortcutActions()
+	multy.applyConfig(configStore.Current())
+	shortcutService.SetChangeHandler(multy.persistShortcuts)
+	keyBroadcastService.SetChangeHandler(multy.persistKeyBroadcast)
+	layoutService.SetChangeHandler(multy.persistLayouts)
+	if err := serviceManager.Start(shortcutService.Name()); err != nil {
+		log.Printf("Shortcuts will not be handled: %v", err)
+	}
+	configStore.OnChange(multy.applyConfig)
+	if err := configStore.Watch(stop); err != nil {
+		log.Printf("Configuration changes will not be applied live: %v", err)
+	}
+
 	// Initialize handlers with their respective services.
 	wheelClickHandler := &handlers.WheelClickHandler{
 		WheelClickService: wheelClickService,
+		ServiceManager:    serviceManager,
 	}
 	handlersService := &handlers.HandlersService{
 		ShortcutService: shortcutService,
 	}
-	startTurnServiceHandler := handlers.NewStartTurnServiceHandler(startTurnService)
+	startTurnServiceHandler := handlers.NewStartTurnServiceHandler(startTurnService, serviceManager)
 
 	// Log open windows for debugging.
 	windows, err := windowService.GetWindows()
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Failed to list open windows: %v", err)
 	}
 	log.Println("Open windows:", windows)
 
@@ -56,10 +131,49 @@ func main() {
 	routes.SetupWindowRoutes(r, windowService)
 	routes.SetupWheelClickRoutes(r, wheelClickHandler)
 	routes.SetupStartTurnServiceRoutes(r, startTurnServiceHandler)
-	routes.SetupRoutesDofusCheck(r, dofusCheckService)
+	routes.SetupRoutesDofusCheck(r, dofusCheckService, serviceManager)
+	routes.SetupCharacterRoutes(r, characterService)
+	routes.SetupEventRoutes(r, eventBus)
+	routes.SetupServiceRoutes(r, serviceManager)
+	routes.SetupKeyBroadcastRoutes(r, keyBroadcastService, serviceManager)
+	routes.SetupMacroRoutes(r, macroService)
+	routes.SetupLayoutRoutes(r, layoutService)
+	routes.SetupCaptureRoutes(r, captureService)
 	// Swagger route for API documentation.
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swagFiles.Handler))
 
-	// Start the server on port 8080.
-	r.Run(":8080")
+	// Start the server on the configured address.
+	server := &http.Server{Addr: cfg.Listen, Handler: r}
+	// Event streams never end by themselves, close them first.
+	server.RegisterOnShutdown(eventBus.Close)
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", cfg.Listen)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	case err := <-serverErr:
+		log.Printf("Server stopped: %v", err)
+	}
+	stopSignals()
+
+	// Drain the in-flight requests, then stop the services in order, so
+	// that no request starts a service while they stop.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("In-flight requests did not complete: %v", err)
+	}
+	macroService.Close()
+	serviceManager.StopAll()
+	cancelServices()
+
+	// Unhook input, end the shell hook message loops and the watchers.
+	close(stop)
+	inputDispatcher.Close()
+	eventBus.Close()
+	log.Println("Shutdown complete")
 }
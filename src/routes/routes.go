@@ -1,8 +1,6 @@
 package routes
 
 import (
-	"net/http"
-
 	"github.com/kihw/multy/src/services"
 
 	"github.com/kihw/multy/src/handlers"
@@ -12,37 +10,31 @@ import (
 
 // SetupWindowRoutes configure les routes liées aux fenêtres
 func SetupWindowRoutes(r *gin.Engine, ws *services.WindowService) {
+	windowHandler := handlers.NewWindowHandler(ws)
+
 	// Route to get the list of open windows
-	r.GET("/windows", func(c *gin.Context) {
-		windows, err := ws.GetWindows()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusOK, windows)
-		}
-	})
-
-	// Ensure the windowService is correctly passed
-	r.POST("/focus/:keyword", func(c *gin.Context) {
-		keyword := c.Param("keyword")
-		err := ws.FocusWindowWithTitle(keyword) // Use the same window service instance
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"message": "Window focused successfully"})
-	})
+	r.GET("/windows", windowHandler.GetWindows)
+	r.GET("/monitors", windowHandler.GetMonitors)
+
+	r.POST("/focus/:keyword", windowHandler.FocusWindow)
 }
 
 // SetupShortcutRoutes configure les routes liées aux raccourcis
 func SetupShortcutRoutes(r *gin.Engine, hs *handlers.HandlersService) {
 	r.POST("/shortcut/register/:key/:windowName", hs.RegisterHotKeyHandler)
 	r.DELETE("/shortcut/unregister/:id", hs.UnregisterHotKeyHandler)
+
+	r.GET("/shortcuts", hs.ListShortcuts)
+	r.POST("/shortcuts", hs.CreateShortcut)
+	r.GET("/shortcuts/:id", hs.GetShortcut)
+	r.PUT("/shortcuts/:id", hs.UpdateShortcut)
+	r.DELETE("/shortcuts/:id", hs.DeleteShortcut)
 }
 
 func SetupWheelClickRoutes(r *gin.Engine, wh *handlers.WheelClickHandler) {
 	r.POST("/wheelclick/start", wh.StartWheelClick)
 	r.POST("/wheelclick/stop", wh.StopWheelClick)
+	r.GET("/wheelclick/report", wh.GetReport)
 }
 
 func SetupStartTurnServiceRoutes(router *gin.Engine, handler *handlers.StartTurnServiceHandler) {
@@ -51,11 +43,91 @@ func SetupStartTurnServiceRoutes(router *gin.Engine, handler *handlers.StartTurn
 
 	// Route pour arrêter le service
 	router.GET("/start-turn/stop", handler.StopService)
+
+	router.GET("/start-turn/status", handler.GetStatus)
+
+	// Routes pour gérer les fenêtres surveillées
+	router.GET("/start-turn/windows", handler.GetWindows)
+	router.POST("/start-turn/windows", handler.AddWindow)
+	router.PUT("/start-turn/windows/:name", handler.UpdateWindow)
+	router.DELETE("/start-turn/windows/:name", handler.RemoveWindow)
 }
 
-func SetupRoutesDofusCheck(router *gin.Engine, dofusCheckService *services.DofusCheckService) {
-	dofusCheckHandler := handlers.NewDofusCheckHandler(dofusCheckService)
+func SetupRoutesDofusCheck(router *gin.Engine, dofusCheckService *services.DofusCheckService, serviceManager *services.ServiceManager) {
+	dofusCheckHandler := handlers.NewDofusCheckHandler(dofusCheckService, serviceManager)
 
 	router.POST("/dofus-check/start", dofusCheckHandler.StartDofusCheck)
 	router.POST("/dofus-check/stop", dofusCheckHandler.StopDofusCheck)
 }
+
+func SetupCharacterRoutes(router *gin.Engine, characterService *services.CharacterService) {
+	characterHandler := handlers.NewCharacterHandler(characterService)
+
+	router.GET("/characters", characterHandler.GetCharacters)
+	router.POST("/characters", characterHandler.CreateCharacter)
+	router.GET("/characters/:id", characterHandler.GetCharacter)
+	router.PUT("/characters/:id", characterHandler.UpdateCharacter)
+	router.DELETE("/characters/:id", characterHandler.DeleteCharacter)
+	router.POST("/characters/:id/focus", characterHandler.FocusCharacter)
+
+	router.GET("/team/focus", characterHandler.GetFocusedCharacter)
+	router.POST("/team/next", characterHandler.FocusNextCharacter)
+	router.POST("/team/previous", characterHandler.FocusPreviousCharacter)
+}
+
+func SetupEventRoutes(router *gin.Engine, eventBus *services.EventBus) {
+	eventHandler := handlers.NewEventHandler(eventBus)
+
+	router.GET("/events", eventHandler.StreamEvents)
+}
+
+func SetupServiceRoutes(router *gin.Engine, serviceManager *services.ServiceManager) {
+	serviceHandler := handlers.NewServiceHandler(serviceManager)
+
+	router.GET("/services", serviceHandler.ListServices)
+	router.GET("/services/:name", serviceHandler.GetService)
+	router.POST("/services/:name/start", serviceHandler.StartService)
+	router.POST("/services/:name/stop", serviceHandler.StopService)
+}
+
+func SetupKeyBroadcastRoutes(router *gin.Engine, keyBroadcastService *services.KeyBroadcastService, serviceManager *services.ServiceManager) {
+	keyBroadcastHandler := handlers.NewKeyBroadcastHandler(keyBroadcastService, serviceManager)
+
+	router.GET("/key-broadcast", keyBroadcastHandler.GetSettings)
+	router.PUT("/key-broadcast", keyBroadcastHandler.UpdateSettings)
+	router.POST("/key-broadcast/toggle", keyBroadcastHandler.Toggle)
+	router.PUT("/key-broadcast/excluded/:name", keyBroadcastHandler.ExcludeWindow)
+	router.DELETE("/key-broadcast/excluded/:name", keyBroadcastHandler.IncludeWindow)
+}
+
+func SetupMacroRoutes(router *gin.Engine, macroService *services.MacroService) {
+	macroHandler := handlers.NewMacroHandler(macroService)
+
+	router.GET("/macros", macroHandler.ListMacros)
+	router.POST("/macros/record", macroHandler.StartRecording)
+	router.POST("/macros/record/stop", macroHandler.StopRecording)
+	router.GET("/macros/playback", macroHandler.GetPlayback)
+	router.DELETE("/macros/playback", macroHandler.StopPlayback)
+	router.GET("/macros/:name", macroHandler.GetMacro)
+	router.PUT("/macros/:name", macroHandler.SaveMacro)
+	router.DELETE("/macros/:name", macroHandler.DeleteMacro)
+	router.POST("/macros/:name/play", macroHandler.PlayMacro)
+}
+
+func SetupLayoutRoutes(router *gin.Engine, layoutService *services.LayoutService) {
+	layoutHandler := handlers.NewLayoutHandler(layoutService)
+
+	router.GET("/layouts", layoutHandler.ListLayouts)
+	router.POST("/layouts/capture", layoutHandler.CaptureLayout)
+	router.POST("/layouts/arrange/:arrangement", layoutHandler.Arrange)
+	router.GET("/layouts/:name", layoutHandler.GetLayout)
+	router.PUT("/layouts/:name", layoutHandler.SaveLayout)
+	router.DELETE("/layouts/:name", layoutHandler.DeleteLayout)
+	router.POST("/layouts/:name/apply", layoutHandler.ApplyLayout)
+}
+
+func SetupCaptureRoutes(router *gin.Engine, captureService *services.CaptureService) {
+	captureHandler := handlers.NewCaptureHandler(captureService)
+
+	router.GET("/windows/:id/screenshot", captureHandler.GetScreenshot)
+}
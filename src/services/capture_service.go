@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+var (
+	ErrWindowNotFound  = errors.New("window not found")
+	ErrWindowMinimized = errors.New("window is minimized")
+	ErrInvalidCapture  = errors.New("invalid capture")
+)
+
+// Image formats of the captures.
+const (
+	CaptureFormatPNG  = "png"
+	CaptureFormatJPEG = "jpeg"
+)
+
+const (
+	// DefaultJPEGQuality is the quality of JPEG captures when none is given.
+	DefaultJPEGQuality = 90
+	// MaxCaptureScale bounds the enlargement of captures.
+	MaxCaptureScale = 4
+)
+
+// CaptureOptions selects the part of the client area to capture and its size.
+type CaptureOptions struct {
+	// Crop is a rectangle of the client area, the whole client area when
+	// empty. It is clipped to the client area.
+	Crop Rect
+	// Scale resizes the capture, 1 when zero.
+	Scale float64
+	// Format is the encoding of screenshots, CaptureFormatPNG when empty.
+	Format string
+	// Quality of JPEG screenshots, from 1 to 100, DefaultJPEGQuality when
+	// zero.
+	Quality int
+}
+
+// checked validates the options and fills in the defaults.
+func (options CaptureOptions) checked() (CaptureOptions, error) {
+	if options.Scale == 0 {
+		options.Scale = 1
+	}
+	if options.Format == "" {
+		options.Format = CaptureFormatPNG
+	}
+	if options.Quality == 0 {
+		options.Quality = DefaultJPEGQuality
+	}
+
+	switch {
+	case !(options.Scale > 0 && options.Scale <= MaxCaptureScale):
+		return options, fmt.Errorf("%w: scale must be greater than 0 and at most %d", ErrInvalidCapture, MaxCaptureScale)
+	case options.Crop != (Rect{}) && (options.Crop.Width() <= 0 || options.Crop.Height() <= 0):
+		return options, fmt.Errorf("%w: crop rectangle is empty", ErrInvalidCapture)
+	case options.Format != CaptureFormatPNG && options.Format != CaptureFormatJPEG:
+		return options, fmt.Errorf("%w: unknown format %q", ErrInvalidCapture, options.Format)
+	case options.Quality < 1 || options.Quality > 100:
+		return options, fmt.Errorf("%w: quality must be between 1 and 100", ErrInvalidCapture)
+	}
+	return options, nil
+}
+
+// CaptureService takes screenshots of the client area of windows.
+type CaptureService struct {
+	windowService *WindowService
+}
+
+// NewCaptureService creates a new instance of the CaptureService.
+func NewCaptureService(ws *WindowService) *CaptureService {
+	return &CaptureService{windowService: ws}
+}
+
+// Capture copies the client area of a window, even when it is covered by
+// other windows, then crops and scales it. Minimized windows have nothing
+// to capture. The bounds of the capture start at (0, 0).
+func (cs *CaptureService) Capture(hwnd WindowHandle, options CaptureOptions) (*image.RGBA, error) {
+	options, err := options.checked()
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := cs.windowService.GetWindow(hwnd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrWindowNotFound, hwnd)
+	}
+	if window.Minimized {
+		return nil, fmt.Errorf("%w: %d", ErrWindowMinimized, hwnd)
+	}
+
+	img, err := cs.windowService.Backend().CaptureClient(hwnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Crop != (Rect{}) {
+		crop := image.Rect(options.Crop.Left, options.Crop.Top, options.Crop.Right, options.Crop.Bottom)
+		if crop = crop.Intersect(img.Bounds()); crop.Empty() {
+			return nil, fmt.Errorf("%w: crop rectangle is outside of the %dx%d client area", ErrInvalidCapture, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+		img = img.SubImage(crop).(*image.RGBA)
+	}
+
+	// Cropped captures are copied too, so that every capture starts at (0, 0).
+	if options.Scale != 1 || img.Rect.Min != (image.Point{}) {
+		width := max(int(math.Round(float64(img.Bounds().Dx())*options.Scale)), 1)
+		height := max(int(math.Round(float64(img.Bounds().Dy())*options.Scale)), 1)
+		img = resize(img, width, height)
+	}
+	return img, nil
+}
+
+// resize scales img to width x height. Each pixel is the average of the
+// pixels it covers when shrinking, and the nearest pixel when enlarging,
+// which keeps the pixels of the game sharp. The result starts at (0, 0).
+func resize(img *image.RGBA, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		top := bounds.Min.Y + y*bounds.Dy()/height
+		bottom := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, top+1)
+		for x := 0; x < width; x++ {
+			left := bounds.Min.X + x*bounds.Dx()/width
+			right := max(bounds.Min.X+(x+1)*bounds.Dx()/width, left+1)
+
+			var sum [4]int
+			for sy := top; sy < bottom; sy++ {
+				row := img.Pix[img.PixOffset(left, sy):img.PixOffset(right, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			count := (right - left) * (bottom - top)
+			offset := result.PixOffset(x, y)
+			for c := range sum {
+				result.Pix[offset+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+	return result
+}
+
+// Screenshot captures a window as Capture does, and encodes the capture in
+// the format of the options.
+func (cs *CaptureService) Screenshot(w io.Writer, hwnd WindowHandle, options CaptureOptions) error {
+	options, err := options.checked()
+	if err != nil {
+		return err
+	}
+	img, err := cs.Capture(hwnd, options)
+	if err != nil {
+		return err
+	}
+
+	if options.Format == CaptureFormatJPEG {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: options.Quality})
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// newCaptureService opens an 800x600 window whose client area shows 40x40
+// squares of colors derived from their position.
+func newCaptureService(t *testing.T) (*FakeWindowBackend, *CaptureService, WindowHandle) {
+	t.Helper()
+	fake := NewFakeWindowBackend()
+	hwnd := fake.AddWindow("Alpha - Dofus 2.70.5")
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			img.SetRGBA(x, y, squareColor(x, y))
+		}
+	}
+	fake.SetWindowImage(hwnd, img)
+	return fake, NewCaptureService(NewWindowService(fake)), hwnd
+}
+
+func squareColor(x, y int) color.RGBA {
+	return color.RGBA{R: uint8(x / 40 * 12), G: uint8(y / 40 * 16), B: 0x80, A: 0xFF}
+}
+
+func TestCaptureOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CaptureOptions
+		valid   bool
+	}{
+		{"defaults", CaptureOptions{}, true},
+		{"jpeg", CaptureOptions{Format: CaptureFormatJPEG}, true},
+		{"gif", CaptureOptions{Format: "gif"}, false},
+		{"uppercase format", CaptureOptions{Format: "PNG"}, false},
+		{"lowest quality", CaptureOptions{Format: CaptureFormatJPEG, Quality: 1}, true},
+		{"highest quality", CaptureOptions{Format: CaptureFormatJPEG, Quality: 100}, true},
+		{"quality above 100", CaptureOptions{Format: CaptureFormatJPEG, Quality: 101}, false},
+		{"negative quality", CaptureOptions{Format: CaptureFormatJPEG, Quality: -1}, false},
+		{"largest scale", CaptureOptions{Scale: MaxCaptureScale}, true},
+		{"scale above the maximum", CaptureOptions{Scale: MaxCaptureScale + 0.5}, false},
+		{"negative scale", CaptureOptions{Scale: -1}, false},
+		{"empty crop", CaptureOptions{Crop: Rect{Left: 10, Top: 10, Right: 10, Bottom: 20}}, false},
+	}
+	for _, test := range tests {
+		options, err := test.options.checked()
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case !test.valid && !errors.Is(err, ErrInvalidCapture):
+			t.Errorf("%s: got %v, want ErrInvalidCapture", test.name, err)
+		case test.valid && (options.Scale == 0 || options.Format == "" || options.Quality == 0):
+			t.Errorf("%s: defaults not filled in: %+v", test.name, options)
+		}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	_, cs, hwnd := newCaptureService(t)
+
+	img, err := cs.Capture(hwnd, CaptureOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 800, 600) || img.RGBAAt(420, 130) != squareColor(420, 130) {
+		t.Fatalf("capture of %v, want the 800x600 client area", img.Bounds())
+	}
+
+	// Crops start at (0, 0) and are clipped to the client area.
+	img, err = cs.Capture(hwnd, CaptureOptions{Crop: Rect{Left: 760, Top: 40, Right: 900, Bottom: 120}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 40, 80) || img.RGBAAt(0, 0) != squareColor(760, 40) {
+		t.Fatalf("crop of %v starting with %v, want 40x80 starting with %v", img.Bounds(), img.RGBAAt(0, 0), squareColor(760, 40))
+	}
+
+	img, err = cs.Capture(hwnd, CaptureOptions{Crop: Rect{Left: 80, Top: 80, Right: 160, Bottom: 160}, Scale: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 40, 40) || img.RGBAAt(39, 39) != squareColor(159, 159) {
+		t.Fatalf("scaled crop of %v, want 40x40", img.Bounds())
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	fake, cs, hwnd := newCaptureService(t)
+
+	if _, err := cs.Capture(hwnd, CaptureOptions{Crop: Rect{Left: 900, Top: 0, Right: 1000, Bottom: 100}}); !errors.Is(err, ErrInvalidCapture) {
+		t.Errorf("crop outside of the client area: got %v, want ErrInvalidCapture", err)
+	}
+	if _, err := cs.Capture(hwnd+100, CaptureOptions{}); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("unknown window: got %v, want ErrWindowNotFound", err)
+	}
+	fake.MinimizeWindow(hwnd)
+	if _, err := cs.Capture(hwnd, CaptureOptions{}); !errors.Is(err, ErrWindowMinimized) {
+		t.Errorf("minimized window: got %v, want ErrWindowMinimized", err)
+	}
+}
+
+func TestScreenshotPNG(t *testing.T) {
+	_, cs, hwnd := newCaptureService(t)
+	capture, err := cs.Capture(hwnd, CaptureOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := cs.Screenshot(&buf, hwnd, CaptureOptions{}); err != nil {
+		t.Fatalf("Screenshot: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding the screenshot: %v", err)
+	}
+	if decoded.Bounds() != capture.Bounds() {
+		t.Fatalf("screenshot of %v, want %v", decoded.Bounds(), capture.Bounds())
+	}
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			if got := color.RGBAModel.Convert(decoded.At(x, y)); got != capture.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d,%d) is %v, want %v", x, y, got, capture.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestScreenshotJPEG(t *testing.T) {
+	_, cs, hwnd := newCaptureService(t)
+
+	sizes := make(map[int]int)
+	for _, quality := range []int{10, 100} {
+		var buf bytes.Buffer
+		if err := cs.Screenshot(&buf, hwnd, CaptureOptions{Format: CaptureFormatJPEG, Quality: quality}); err != nil {
+			t.Fatalf("Screenshot at quality %d: %v", quality, err)
+		}
+		sizes[quality] = buf.Len()
+
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatalf("decoding the screenshot at quality %d: %v", quality, err)
+		}
+		if decoded.Bounds() != image.Rect(0, 0, 800, 600) {
+			t.Fatalf("screenshot of %v, want 800x600", decoded.Bounds())
+		}
+		if quality < 100 {
+			continue
+		}
+		// The squares are uniform, their centers survive the compression.
+		for _, p := range []image.Point{{20, 20}, {420, 300}, {780, 580}} {
+			got := color.RGBAModel.Convert(decoded.At(p.X, p.Y)).(color.RGBA)
+			want := squareColor(p.X, p.Y)
+			if absDiff(got.R, want.R) > 4 || absDiff(got.G, want.G) > 4 || absDiff(got.B, want.B) > 4 {
+				t.Errorf("pixel %v is %v, want about %v", p, got, want)
+			}
+		}
+	}
+	if sizes[10] >= sizes[100] {
+		t.Fatalf("screenshot of %d bytes at quality 10, want less than the %d bytes at 100", sizes[10], sizes[100])
+	}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCharacterNotFound = errors.New("character not found")
+	ErrCharacterExists   = errors.New("character already exists")
+	ErrSlotTaken         = errors.New("slot already taken")
+	ErrCharacterOffline  = errors.New("character has no open client")
+	ErrNoCharacterFocus  = errors.New("no team member has focus")
+)
+
+// gameVersionPattern matches the version part of a game window title.
+var gameVersionPattern = regexp.MustCompile(`^(?:Dofus\s*)?v?(\d+(?:\.\d+)+)`)
+
+// Character is a team member, bound to the game client window it runs in.
+type Character struct {
+	Name    string       `json:"name"`
+	Slot    int          `json:"slot"`
+	Version string       `json:"version"`
+	Title   string       `json:"title"`
+	Handle  WindowHandle `json:"handle" swaggertype:"integer"`
+	Online  bool         `json:"online"`
+}
+
+// ParseGameTitle extracts the character name and game version from a client
+// window title such as "Name - Dofus 2.70.5" or "Name - Iop - 3.0.12.8 - Release".
+func ParseGameTitle(title string) (name, version string, ok bool) {
+	parts := strings.Split(title, " - ")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+
+	name = strings.TrimSpace(parts[0])
+	if name == "" || strings.EqualFold(name, "Dofus") {
+		return "", "", false
+	}
+
+	for _, part := range parts[1:] {
+		if match := gameVersionPattern.FindStringSubmatch(strings.TrimSpace(part)); match != nil {
+			return name, match[1], true
+		}
+	}
+	return "", "", false
+}
+
+// CharacterService keeps a registry of characters discovered from the game
+// client windows, each assigned a stable slot number.
+type CharacterService struct {
+	mu            sync.Mutex
+	windowService *WindowService
+	characters    map[string]*Character // keyed by lower-cased name
+}
+
+// NewCharacterService creates a new instance of the CharacterService.
+func NewCharacterService(windowService *WindowService) *CharacterService {
+	return &CharacterService{
+		windowService: windowService,
+		characters:    make(map[string]*Character),
+	}
+}
+
+// Refresh discovers the running clients and binds them to their characters,
+// registering unknown characters in the first free slot.
+func (cs *CharacterService) Refresh() error {
+	windows, err := cs.windowService.ListWindows(WindowFilter{Game: "dofus"})
+	if err != nil {
+		return err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, character := range cs.characters {
+		character.Online = false
+		character.Handle = 0
+	}
+
+	for _, window := range windows {
+		name, version, ok := ParseGameTitle(window.Title)
+		if !ok {
+			continue
+		}
+
+		character, exists := cs.characters[strings.ToLower(name)]
+		if !exists {
+			character = &Character{Name: name, Slot: cs.freeSlot()}
+			cs.characters[strings.ToLower(name)] = character
+		} else if character.Online {
+			// Two clients for the same character, keep the topmost one.
+			continue
+		}
+		character.Version = version
+		character.Title = window.Title
+		character.Handle = window.Handle
+		character.Online = true
+	}
+
+	return nil
+}
+
+// freeSlot returns the lowest slot number not assigned to a character.
+func (cs *CharacterService) freeSlot() int {
+	taken := make(map[int]bool, len(cs.characters))
+	for _, character := range cs.characters {
+		taken[character.Slot] = true
+	}
+	slot := 1
+	for taken[slot] {
+		slot++
+	}
+	return slot
+}
+
+// GetCharacters returns the registered characters ordered by slot.
+func (cs *CharacterService) GetCharacters() []Character {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	characters := make([]Character, 0, len(cs.characters))
+	for _, character := range cs.characters {
+		characters = append(characters, *character)
+	}
+	sort.Slice(characters, func(i, j int) bool {
+		return characters[i].Slot < characters[j].Slot
+	})
+	return characters
+}
+
+// GetCharacter returns the character designated by ref, either a character
+// name ("Foo") or a slot number ("3" or "slot 3").
+func (cs *CharacterService) GetCharacter(ref string) (Character, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	character := cs.lookup(ref)
+	if character == nil {
+		return Character{}, fmt.Errorf("%w: %s", ErrCharacterNotFound, ref)
+	}
+	return *character, nil
+}
+
+func (cs *CharacterService) lookup(ref string) *Character {
+	ref = strings.TrimSpace(ref)
+	if character, ok := cs.characters[strings.ToLower(ref)]; ok {
+		return character
+	}
+
+	slotRef := strings.TrimSpace(strings.TrimPrefix(strings.ToLower(ref), "slot"))
+	if slot, err := strconv.Atoi(slotRef); err == nil {
+		for _, character := range cs.characters {
+			if character.Slot == slot {
+				return character
+			}
+		}
+	}
+	return nil
+}
+
+// AddCharacter registers a character ahead of its client, in the given slot
+// or the first free one when slot is 0.
+func (cs *CharacterService) AddCharacter(name string, slot int) (Character, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Character{}, fmt.Errorf("character name is empty")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, exists := cs.characters[strings.ToLower(name)]; exists {
+		return Character{}, fmt.Errorf("%w: %s", ErrCharacterExists, name)
+	}
+	if slot == 0 {
+		slot = cs.freeSlot()
+	} else if err := cs.checkSlot(slot, nil); err != nil {
+		return Character{}, err
+	}
+
+	character := &Character{Name: name, Slot: slot}
+	cs.characters[strings.ToLower(name)] = character
+	return *character, nil
+}
+
+// SetTeam registers the team members in their slots. Members without one
+// keep their current slot, or get the first free slot. Other characters
+// holding one of the given slots are moved to a free slot.
+func (cs *CharacterService) SetTeam(team []Character) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	members := make(map[string]bool, len(team))
+	reserved := make(map[int]bool, len(team))
+	for _, member := range team {
+		members[strings.ToLower(member.Name)] = true
+		if member.Slot > 0 {
+			reserved[member.Slot] = true
+		}
+	}
+	for _, member := range team {
+		key := strings.ToLower(member.Name)
+		character, exists := cs.characters[key]
+		if !exists {
+			character = &Character{Name: member.Name}
+			cs.characters[key] = character
+		}
+		switch {
+		case member.Slot > 0:
+			character.Slot = member.Slot
+		case reserved[character.Slot]:
+			character.Slot = 0
+		}
+	}
+
+	var displaced []*Character
+	for key, character := range cs.characters {
+		if !members[key] && reserved[character.Slot] {
+			character.Slot = 0
+			displaced = append(displaced, character)
+		}
+	}
+	sort.Slice(displaced, func(i, j int) bool {
+		return displaced[i].Name < displaced[j].Name
+	})
+
+	for _, member := range team {
+		if character := cs.characters[strings.ToLower(member.Name)]; character.Slot == 0 {
+			character.Slot = cs.freeSlot()
+		}
+	}
+	for _, character := range displaced {
+		character.Slot = cs.freeSlot()
+	}
+}
+
+// SetSlot moves the character designated by ref to another slot.
+func (cs *CharacterService) SetSlot(ref string, slot int) (Character, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	character := cs.lookup(ref)
+	if character == nil {
+		return Character{}, fmt.Errorf("%w: %s", ErrCharacterNotFound, ref)
+	}
+	if err := cs.checkSlot(slot, character); err != nil {
+		return Character{}, err
+	}
+
+	character.Slot = slot
+	return *character, nil
+}
+
+func (cs *CharacterService) checkSlot(slot int, self *Character) error {
+	if slot < 1 {
+		return fmt.Errorf("invalid slot: %d", slot)
+	}
+	for _, character := range cs.characters {
+		if character != self && character.Slot == slot {
+			return fmt.Errorf("%w: slot %d is assigned to %s", ErrSlotTaken, slot, character.Name)
+		}
+	}
+	return nil
+}
+
+// RemoveCharacter removes the character designated by ref from the registry.
+func (cs *CharacterService) RemoveCharacter(ref string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	character := cs.lookup(ref)
+	if character == nil {
+		return fmt.Errorf("%w: %s", ErrCharacterNotFound, ref)
+	}
+	delete(cs.characters, strings.ToLower(character.Name))
+	return nil
+}
+
+// ResolveWindow returns the client window of the character designated by ref,
+// refreshing the registry so that restarted clients are found.
+func (cs *CharacterService) ResolveWindow(ref string) (WindowHandle, error) {
+	if err := cs.Refresh(); err != nil {
+		return 0, err
+	}
+
+	character, err := cs.GetCharacter(ref)
+	if err != nil {
+		return 0, err
+	}
+	if !character.Online {
+		return 0, fmt.Errorf("%w: %s", ErrCharacterOffline, character.Name)
+	}
+	return character.Handle, nil
+}
+
+// FocusCharacter brings the client of the character designated by ref to the foreground.
+func (cs *CharacterService) FocusCharacter(ref string) error {
+	hwnd, err := cs.ResolveWindow(ref)
+	if err != nil {
+		return err
+	}
+	return cs.windowService.FocusWindow(hwnd)
+}
+
+// FocusedCharacter returns the character whose client is in the foreground.
+func (cs *CharacterService) FocusedCharacter() (Character, error) {
+	if err := cs.Refresh(); err != nil {
+		return Character{}, err
+	}
+
+	foreground := cs.windowService.GetForegroundWindow()
+	for _, character := range cs.GetCharacters() {
+		if character.Online && character.Handle == foreground {
+			return character, nil
+		}
+	}
+	return Character{}, ErrNoCharacterFocus
+}
+
+// FocusNext brings the client of the next character in slot order to the
+// foreground, skipping closed clients and wrapping around after the last one.
+func (cs *CharacterService) FocusNext() (Character, error) {
+	return cs.cycleFocus(1)
+}
+
+// FocusPrevious brings the client of the previous character in slot order
+// to the foreground, skipping closed clients and wrapping around.
+func (cs *CharacterService) FocusPrevious() (Character, error) {
+	return cs.cycleFocus(-1)
+}
+
+func (cs *CharacterService) cycleFocus(step int) (Character, error) {
+	if err := cs.Refresh(); err != nil {
+		return Character{}, err
+	}
+
+	characters := cs.GetCharacters()
+	count := len(characters)
+	if count == 0 {
+		return Character{}, fmt.Errorf("%w: the team is empty", ErrCharacterNotFound)
+	}
+
+	// Start before the first character, or after the last one when going
+	// backwards, if no team member has focus.
+	current := -1
+	if step < 0 {
+		current = count
+	}
+	foreground := cs.windowService.GetForegroundWindow()
+	for i, character := range characters {
+		if character.Online && character.Handle == foreground {
+			current = i
+			break
+		}
+	}
+
+	for n := 1; n <= count; n++ {
+		character := characters[((current+step*n)%count+count)%count]
+		if !character.Online {
+			continue
+		}
+		if err := cs.windowService.FocusWindow(character.Handle); err != nil {
+			return Character{}, err
+		}
+		return character, nil
+	}
+	return Character{}, fmt.Errorf("%w: no team member is online", ErrCharacterOffline)
+}
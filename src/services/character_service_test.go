@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+// newFakeTeam opens a game client per name on a fake backend, the first name
+// ending up topmost, and registers the characters in that order.
+func newFakeTeam(t *testing.T, names ...string) (*FakeWindowBackend, *CharacterService, []WindowHandle) {
+	t.Helper()
+	fake := NewFakeWindowBackend()
+	handles := make([]WindowHandle, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		handles[i] = fake.AddWindow(names[i] + " - Dofus 2.70.5")
+	}
+
+	characters := NewCharacterService(NewWindowService(fake))
+	team := make([]Character, len(names))
+	for i, name := range names {
+		team[i] = Character{Name: name, Slot: i + 1}
+	}
+	characters.SetTeam(team)
+	if err := characters.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	return fake, characters, handles
+}
+
+func TestParseGameTitle(t *testing.T) {
+	tests := []struct {
+		title, name, version string
+		ok                   bool
+	}{
+		{"Iopette - Dofus 2.70.5", "Iopette", "2.70.5", true},
+		{"Iopette - Iop - 3.0.12.8 - Release", "Iopette", "3.0.12.8", true},
+		{"Dofus - 2.70.5", "", "", false},
+		{"Iopette - Notepad", "", "", false},
+		{"Dofus", "", "", false},
+	}
+	for _, tt := range tests {
+		name, version, ok := ParseGameTitle(tt.title)
+		if name != tt.name || version != tt.version || ok != tt.ok {
+			t.Errorf("ParseGameTitle(%q) = %q, %q, %v, want %q, %q, %v", tt.title, name, version, ok, tt.name, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestFocusNextCyclesInSlotOrder(t *testing.T) {
+	fake, characters, handles := newFakeTeam(t, "Alpha", "Beta", "Gamma")
+
+	want := []WindowHandle{handles[0], handles[1], handles[2], handles[0]}
+	for i, hwnd := range want {
+		character, err := characters.FocusNext()
+		if err != nil {
+			t.Fatalf("FocusNext #%d: %v", i, err)
+		}
+		if character.Handle != hwnd || fake.GetForegroundWindow() != hwnd {
+			t.Fatalf("FocusNext #%d focused %s (hwnd=%d), want hwnd=%d", i, character.Name, fake.GetForegroundWindow(), hwnd)
+		}
+	}
+}
+
+func TestFocusPreviousWrapsAround(t *testing.T) {
+	fake, characters, handles := newFakeTeam(t, "Alpha", "Beta", "Gamma")
+	if err := fake.SetForegroundWindow(handles[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	character, err := characters.FocusPrevious()
+	if err != nil {
+		t.Fatalf("FocusPrevious: %v", err)
+	}
+	if character.Name != "Gamma" || fake.GetForegroundWindow() != handles[2] {
+		t.Fatalf("FocusPrevious focused %s, want Gamma", character.Name)
+	}
+}
+
+func TestFocusNextSkipsClosedClients(t *testing.T) {
+	fake, characters, handles := newFakeTeam(t, "Alpha", "Beta", "Gamma")
+	if err := fake.SetForegroundWindow(handles[0]); err != nil {
+		t.Fatal(err)
+	}
+	fake.CloseWindow(handles[1])
+
+	character, err := characters.FocusNext()
+	if err != nil {
+		t.Fatalf("FocusNext: %v", err)
+	}
+	if character.Name != "Gamma" {
+		t.Fatalf("FocusNext focused %s, want Gamma", character.Name)
+	}
+
+	fake.CloseWindow(handles[0])
+	fake.CloseWindow(handles[2])
+	if _, err := characters.FocusNext(); !errors.Is(err, ErrCharacterOffline) {
+		t.Fatalf("FocusNext without clients: got %v, want ErrCharacterOffline", err)
+	}
+}
+
+func TestSetTeamKeepsTheSlotsOfUnslottedMembers(t *testing.T) {
+	characters := NewCharacterService(NewWindowService(NewFakeWindowBackend()))
+	characters.SetTeam([]Character{{Name: "Alpha"}, {Name: "Beta"}, {Name: "Gamma"}})
+	if _, err := characters.SetSlot("Alpha", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloading the same team must not renumber it, and a slot given to
+	// another member still takes precedence.
+	characters.SetTeam([]Character{{Name: "Alpha"}, {Name: "Beta"}, {Name: "Gamma", Slot: 5}, {Name: "Delta"}})
+	want := map[string]int{"Alpha": 1, "Beta": 2, "Delta": 3, "Gamma": 5}
+	for _, character := range characters.GetCharacters() {
+		if slot, ok := want[character.Name]; !ok || character.Slot != slot {
+			t.Errorf("%s is in slot %d, want %d", character.Name, character.Slot, slot)
+		}
+	}
+}
@@ -1,47 +1,67 @@
 package services
 
 import (
+	"context"
 	"log"
 	"strings"
-	"syscall"
 	"time"
 )
 
 // DofusCheckService monitors the Dofus window and manages services accordingly.
 type DofusCheckService struct {
 	windowService      *WindowService
+	events             *EventBus
 	isWheelClickActive bool
 	isShortcutActive   bool
-	stopChan           chan bool
+	runner             *serviceRunner
 }
 
 // NewDofusCheckService creates a new instance of the DofusCheckService.
-func NewDofusCheckService(windowService *WindowService) *DofusCheckService {
+func NewDofusCheckService(windowService *WindowService, events *EventBus) *DofusCheckService {
 	return &DofusCheckService{
 		windowService: windowService,
-		stopChan:      make(chan bool),
+		events:        events,
+		runner:        newServiceRunner("dofusCheck", events),
 	}
 }
 
-// StartMonitoring begins monitoring the Dofus window's state.
-func (dcs *DofusCheckService) StartMonitoring() {
-	go func() {
-		for {
-			select {
-			case <-dcs.stopChan:
-				log.Println("DofusCheckService stopped.")
-				return
-			default:
-				dcs.checkDofusWindow()
-				time.Sleep(1 * time.Second) // Poll every second
-			}
-		}
-	}()
+// Name identifies the service.
+func (dcs *DofusCheckService) Name() string {
+	return "dofusCheck"
+}
+
+// Start begins monitoring the Dofus window's state, until Stop is called or
+// ctx is cancelled.
+func (dcs *DofusCheckService) Start(ctx context.Context) error {
+	return dcs.runner.start(ctx, nil, dcs.monitor)
+}
+
+// Stop stops the window monitoring.
+func (dcs *DofusCheckService) Stop() error {
+	return dcs.runner.stop()
+}
+
+// Status reports the state of the service.
+func (dcs *DofusCheckService) Status() ServiceStatus {
+	return dcs.runner.status()
 }
 
-// StopMonitoring stops the window monitoring.
-func (dcs *DofusCheckService) StopMonitoring() {
-	dcs.stopChan <- true
+func (dcs *DofusCheckService) lifecycle() *serviceRunner {
+	return dcs.runner
+}
+
+func (dcs *DofusCheckService) monitor(ctx context.Context) error {
+	ticker := time.NewTicker(1 * time.Second) // Poll every second
+	defer ticker.Stop()
+
+	for {
+		dcs.checkDofusWindow()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
 }
 
 // checkDofusWindow checks if Dofus is in the foreground and updates services accordingly.
@@ -101,10 +121,3 @@ func (dcs *DofusCheckService) deactivateWheelClick() {
 	dcs.isWheelClickActive = false
 	// Call the existing wheel click service to stop monitoring
 }
-func (ws *WindowService) GetForegroundWindow() syscall.Handle {
-	return GetForegroundWindow()
-}
-
-func (ws *WindowService) GetWindowText(hwnd syscall.Handle) string {
-	return GetWindowText(hwnd)
-}
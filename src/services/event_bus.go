@@ -0,0 +1,231 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the type of an event published on the EventBus. Types are
+// dotted, so that a filter on "window" selects every "window.*" event.
+type EventType string
+
+const (
+	EventWindowActivated EventType = "window.activated"
+	EventWindowFlash     EventType = "window.flash"
+	EventWindowCreated   EventType = "window.created"
+	EventWindowDestroyed EventType = "window.destroyed"
+	EventTurnStart       EventType = "turn.start"
+	EventTurnBound       EventType = "turn.bound"
+	EventTurnLost        EventType = "turn.lost"
+	EventHotkey          EventType = "hotkey"
+	EventClickBroadcast  EventType = "click.broadcast"
+	EventClickReport     EventType = "click.report"
+	EventKeyBroadcast    EventType = "key.broadcast"
+	EventServiceState    EventType = "service.state"
+	EventMacroRecording  EventType = "macro.recording"
+	EventMacroRecorded   EventType = "macro.recorded"
+	EventMacroPlaying    EventType = "macro.playing"
+	EventMacroPlayed     EventType = "macro.played"
+	EventLayoutApplied   EventType = "layout.applied"
+)
+
+// Event is something that happened in Multy, streamed to clients as JSON.
+// Data depends on the type: the TurnWindow for turn events, the Shortcut for
+// hotkey events, ClickData for click broadcasts, the BroadcastReport once
+// every window was clicked, KeyData for key broadcasts, ServiceStateData for
+// service state changes, MacroInfo for macro recordings, the Playback for
+// macro playbacks and the LayoutResult for layouts.
+type Event struct {
+	Type   EventType    `json:"type"`
+	Time   time.Time    `json:"time"`
+	Window WindowHandle `json:"window,omitempty" swaggertype:"integer"`
+	Title  string       `json:"title,omitempty"`
+	Data   interface{}  `json:"data,omitempty"`
+}
+
+// ClickData is the data of click broadcast events: the screen coordinates
+// clicked in the target window and, when the click was over one of the
+// windows, the source window and the position relative to its client area.
+type ClickData struct {
+	X        int            `json:"x"`
+	Y        int            `json:"y"`
+	Source   WindowHandle   `json:"source,omitempty" swaggertype:"integer"`
+	Relative *RelativePoint `json:"relative,omitempty"`
+}
+
+// ServiceStateData is the data of service state events.
+type ServiceStateData struct {
+	Service string `json:"service"`
+	State   string `json:"state"`
+	Error   string `json:"error,omitempty"`
+	// Attempt is the restart attempt of a restarting service.
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// EventFilter selects the events delivered to a subscription. Zero fields
+// match any event.
+type EventFilter struct {
+	// Types lists event types or type prefixes such as "window".
+	Types []EventType
+	// Window restricts the events to a window.
+	Window WindowHandle
+}
+
+// Match reports whether the event passes the filter.
+func (f EventFilter) Match(event Event) bool {
+	if f.Window != 0 && event.Window != f.Window {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if event.Type == t || strings.HasPrefix(string(event.Type), string(t)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// eventBufferSize is the number of events a subscriber may lag behind before
+// events are dropped for it.
+const eventBufferSize = 64
+
+// EventSubscription delivers the events matching its filter on C, until it is
+// unsubscribed or the bus is closed.
+type EventSubscription struct {
+	C <-chan Event
+
+	events chan Event
+	filter EventFilter
+	bus    *EventBus
+}
+
+// Unsubscribe stops the delivery of events and closes C. It may be called
+// more than once.
+func (s *EventSubscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+}
+
+// EventBus fans out the events published by the services to subscribers.
+// A nil *EventBus discards the events published.
+type EventBus struct {
+	mu            sync.Mutex
+	closed        bool
+	subscriptions map[*EventSubscription]struct{}
+}
+
+// NewEventBus creates a new instance of the EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscriptions: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Publish delivers the event to the matching subscribers, without waiting
+// for slow ones.
+func (b *EventBus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscription := range b.subscriptions {
+		if !subscription.filter.Match(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			log.Printf("Event %s dropped, subscriber too slow", event.Type)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events matching the filter.
+func (b *EventBus) Subscribe(filter EventFilter) *EventSubscription {
+	events := make(chan Event, eventBufferSize)
+	subscription := &EventSubscription{C: events, events: events, filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(events)
+		return subscription
+	}
+	b.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (b *EventBus) unsubscribe(subscription *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscriptions[subscription]; ok {
+		delete(b.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// Close closes every subscription, ending the event streams.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for subscription := range b.subscriptions {
+		close(subscription.events)
+	}
+	b.subscriptions = nil
+}
+
+// publishServiceState publishes a service state change.
+func (b *EventBus) publishServiceState(service, state string, err error) {
+	data := ServiceStateData{Service: service, State: state}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	b.Publish(Event{Type: EventServiceState, Data: data})
+}
+
+// shellEventTypes maps the shell events to the event types published.
+var shellEventTypes = map[ShellEventCode]EventType{
+	ShellWindowCreated:   EventWindowCreated,
+	ShellWindowDestroyed: EventWindowDestroyed,
+	ShellWindowActivated: EventWindowActivated,
+	ShellWindowFlash:     EventWindowFlash,
+}
+
+// WatchWindows publishes the window events of the backend until stop is closed.
+func (b *EventBus) WatchWindows(ws *WindowService, stop <-chan struct{}) error {
+	shellEvents, err := ws.Backend().WatchShellEvents(stop)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for shellEvent := range shellEvents {
+			eventType, ok := shellEventTypes[shellEvent.Code]
+			if !ok {
+				continue
+			}
+			event := Event{Type: eventType, Window: shellEvent.Window}
+			if shellEvent.Code != ShellWindowDestroyed {
+				event.Title = ws.GetWindowText(shellEvent.Window)
+			}
+			b.Publish(event)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventFilter(t *testing.T) {
+	flash := Event{Type: EventWindowFlash, Window: 42}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"zero filter", EventFilter{}, true},
+		{"type", EventFilter{Types: []EventType{EventWindowFlash}}, true},
+		{"prefix", EventFilter{Types: []EventType{"window"}}, true},
+		{"partial prefix", EventFilter{Types: []EventType{"win"}}, false},
+		{"other type", EventFilter{Types: []EventType{EventTurnStart}}, false},
+		{"types", EventFilter{Types: []EventType{EventTurnStart, "window"}}, true},
+		{"window", EventFilter{Window: 42}, true},
+		{"other window", EventFilter{Window: 7}, false},
+		{"type and other window", EventFilter{Types: []EventType{"window"}, Window: 7}, false},
+	}
+	for _, test := range tests {
+		if got := test.filter.Match(flash); got != test.want {
+			t.Errorf("%s: Match = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEventBusFansOutEvents(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	windows := bus.Subscribe(EventFilter{Types: []EventType{"window"}})
+	turns := bus.Subscribe(EventFilter{Types: []EventType{"turn"}})
+	all := bus.Subscribe(EventFilter{})
+
+	bus.Publish(Event{Type: EventWindowActivated, Window: 1})
+	bus.Publish(Event{Type: EventTurnStart, Window: 1})
+
+	if event := nextEvent(t, windows); event.Type != EventWindowActivated || event.Time.IsZero() {
+		t.Errorf("window subscription received %+v, want a timestamped window.activated", event)
+	}
+	if event := nextEvent(t, turns); event.Type != EventTurnStart {
+		t.Errorf("turn subscription received %+v, want turn.start", event)
+	}
+	for i := 0; i < 2; i++ {
+		nextEvent(t, all)
+	}
+	for name, subscription := range map[string]*EventSubscription{"window": windows, "turn": turns, "unfiltered": all} {
+		if n := len(subscription.C); n != 0 {
+			t.Errorf("%s subscription has %d more events", name, n)
+		}
+	}
+}
+
+func TestEventBusKeepsTheTimeOfEvents(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+	subscription := bus.Subscribe(EventFilter{})
+
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	bus.Publish(Event{Type: EventHotkey, Time: when})
+	if event := nextEvent(t, subscription); !event.Time.Equal(when) {
+		t.Fatalf("event time = %v, want %v", event.Time, when)
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	subscription := bus.Subscribe(EventFilter{})
+	other := bus.Subscribe(EventFilter{})
+	subscription.Unsubscribe()
+	subscription.Unsubscribe()
+
+	if _, ok := <-subscription.C; ok {
+		t.Fatal("Unsubscribe did not close the subscription")
+	}
+	bus.Publish(Event{Type: EventHotkey})
+	nextEvent(t, other)
+}
+
+func TestEventBusDropsEventsForSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	slow := bus.Subscribe(EventFilter{})
+	fast := bus.Subscribe(EventFilter{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*eventBufferSize; i++ {
+			bus.Publish(Event{Type: EventHotkey, Data: i})
+			<-fast.C
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	if n := len(slow.C); n != eventBufferSize {
+		t.Fatalf("slow subscription holds %d events, want %d", n, eventBufferSize)
+	}
+	// The oldest events are kept.
+	if event := nextEvent(t, slow); event.Data != 0 {
+		t.Fatalf("slow subscription starts with the event %v, want 0", event.Data)
+	}
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := NewEventBus()
+	subscription := bus.Subscribe(EventFilter{})
+	bus.Close()
+	bus.Close()
+
+	if _, ok := <-subscription.C; ok {
+		t.Fatal("Close did not close the subscription")
+	}
+	subscription.Unsubscribe()
+	bus.Publish(Event{Type: EventHotkey})
+
+	late := bus.Subscribe(EventFilter{})
+	if _, ok := <-late.C; ok {
+		t.Fatal("subscription after Close is open")
+	}
+}
+
+func TestNilEventBusDiscardsEvents(t *testing.T) {
+	var bus *EventBus
+	bus.Publish(Event{Type: EventHotkey})
+	bus.publishServiceState("shortcuts", string(ServiceFailed), errors.New("crashed"))
+}
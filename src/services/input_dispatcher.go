@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	hook "github.com/robotn/gohook"
+)
+
+// ErrInputClosed is returned by listeners whose subscription was closed
+// with the dispatcher.
+var ErrInputClosed = errors.New("input hook closed")
+
+// InputEventKind is the kind of a global input event. Kinds are bit flags, so
+// that a filter can select several of them.
+type InputEventKind uint16
+
+const (
+	InputKeyDown InputEventKind = 1 << iota
+	InputKeyUp
+	// InputKeyTyped follows InputKeyDown for keys producing a character.
+	InputKeyTyped
+	InputMouseDown
+	InputMouseUp
+	// InputMouseClick follows InputMouseUp when the mouse did not move.
+	InputMouseClick
+	InputMouseMove
+	InputMouseDrag
+	InputMouseWheel
+
+	InputKeyEvents   = InputKeyDown | InputKeyUp | InputKeyTyped
+	InputMouseEvents = InputMouseDown | InputMouseUp | InputMouseClick | InputMouseMove | InputMouseDrag | InputMouseWheel
+)
+
+var inputEventKindNames = map[InputEventKind]string{
+	InputKeyDown:    "keyDown",
+	InputKeyUp:      "keyUp",
+	InputKeyTyped:   "keyTyped",
+	InputMouseDown:  "mouseDown",
+	InputMouseUp:    "mouseUp",
+	InputMouseClick: "mouseClick",
+	InputMouseMove:  "mouseMove",
+	InputMouseDrag:  "mouseDrag",
+	InputMouseWheel: "mouseWheel",
+}
+
+func (k InputEventKind) String() string {
+	if name, ok := inputEventKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MouseButton identifies a mouse button in input events.
+type MouseButton uint16
+
+const (
+	MouseLeft MouseButton = iota + 1
+	MouseRight
+	MouseMiddle
+	MouseX1
+	MouseX2
+)
+
+// InputEvent is a keyboard or mouse event captured by the global input hook.
+// Coordinates are screen coordinates.
+type InputEvent struct {
+	Kind      InputEventKind
+	When      time.Time
+	Keycode   uint16
+	Rawcode   uint16
+	Keychar   rune
+	Mask      uint16
+	Modifiers KeyModifiers
+	Button    MouseButton
+	Clicks    int
+	X, Y      int
+	Rotation  int
+}
+
+// InputFilter selects the events delivered to a subscription. Zero fields
+// match any event.
+type InputFilter struct {
+	// Kinds is a set of event kinds.
+	Kinds InputEventKind
+	// Button restricts mouse events to a button.
+	Button MouseButton
+	// Combo restricts key events to a key combination.
+	Combo *KeyCombo
+}
+
+// Match reports whether the event passes the filter.
+func (f InputFilter) Match(ev InputEvent) bool {
+	if f.Kinds != 0 && f.Kinds&ev.Kind == 0 {
+		return false
+	}
+	if f.Button != 0 && ev.Kind&(InputMouseDown|InputMouseUp|InputMouseClick) != 0 && ev.Button != f.Button {
+		return false
+	}
+	if f.Combo != nil && ev.Kind&InputKeyEvents != 0 && !f.Combo.Matches(ev.Keycode, ev.Mask) {
+		return false
+	}
+	return true
+}
+
+// inputBufferSize is the number of events a subscriber may lag behind before
+// events are dropped for it.
+const inputBufferSize = 64
+
+// InputSubscription delivers the events matching its filter on C, until it is
+// unsubscribed or the dispatcher is closed.
+type InputSubscription struct {
+	C <-chan InputEvent
+
+	events     chan InputEvent
+	filter     InputFilter
+	dispatcher *InputDispatcher
+}
+
+// Unsubscribe stops the delivery of events and closes C. It may be called
+// more than once.
+func (s *InputSubscription) Unsubscribe() {
+	s.dispatcher.unsubscribe(s)
+}
+
+// InputDispatcher owns the process-wide input hook and fans its events out
+// to subscribers. The hook is installed on the first subscription and kept
+// until the dispatcher is closed, since gohook does not survive being
+// stopped while events are in flight.
+type InputDispatcher struct {
+	mu            sync.Mutex
+	started       bool
+	closed        bool
+	subscriptions map[*InputSubscription]struct{}
+}
+
+// NewInputDispatcher creates a new instance of the InputDispatcher.
+func NewInputDispatcher() *InputDispatcher {
+	return &InputDispatcher{
+		subscriptions: make(map[*InputSubscription]struct{}),
+	}
+}
+
+// Subscribe returns a subscription to the events matching the filter.
+func (d *InputDispatcher) Subscribe(filter InputFilter) *InputSubscription {
+	events := make(chan InputEvent, inputBufferSize)
+	subscription := &InputSubscription{C: events, events: events, filter: filter, dispatcher: d}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		close(events)
+		return subscription
+	}
+	d.subscriptions[subscription] = struct{}{}
+
+	if !d.started {
+		d.started = true
+		evChan := hook.Start()
+		go d.run(evChan)
+		log.Println("Input hook started")
+	}
+	return subscription
+}
+
+func (d *InputDispatcher) unsubscribe(subscription *InputSubscription) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.subscriptions[subscription]; ok {
+		delete(d.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// Close removes the input hook and closes every subscription.
+func (d *InputDispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for subscription := range d.subscriptions {
+		close(subscription.events)
+	}
+	d.subscriptions = nil
+
+	if d.started {
+		hook.End()
+		log.Println("Input hook stopped")
+	}
+}
+
+func (d *InputDispatcher) run(evChan <-chan hook.Event) {
+	for ev := range evChan {
+		if event, ok := inputEventFromHook(ev); ok {
+			d.dispatch(event)
+		}
+	}
+}
+
+func (d *InputDispatcher) dispatch(event InputEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for subscription := range d.subscriptions {
+		if !subscription.filter.Match(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			log.Printf("Input event %s dropped, subscriber too slow", event.Kind)
+		}
+	}
+}
+
+// inputEventFromHook converts a gohook event. The gohook kinds follow the
+// libuiohook events, where KeyHold is the key press and MouseHold and
+// MouseDown are the button press and release.
+func inputEventFromHook(ev hook.Event) (InputEvent, bool) {
+	var kind InputEventKind
+	switch ev.Kind {
+	case hook.KeyHold:
+		kind = InputKeyDown
+	case hook.KeyUp:
+		kind = InputKeyUp
+	case hook.KeyDown:
+		kind = InputKeyTyped
+	case hook.MouseHold:
+		kind = InputMouseDown
+	case hook.MouseDown:
+		kind = InputMouseUp
+	case hook.MouseUp:
+		kind = InputMouseClick
+	case hook.MouseMove:
+		kind = InputMouseMove
+	case hook.MouseDrag:
+		kind = InputMouseDrag
+	case hook.MouseWheel:
+		kind = InputMouseWheel
+	default:
+		return InputEvent{}, false
+	}
+
+	return InputEvent{
+		Kind:      kind,
+		When:      ev.When,
+		Keycode:   ev.Keycode,
+		Rawcode:   ev.Rawcode,
+		Keychar:   ev.Keychar,
+		Mask:      ev.Mask,
+		Modifiers: ModifiersFromMask(ev.Mask),
+		Button:    MouseButton(ev.Button),
+		Clicks:    int(ev.Clicks),
+		X:         int(ev.X),
+		Y:         int(ev.Y),
+		Rotation:  int(ev.Rotation),
+	}, true
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	hook "github.com/robotn/gohook"
+)
+
+// newInputDispatcher returns a dispatcher whose events are dispatched by the
+// test, the hook is not installed.
+func newInputDispatcher() *InputDispatcher {
+	d := NewInputDispatcher()
+	d.started = true
+	return d
+}
+
+// closeInputDispatcher closes a dispatcher created by newInputDispatcher,
+// without removing the hook it never installed.
+func closeInputDispatcher(d *InputDispatcher) {
+	d.mu.Lock()
+	d.started = false
+	d.mu.Unlock()
+	d.Close()
+}
+
+func nextInput(t *testing.T, subscription *InputSubscription) InputEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-subscription.C:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no input event")
+	}
+	return InputEvent{}
+}
+
+func TestInputFilter(t *testing.T) {
+	f2, err := ParseKeyCombo("F2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyF2 := InputEvent{Kind: InputKeyDown, Keycode: f2.Keycode}
+	rightDown := InputEvent{Kind: InputMouseDown, Button: MouseRight}
+
+	tests := []struct {
+		name   string
+		filter InputFilter
+		event  InputEvent
+		want   bool
+	}{
+		{"zero filter", InputFilter{}, keyF2, true},
+		{"kind", InputFilter{Kinds: InputKeyDown}, keyF2, true},
+		{"other kind", InputFilter{Kinds: InputKeyUp}, keyF2, false},
+		{"kinds", InputFilter{Kinds: InputKeyEvents}, keyF2, true},
+		{"button", InputFilter{Button: MouseRight}, rightDown, true},
+		{"other button", InputFilter{Button: MouseLeft}, rightDown, false},
+		{"button on a key", InputFilter{Button: MouseLeft}, keyF2, true},
+		{"button on a move", InputFilter{Button: MouseLeft}, InputEvent{Kind: InputMouseMove}, true},
+		{"combo", InputFilter{Combo: &f2}, keyF2, true},
+		{"combo with a modifier", InputFilter{Combo: &f2}, InputEvent{Kind: InputKeyDown, Keycode: f2.Keycode, Mask: 0x0002}, false},
+		{"combo on a click", InputFilter{Combo: &f2}, rightDown, true},
+	}
+	for _, test := range tests {
+		if got := test.filter.Match(test.event); got != test.want {
+			t.Errorf("%s: Match = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestInputDispatcherFansOutEvents(t *testing.T) {
+	d := newInputDispatcher()
+	defer closeInputDispatcher(d)
+
+	keys := d.Subscribe(InputFilter{Kinds: InputKeyEvents})
+	clicks := d.Subscribe(InputFilter{Kinds: InputMouseDown, Button: MouseLeft})
+	all := d.Subscribe(InputFilter{})
+
+	d.dispatch(InputEvent{Kind: InputKeyDown, Keycode: 0x003B})
+	d.dispatch(InputEvent{Kind: InputMouseDown, Button: MouseRight})
+	d.dispatch(InputEvent{Kind: InputMouseDown, Button: MouseLeft})
+
+	if ev := nextInput(t, keys); ev.Kind != InputKeyDown || ev.Keycode != 0x003B {
+		t.Errorf("key subscription received %+v, want the F1 key", ev)
+	}
+	if ev := nextInput(t, clicks); ev.Button != MouseLeft {
+		t.Errorf("click subscription received %+v, want the left button", ev)
+	}
+	for i := 0; i < 3; i++ {
+		nextInput(t, all)
+	}
+	for name, subscription := range map[string]*InputSubscription{"key": keys, "click": clicks, "unfiltered": all} {
+		if n := len(subscription.C); n != 0 {
+			t.Errorf("%s subscription has %d more events", name, n)
+		}
+	}
+}
+
+func TestInputDispatcherUnsubscribe(t *testing.T) {
+	d := newInputDispatcher()
+	defer closeInputDispatcher(d)
+
+	subscription := d.Subscribe(InputFilter{})
+	other := d.Subscribe(InputFilter{})
+	subscription.Unsubscribe()
+	subscription.Unsubscribe()
+
+	if _, ok := <-subscription.C; ok {
+		t.Fatal("Unsubscribe did not close the subscription")
+	}
+	d.dispatch(InputEvent{Kind: InputKeyDown})
+	nextInput(t, other)
+}
+
+func TestInputDispatcherDropsEventsForSlowSubscribers(t *testing.T) {
+	d := newInputDispatcher()
+	defer closeInputDispatcher(d)
+
+	slow := d.Subscribe(InputFilter{})
+	fast := d.Subscribe(InputFilter{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*inputBufferSize; i++ {
+			d.dispatch(InputEvent{Kind: InputKeyDown, Keycode: uint16(i)})
+			<-fast.C
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("dispatch blocked on a slow subscriber")
+	}
+
+	if n := len(slow.C); n != inputBufferSize {
+		t.Fatalf("slow subscription holds %d events, want %d", n, inputBufferSize)
+	}
+	// The oldest events are kept.
+	if ev := nextInput(t, slow); ev.Keycode != 0 {
+		t.Fatalf("slow subscription starts with the keycode %d, want 0", ev.Keycode)
+	}
+}
+
+func TestInputDispatcherClose(t *testing.T) {
+	d := newInputDispatcher()
+	subscription := d.Subscribe(InputFilter{})
+	closeInputDispatcher(d)
+	d.Close()
+
+	if _, ok := <-subscription.C; ok {
+		t.Fatal("Close did not close the subscription")
+	}
+	subscription.Unsubscribe()
+	d.dispatch(InputEvent{Kind: InputKeyDown})
+
+	late := d.Subscribe(InputFilter{})
+	if _, ok := <-late.C; ok {
+		t.Fatal("subscription after Close is open")
+	}
+}
+
+func TestInputEventFromHook(t *testing.T) {
+	tests := []struct {
+		kind uint8
+		want InputEventKind
+	}{
+		{hook.KeyHold, InputKeyDown},
+		{hook.KeyUp, InputKeyUp},
+		{hook.KeyDown, InputKeyTyped},
+		{hook.MouseHold, InputMouseDown},
+		{hook.MouseDown, InputMouseUp},
+		{hook.MouseUp, InputMouseClick},
+		{hook.MouseMove, InputMouseMove},
+		{hook.MouseDrag, InputMouseDrag},
+		{hook.MouseWheel, InputMouseWheel},
+	}
+	for _, test := range tests {
+		ev, ok := inputEventFromHook(hook.Event{Kind: test.kind, Mask: 0x0020, Button: 2, X: 10, Y: 20})
+		if !ok || ev.Kind != test.want {
+			t.Errorf("hook kind %d converted to %s, want %s", test.kind, ev.Kind, test.want)
+			continue
+		}
+		if ev.Modifiers != ModCtrl || ev.Button != MouseRight || ev.X != 10 || ev.Y != 20 {
+			t.Errorf("hook kind %d converted to %+v", test.kind, ev)
+		}
+	}
+	if _, ok := inputEventFromHook(hook.Event{Kind: hook.HookEnabled}); ok {
+		t.Error("HookEnabled converted to an input event")
+	}
+}
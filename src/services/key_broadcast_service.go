@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// KeyBroadcastSettings configures the keys broadcast to the team.
+type KeyBroadcastSettings struct {
+	// Keys is the allow-list of keys broadcast, such as "1", "F2" or "Enter".
+	Keys []string `json:"keys"`
+	// Leader is the character whose keys are broadcast, or any team member
+	// when empty, as long as its client is in the foreground.
+	Leader string `json:"leader,omitempty"`
+	// Excluded lists the characters not receiving the keys.
+	Excluded []string `json:"excluded"`
+}
+
+// KeyData is the data of key broadcast events.
+type KeyData struct {
+	Key     string   `json:"key"`
+	Leader  string   `json:"leader"`
+	Targets []string `json:"targets"`
+}
+
+// KeyBroadcastService delivers the allowed keys pressed in the leader window
+// to the other team windows, while it runs.
+type KeyBroadcastService struct {
+	windowService *WindowService
+	characterSvc  *CharacterService
+	input         *InputDispatcher
+	events        *EventBus
+	runner        *serviceRunner
+
+	mu       sync.Mutex
+	settings KeyBroadcastSettings
+	keys     []KeyCombo
+	onChange func(KeyBroadcastSettings)
+
+	// Owned by the listening goroutine: the targets of the keys held down,
+	// which also receive their key up and the characters typed.
+	held   map[uint16][]Character
+	typing []Character
+}
+
+// NewKeyBroadcastService creates a new instance of the KeyBroadcastService.
+func NewKeyBroadcastService(ws *WindowService, cs *CharacterService, input *InputDispatcher, events *EventBus) *KeyBroadcastService {
+	return &KeyBroadcastService{
+		windowService: ws,
+		characterSvc:  cs,
+		input:         input,
+		events:        events,
+		runner:        newServiceRunner("keyBroadcast", events),
+		settings:      KeyBroadcastSettings{Keys: []string{}, Excluded: []string{}},
+	}
+}
+
+// SetChangeHandler registers a function called with the settings whenever
+// they change, e.g. to persist them.
+func (kbs *KeyBroadcastService) SetChangeHandler(onChange func(KeyBroadcastSettings)) {
+	kbs.mu.Lock()
+	defer kbs.mu.Unlock()
+	kbs.onChange = onChange
+}
+
+// Settings returns the current settings.
+func (kbs *KeyBroadcastService) Settings() KeyBroadcastSettings {
+	kbs.mu.Lock()
+	defer kbs.mu.Unlock()
+	return kbs.copySettings()
+}
+
+func (kbs *KeyBroadcastService) copySettings() KeyBroadcastSettings {
+	settings := kbs.settings
+	settings.Keys = append([]string{}, settings.Keys...)
+	settings.Excluded = append([]string{}, settings.Excluded...)
+	return settings
+}
+
+// SetSettings replaces the settings, taking effect on the next key, and
+// calls the change handler. Keys are single keys, modifiers are not
+// broadcast.
+func (kbs *KeyBroadcastService) SetSettings(settings KeyBroadcastSettings) error {
+	if err := kbs.LoadSettings(settings); err != nil {
+		return err
+	}
+
+	kbs.mu.Lock()
+	onChange, current := kbs.onChange, kbs.copySettings()
+	kbs.mu.Unlock()
+
+	if onChange != nil {
+		onChange(current)
+	}
+	return nil
+}
+
+// LoadSettings replaces the settings with those of the configuration,
+// without calling the change handler.
+func (kbs *KeyBroadcastService) LoadSettings(settings KeyBroadcastSettings) error {
+	keys := make([]KeyCombo, 0, len(settings.Keys))
+	for _, key := range settings.Keys {
+		combo, err := ParseKeyCombo(key)
+		if err != nil {
+			return err
+		}
+		if combo.Modifiers != 0 {
+			return fmt.Errorf("%w: %s: modifiers are not broadcast", ErrInvalidCombo, key)
+		}
+		keys = append(keys, combo)
+	}
+
+	kbs.mu.Lock()
+	kbs.settings = settings
+	kbs.settings.Keys = append([]string{}, settings.Keys...)
+	kbs.settings.Excluded = append([]string{}, settings.Excluded...)
+	kbs.keys = keys
+	kbs.mu.Unlock()
+	return nil
+}
+
+// SetExcluded excludes a character from the broadcast, or includes it back.
+func (kbs *KeyBroadcastService) SetExcluded(name string, excluded bool) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("character name is empty")
+	}
+
+	settings := kbs.Settings()
+	list := settings.Excluded[:0]
+	for _, other := range settings.Excluded {
+		if !strings.EqualFold(other, name) {
+			list = append(list, other)
+		}
+	}
+	if excluded {
+		list = append(list, name)
+	}
+	settings.Excluded = list
+	return kbs.SetSettings(settings)
+}
+
+// Name identifies the service.
+func (kbs *KeyBroadcastService) Name() string {
+	return "keyBroadcast"
+}
+
+// Start broadcasts the allowed keys until Stop is called or ctx is cancelled.
+func (kbs *KeyBroadcastService) Start(ctx context.Context) error {
+	return kbs.runner.start(ctx, nil, kbs.listen)
+}
+
+// Stop stops broadcasting keys.
+func (kbs *KeyBroadcastService) Stop() error {
+	return kbs.runner.stop()
+}
+
+// Status reports the state of the service.
+func (kbs *KeyBroadcastService) Status() ServiceStatus {
+	return kbs.runner.status()
+}
+
+func (kbs *KeyBroadcastService) lifecycle() *serviceRunner {
+	return kbs.runner
+}
+
+func (kbs *KeyBroadcastService) listen(ctx context.Context) error {
+	subscription := kbs.input.Subscribe(InputFilter{Kinds: InputKeyEvents})
+	defer subscription.Unsubscribe()
+
+	kbs.held, kbs.typing = make(map[uint16][]Character), nil
+	log.Println("Broadcasting keys to the team")
+
+	for {
+		select {
+		case ev, ok := <-subscription.C:
+			if !ok {
+				return ErrInputClosed
+			}
+			kbs.handleKey(ev)
+		case <-ctx.Done():
+			log.Println("Stopped broadcasting keys")
+			return nil
+		}
+	}
+}
+
+// handleKey broadcasts a key pressed in the leader window to the targets,
+// then its characters and its release to the same targets.
+func (kbs *KeyBroadcastService) handleKey(ev InputEvent) {
+	switch ev.Kind {
+	case InputKeyDown:
+		kbs.typing = nil
+		if !kbs.allowed(ev.Keycode) {
+			return
+		}
+		leader, targets := kbs.targets()
+		if len(targets) == 0 {
+			return
+		}
+		kbs.held[ev.Keycode], kbs.typing = targets, targets
+		kbs.post(targets, ev)
+
+		key := KeyCombo{Keycode: ev.Keycode}.String()
+		data := KeyData{Key: key, Leader: leader.Name}
+		for _, target := range targets {
+			data.Targets = append(data.Targets, target.Name)
+		}
+		log.Printf("Key %s broadcast from %s to %s", key, leader.Name, strings.Join(data.Targets, ", "))
+		kbs.events.Publish(Event{Type: EventKeyBroadcast, Window: leader.Handle, Title: leader.Title, Data: data})
+	case InputKeyTyped:
+		kbs.post(kbs.typing, ev)
+	case InputKeyUp:
+		if targets, ok := kbs.held[ev.Keycode]; ok {
+			delete(kbs.held, ev.Keycode)
+			kbs.post(targets, ev)
+		}
+		kbs.typing = nil
+	}
+}
+
+// allowed reports whether the key is in the allow-list.
+func (kbs *KeyBroadcastService) allowed(keycode uint16) bool {
+	kbs.mu.Lock()
+	defer kbs.mu.Unlock()
+
+	for _, key := range kbs.keys {
+		if key.Matches(keycode, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// targets returns the team member in the foreground, if it may lead, and
+// the other online members not excluded.
+func (kbs *KeyBroadcastService) targets() (Character, []Character) {
+	settings := kbs.Settings()
+	foreground := kbs.windowService.GetForegroundWindow()
+
+	characters := kbs.characterSvc.GetCharacters()
+	leader, ok := findOnline(characters, foreground)
+	if !ok {
+		// The leader client may have been restarted since the last refresh.
+		// Other windows are left alone, not to refresh on every key typed.
+		if _, _, game := ParseGameTitle(kbs.windowService.GetWindowText(foreground)); !game {
+			return Character{}, nil
+		}
+		if err := kbs.characterSvc.Refresh(); err != nil {
+			log.Printf("Failed to refresh characters: %v", err)
+			return Character{}, nil
+		}
+		characters = kbs.characterSvc.GetCharacters()
+		if leader, ok = findOnline(characters, foreground); !ok {
+			return Character{}, nil
+		}
+	}
+	if settings.Leader != "" && !strings.EqualFold(leader.Name, settings.Leader) {
+		return Character{}, nil
+	}
+
+	var targets []Character
+	for _, character := range characters {
+		if !character.Online || character.Handle == foreground || containsFold(settings.Excluded, character.Name) {
+			continue
+		}
+		targets = append(targets, character)
+	}
+	return leader, targets
+}
+
+func (kbs *KeyBroadcastService) post(targets []Character, ev InputEvent) {
+	backend := kbs.windowService.Backend()
+	for _, target := range targets {
+		if err := backend.PostKey(target.Handle, ev); err != nil {
+			log.Printf("Failed to send key to %s: %v", target.Name, err)
+		}
+	}
+}
+
+// findOnline returns the online character whose client is hwnd.
+func findOnline(characters []Character, hwnd WindowHandle) (Character, bool) {
+	for _, character := range characters {
+		if character.Online && character.Handle == hwnd {
+			return character, true
+		}
+	}
+	return Character{}, false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+)
+
+// newKeyBroadcast broadcasts the key 1 within the team.
+func newKeyBroadcast(t *testing.T, names ...string) (*FakeWindowBackend, *KeyBroadcastService, *CharacterService, []WindowHandle) {
+	t.Helper()
+	fake, characters, handles := newFakeTeam(t, names...)
+	kbs := NewKeyBroadcastService(characters.windowService, characters, NewInputDispatcher(), NewEventBus())
+	if err := kbs.LoadSettings(KeyBroadcastSettings{Keys: []string{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	kbs.held = make(map[uint16][]Character)
+	return fake, kbs, characters, handles
+}
+
+// pressKey1 presses and releases the key 1.
+func pressKey1(t *testing.T, kbs *KeyBroadcastService) {
+	t.Helper()
+	combo, err := ParseKeyCombo("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kbs.handleKey(InputEvent{Kind: InputKeyDown, Keycode: combo.Keycode})
+	kbs.handleKey(InputEvent{Kind: InputKeyUp, Keycode: combo.Keycode})
+}
+
+func TestKeyBroadcastFollowsRestartedLeaders(t *testing.T) {
+	fake, kbs, _, handles := newKeyBroadcast(t, "Alpha", "Beta")
+	fake.CloseWindow(handles[0])
+	restarted := fake.AddWindow("Alpha - Dofus 2.70.5")
+	if err := fake.SetForegroundWindow(restarted); err != nil {
+		t.Fatal(err)
+	}
+
+	pressKey1(t, kbs)
+	keys := fake.Keys()
+	if len(keys) != 2 || keys[0].Window != handles[1] || keys[1].Window != handles[1] {
+		t.Fatalf("keys = %+v, want the key pressed and released in %d", keys, handles[1])
+	}
+}
+
+func TestKeyBroadcastIgnoresOtherWindows(t *testing.T) {
+	fake, kbs, characters, _ := newKeyBroadcast(t, "Alpha", "Beta")
+	notepad := fake.AddWindow("Notepad")
+	fake.AddWindow("Gamma - Dofus 2.70.5")
+	if err := fake.SetForegroundWindow(notepad); err != nil {
+		t.Fatal(err)
+	}
+
+	pressKey1(t, kbs)
+	if keys := fake.Keys(); len(keys) != 0 {
+		t.Fatalf("keys = %+v, want none", keys)
+	}
+	// Typing in another application does not refresh the characters.
+	if _, err := characters.GetCharacter("Gamma"); err == nil {
+		t.Fatal("Gamma was discovered by a key typed in Notepad")
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown key name")
+	ErrInvalidCombo = errors.New("invalid key combination")
+)
+
+// KeyModifiers is a set of modifier keys. Left and right keys are not told apart.
+type KeyModifiers uint16
+
+const (
+	ModShift KeyModifiers = 1 << iota
+	ModCtrl
+	ModMeta
+	ModAlt
+)
+
+// Modifier bits of the hook event masks, left keys in the low nibble and right
+// keys in the next one.
+const (
+	maskModifiersLeft  = 0x000F
+	maskModifiersRight = 0x00F0
+)
+
+// ModifiersFromMask returns the modifiers held according to a hook event mask.
+func ModifiersFromMask(mask uint16) KeyModifiers {
+	return KeyModifiers((mask&maskModifiersLeft)|(mask&maskModifiersRight)>>4) & (ModShift | ModCtrl | ModMeta | ModAlt)
+}
+
+var modifierNames = []struct {
+	modifier KeyModifiers
+	name     string
+	aliases  []string
+}{
+	{ModCtrl, "Ctrl", []string{"ctrl", "control", "ctl"}},
+	{ModAlt, "Alt", []string{"alt", "option"}},
+	{ModShift, "Shift", []string{"shift"}},
+	{ModMeta, "Meta", []string{"meta", "win", "windows", "super", "cmd", "command"}},
+}
+
+// keyNames lists the keys by virtual keycode, as reported by the input hook.
+// The first name is the one used when formatting a combination.
+var keyNames = []struct {
+	keycode uint16
+	names   []string
+}{
+	{0x0001, []string{"Escape", "Esc"}},
+	{0x003B, []string{"F1"}},
+	{0x003C, []string{"F2"}},
+	{0x003D, []string{"F3"}},
+	{0x003E, []string{"F4"}},
+	{0x003F, []string{"F5"}},
+	{0x0040, []string{"F6"}},
+	{0x0041, []string{"F7"}},
+	{0x0042, []string{"F8"}},
+	{0x0043, []string{"F9"}},
+	{0x0044, []string{"F10"}},
+	{0x0057, []string{"F11"}},
+	{0x0058, []string{"F12"}},
+	{0x005B, []string{"F13"}},
+	{0x005C, []string{"F14"}},
+	{0x005D, []string{"F15"}},
+	{0x0063, []string{"F16"}},
+	{0x0064, []string{"F17"}},
+	{0x0065, []string{"F18"}},
+	{0x0066, []string{"F19"}},
+	{0x0067, []string{"F20"}},
+	{0x0068, []string{"F21"}},
+	{0x0069, []string{"F22"}},
+	{0x006A, []string{"F23"}},
+	{0x006B, []string{"F24"}},
+
+	{0x0029, []string{"`", "Backquote", "Grave"}},
+	{0x0002, []string{"1"}},
+	{0x0003, []string{"2"}},
+	{0x0004, []string{"3"}},
+	{0x0005, []string{"4"}},
+	{0x0006, []string{"5"}},
+	{0x0007, []string{"6"}},
+	{0x0008, []string{"7"}},
+	{0x0009, []string{"8"}},
+	{0x000A, []string{"9"}},
+	{0x000B, []string{"0"}},
+	{0x000C, []string{"-", "Minus"}},
+	{0x000D, []string{"=", "Equals"}},
+	{0x000E, []string{"Backspace"}},
+	{0x000F, []string{"Tab"}},
+	{0x003A, []string{"CapsLock"}},
+
+	{0x001E, []string{"A"}},
+	{0x0030, []string{"B"}},
+	{0x002E, []string{"C"}},
+	{0x0020, []string{"D"}},
+	{0x0012, []string{"E"}},
+	{0x0021, []string{"F"}},
+	{0x0022, []string{"G"}},
+	{0x0023, []string{"H"}},
+	{0x0017, []string{"I"}},
+	{0x0024, []string{"J"}},
+	{0x0025, []string{"K"}},
+	{0x0026, []string{"L"}},
+	{0x0032, []string{"M"}},
+	{0x0031, []string{"N"}},
+	{0x0018, []string{"O"}},
+	{0x0019, []string{"P"}},
+	{0x0010, []string{"Q"}},
+	{0x0013, []string{"R"}},
+	{0x001F, []string{"S"}},
+	{0x0014, []string{"T"}},
+	{0x0016, []string{"U"}},
+	{0x002F, []string{"V"}},
+	{0x0011, []string{"W"}},
+	{0x002D, []string{"X"}},
+	{0x0015, []string{"Y"}},
+	{0x002C, []string{"Z"}},
+
+	{0x001A, []string{"[", "OpenBracket"}},
+	{0x001B, []string{"]", "CloseBracket"}},
+	{0x002B, []string{"\\", "Backslash"}},
+	{0x0027, []string{";", "Semicolon"}},
+	{0x0028, []string{"'", "Quote"}},
+	{0x001C, []string{"Enter", "Return"}},
+	{0x0033, []string{",", "Comma"}},
+	{0x0034, []string{".", "Period"}},
+	{0x0035, []string{"/", "Slash"}},
+	{0x0039, []string{"Space", "Spacebar"}},
+
+	{0x0E37, []string{"PrintScreen", "PrtSc"}},
+	{0x0046, []string{"ScrollLock"}},
+	{0x0E45, []string{"Pause", "Break"}},
+	{0x0E52, []string{"Insert", "Ins"}},
+	{0x0E53, []string{"Delete", "Del"}},
+	{0x0E47, []string{"Home"}},
+	{0x0E4F, []string{"End"}},
+	{0x0E49, []string{"PageUp", "PgUp"}},
+	{0x0E51, []string{"PageDown", "PgDn"}},
+	{0xE048, []string{"Up", "ArrowUp"}},
+	{0xE04B, []string{"Left", "ArrowLeft"}},
+	{0xE04D, []string{"Right", "ArrowRight"}},
+	{0xE050, []string{"Down", "ArrowDown"}},
+	{0x0E5D, []string{"ContextMenu", "Menu", "Apps"}},
+
+	{0x0045, []string{"NumLock"}},
+	{0x0E35, []string{"NumpadDivide", "NumpadSlash"}},
+	{0x0037, []string{"NumpadMultiply", "NumpadAsterisk"}},
+	{0x004A, []string{"NumpadSubtract", "NumpadMinus"}},
+	{0x004E, []string{"NumpadAdd", "NumpadPlus"}},
+	{0x0E1C, []string{"NumpadEnter"}},
+	{0x0053, []string{"NumpadDecimal", "NumpadDelete"}},
+	{0x004F, []string{"Numpad1"}},
+	{0x0050, []string{"Numpad2"}},
+	{0x0051, []string{"Numpad3"}},
+	{0x004B, []string{"Numpad4"}},
+	{0x004C, []string{"Numpad5"}},
+	{0x004D, []string{"Numpad6"}},
+	{0x0047, []string{"Numpad7"}},
+	{0x0048, []string{"Numpad8"}},
+	{0x0049, []string{"Numpad9"}},
+	{0x0052, []string{"Numpad0"}},
+}
+
+var (
+	keycodesByName  = make(map[string]uint16)
+	keyNamesByCode  = make(map[uint16]string)
+	modifiersByName = make(map[string]KeyModifiers)
+)
+
+func init() {
+	for _, key := range keyNames {
+		keyNamesByCode[key.keycode] = key.names[0]
+		for _, name := range key.names {
+			keycodesByName[normalizeKeyName(name)] = key.keycode
+		}
+	}
+	// Short numpad names: "Num4", "KP4".
+	for i := 0; i <= 9; i++ {
+		keycode := keycodesByName[fmt.Sprintf("numpad%d", i)]
+		keycodesByName[fmt.Sprintf("num%d", i)] = keycode
+		keycodesByName[fmt.Sprintf("kp%d", i)] = keycode
+	}
+	for _, modifier := range modifierNames {
+		for _, alias := range modifier.aliases {
+			modifiersByName[alias] = modifier.modifier
+		}
+	}
+}
+
+// normalizeKeyName makes key names case-insensitive and ignores separators,
+// so that "Page Up", "page_up" and "PageUp" are the same key.
+func normalizeKeyName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) <= 1 {
+		return name
+	}
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+// KeyCombo is a key pressed while holding a set of modifiers, such as "Ctrl+Alt+F3".
+type KeyCombo struct {
+	Modifiers KeyModifiers
+	Keycode   uint16
+}
+
+// ParseKeyCombo parses a combination of modifiers and a key separated by
+// "+", e.g. "Ctrl+Alt+F3", "Numpad4" or "Shift+Tab". Names are case-insensitive.
+func ParseKeyCombo(combo string) (KeyCombo, error) {
+	if strings.TrimSpace(combo) == "" {
+		return KeyCombo{}, fmt.Errorf("%w: empty key", ErrInvalidCombo)
+	}
+
+	var result KeyCombo
+	parts := strings.Split(combo, "+")
+	for i, part := range parts {
+		name := normalizeKeyName(part)
+		if name == "" {
+			return KeyCombo{}, fmt.Errorf("%w: empty key name in %q", ErrInvalidCombo, combo)
+		}
+
+		if i < len(parts)-1 {
+			modifier, ok := modifiersByName[name]
+			if !ok {
+				return KeyCombo{}, fmt.Errorf("%w: %q is not a modifier (Ctrl, Alt, Shift or Meta) in %q", ErrInvalidCombo, strings.TrimSpace(part), combo)
+			}
+			if result.Modifiers&modifier != 0 {
+				return KeyCombo{}, fmt.Errorf("%w: duplicate modifier %q in %q", ErrInvalidCombo, strings.TrimSpace(part), combo)
+			}
+			result.Modifiers |= modifier
+			continue
+		}
+
+		if _, ok := modifiersByName[name]; ok {
+			return KeyCombo{}, fmt.Errorf("%w: %q has no key besides its modifiers", ErrInvalidCombo, combo)
+		}
+		keycode, ok := keycodesByName[name]
+		if !ok {
+			return KeyCombo{}, fmt.Errorf("%w %q in %q", ErrUnknownKey, strings.TrimSpace(part), combo)
+		}
+		result.Keycode = keycode
+	}
+
+	return result, nil
+}
+
+// Matches reports whether a key event, given by its keycode and modifier
+// mask, is this combination. Modifiers must match exactly, so that "1" does
+// not fire on Ctrl+1.
+func (kc KeyCombo) Matches(keycode, mask uint16) bool {
+	// With Num Lock off, the numpad digits are reported as navigation keys.
+	if keycode&0xFF00 == 0xEE00 {
+		keycode &= 0x00FF
+	}
+	return keycode == kc.Keycode && ModifiersFromMask(mask) == kc.Modifiers
+}
+
+// String formats the combination with its canonical names, e.g. "Ctrl+Alt+F3".
+func (kc KeyCombo) String() string {
+	var parts []string
+	for _, modifier := range modifierNames {
+		if kc.Modifiers&modifier.modifier != 0 {
+			parts = append(parts, modifier.name)
+		}
+	}
+	name, ok := keyNamesByCode[kc.Keycode]
+	if !ok {
+		name = fmt.Sprintf("0x%04X", kc.Keycode)
+	}
+	return strings.Join(append(parts, name), "+")
+}
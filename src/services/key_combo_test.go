@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  KeyCombo
+		// name is the canonical name of the combination.
+		name string
+	}{
+		{"F1", KeyCombo{Keycode: 0x003B}, "F1"},
+		{"ctrl+alt+f3", KeyCombo{Modifiers: ModCtrl | ModAlt, Keycode: 0x003D}, "Ctrl+Alt+F3"},
+		{"Alt + Control + F3", KeyCombo{Modifiers: ModCtrl | ModAlt, Keycode: 0x003D}, "Ctrl+Alt+F3"},
+		{"Shift+Tab", KeyCombo{Modifiers: ModShift, Keycode: 0x000F}, "Shift+Tab"},
+		{"Win+Page Up", KeyCombo{Modifiers: ModMeta, Keycode: 0x0E49}, "Meta+PageUp"},
+		{"Numpad4", KeyCombo{Keycode: 0x004B}, "Numpad4"},
+		{"Num4", KeyCombo{Keycode: 0x004B}, "Numpad4"},
+		{"kp_4", KeyCombo{Keycode: 0x004B}, "Numpad4"},
+		{"Ctrl+NumpadEnter", KeyCombo{Modifiers: ModCtrl, Keycode: 0x0E1C}, "Ctrl+NumpadEnter"},
+		{"-", KeyCombo{Keycode: 0x000C}, "-"},
+	}
+	for _, test := range tests {
+		combo, err := ParseKeyCombo(test.combo)
+		if err != nil {
+			t.Errorf("ParseKeyCombo(%q): %v", test.combo, err)
+			continue
+		}
+		if combo != test.want || combo.String() != test.name {
+			t.Errorf("ParseKeyCombo(%q) = %+v (%s), want %+v (%s)", test.combo, combo, combo, test.want, test.name)
+		}
+	}
+}
+
+func TestParseKeyComboRejectsInvalidCombos(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  error
+	}{
+		{"", ErrInvalidCombo},
+		{"  ", ErrInvalidCombo},
+		{"Ctrl+", ErrInvalidCombo},
+		{"+F1", ErrInvalidCombo},
+		{"Ctrl+Ctrl+F1", ErrInvalidCombo},
+		{"Ctrl+Control+F1", ErrInvalidCombo},
+		{"Win+Super+F1", ErrInvalidCombo},
+		{"Hyper+F1", ErrInvalidCombo},
+		{"F1+F2", ErrInvalidCombo},
+		{"Ctrl+Alt", ErrInvalidCombo},
+		{"F25", ErrUnknownKey},
+		{"Ctrl+Numpad10", ErrUnknownKey},
+	}
+	for _, test := range tests {
+		if combo, err := ParseKeyCombo(test.combo); !errors.Is(err, test.want) {
+			t.Errorf("ParseKeyCombo(%q) = %+v, %v, want %v", test.combo, combo, err, test.want)
+		}
+	}
+}
+
+func TestKeyComboMatches(t *testing.T) {
+	const (
+		leftShift  = 0x0001
+		leftCtrl   = 0x0002
+		leftAlt    = 0x0008
+		rightShift = 0x0010
+		rightCtrl  = 0x0020
+		rightAlt   = 0x0080
+	)
+	tests := []struct {
+		combo   string
+		keycode uint16
+		mask    uint16
+		want    bool
+	}{
+		{"1", 0x0002, 0, true},
+		{"1", 0x0002, leftCtrl, false},
+		{"1", 0x0003, 0, false},
+		{"Ctrl+1", 0x0002, leftCtrl, true},
+		{"Ctrl+1", 0x0002, rightCtrl, true},
+		{"Ctrl+1", 0x0002, leftCtrl | rightCtrl, true},
+		{"Ctrl+1", 0x0002, 0, false},
+		{"Ctrl+1", 0x0002, leftCtrl | leftShift, false},
+		{"Ctrl+Alt+F3", 0x003D, leftCtrl | rightAlt, true},
+		{"Ctrl+Alt+F3", 0x003D, rightCtrl | leftAlt, true},
+		{"Ctrl+Alt+F3", 0x003D, rightCtrl, false},
+		{"Shift+Tab", 0x000F, rightShift, true},
+		// Lock keys in the upper bits of the mask are ignored.
+		{"Shift+Tab", 0x000F, rightShift | 0x2000, true},
+		// With Num Lock off, the numpad digits are reported as
+		// navigation keys.
+		{"Numpad4", 0x004B, 0, true},
+		{"Numpad4", 0xEE4B, 0, true},
+		{"Numpad4", 0xE04B, 0, false},
+		{"Left", 0xE04B, 0, true},
+		{"Ctrl+Numpad0", 0xEE52, rightCtrl, true},
+		{"Numpad0", 0x0E52, 0, false},
+	}
+	for _, test := range tests {
+		combo, err := ParseKeyCombo(test.combo)
+		if err != nil {
+			t.Fatalf("ParseKeyCombo(%q): %v", test.combo, err)
+		}
+		if got := combo.Matches(test.keycode, test.mask); got != test.want {
+			t.Errorf("%s.Matches(0x%04X, 0x%04X) = %v, want %v", test.combo, test.keycode, test.mask, got, test.want)
+		}
+	}
+}
+
+func TestModifiersFromMask(t *testing.T) {
+	tests := []struct {
+		mask uint16
+		want KeyModifiers
+	}{
+		{0, 0},
+		{0x0001, ModShift},
+		{0x0010, ModShift},
+		{0x0011, ModShift},
+		{0x0022, ModCtrl},
+		{0x0084, ModMeta | ModAlt},
+		{0xFF00, 0},
+	}
+	for _, test := range tests {
+		if got := ModifiersFromMask(test.mask); got != test.want {
+			t.Errorf("ModifiersFromMask(0x%04X) = %b, want %b", test.mask, got, test.want)
+		}
+	}
+}
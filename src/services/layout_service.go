@@ -0,0 +1,358 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrLayoutNotFound  = errors.New("layout not found")
+	ErrInvalidLayout   = errors.New("invalid layout")
+	ErrMonitorNotFound = errors.New("monitor not found")
+)
+
+// Arrangements computed from the work area.
+const (
+	// LayoutTile splits the work area in a grid, one cell per window.
+	LayoutTile = "tile"
+	// LayoutCascade offsets each window from the previous one by a title bar.
+	LayoutCascade = "cascade"
+	// LayoutStack gives every window the whole work area.
+	LayoutStack = "stack"
+)
+
+// cascadeStep is the offset between cascaded windows at 100% scaling.
+const cascadeStep = 32
+
+// LayoutWindow is the window rectangle, decorations included, of a character.
+type LayoutWindow struct {
+	Character string `json:"character"`
+	Rect      Rect   `json:"rect"`
+}
+
+// Layout is a named arrangement of the team windows.
+type Layout struct {
+	Name    string         `json:"name"`
+	Windows []LayoutWindow `json:"windows"`
+}
+
+// Placement is the outcome of moving the client of a character.
+type Placement struct {
+	Character string       `json:"character"`
+	Handle    WindowHandle `json:"handle,omitempty" swaggertype:"integer"`
+	Rect      Rect         `json:"rect"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// LayoutResult reports the windows moved by an arrangement or a layout.
+type LayoutResult struct {
+	Layout  string      `json:"layout"`
+	Windows []Placement `json:"windows"`
+}
+
+// LayoutService arranges the team windows, and stores named layouts of them.
+type LayoutService struct {
+	windowService *WindowService
+	characterSvc  *CharacterService
+	events        *EventBus
+
+	mu       sync.Mutex
+	layouts  map[string]Layout // keyed by lower-cased name
+	onChange func([]Layout)
+}
+
+// NewLayoutService creates a new instance of the LayoutService.
+func NewLayoutService(ws *WindowService, cs *CharacterService, events *EventBus) *LayoutService {
+	return &LayoutService{
+		windowService: ws,
+		characterSvc:  cs,
+		events:        events,
+		layouts:       make(map[string]Layout),
+	}
+}
+
+// SetChangeHandler registers a function called with the layouts whenever
+// they change, e.g. to persist them.
+func (ls *LayoutService) SetChangeHandler(onChange func([]Layout)) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.onChange = onChange
+}
+
+// Layouts returns the saved layouts ordered by name.
+func (ls *LayoutService) Layouts() []Layout {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.sortedLayouts()
+}
+
+func (ls *LayoutService) sortedLayouts() []Layout {
+	layouts := make([]Layout, 0, len(ls.layouts))
+	for _, layout := range ls.layouts {
+		layout.Windows = append([]LayoutWindow{}, layout.Windows...)
+		layouts = append(layouts, layout)
+	}
+	sort.Slice(layouts, func(i, j int) bool {
+		return layouts[i].Name < layouts[j].Name
+	})
+	return layouts
+}
+
+// SetLayouts replaces the saved layouts with those of the configuration,
+// without calling the change handler.
+func (ls *LayoutService) SetLayouts(layouts []Layout) error {
+	byName := make(map[string]Layout, len(layouts))
+	for _, layout := range layouts {
+		layout, err := checkLayout(layout)
+		if err != nil {
+			return err
+		}
+		byName[strings.ToLower(layout.Name)] = layout
+	}
+
+	ls.mu.Lock()
+	ls.layouts = byName
+	ls.mu.Unlock()
+	return nil
+}
+
+// GetLayout returns the layout with the given name.
+func (ls *LayoutService) GetLayout(name string) (Layout, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	layout, ok := ls.layouts[strings.ToLower(name)]
+	if !ok {
+		return Layout{}, fmt.Errorf("%w: %s", ErrLayoutNotFound, name)
+	}
+	layout.Windows = append([]LayoutWindow{}, layout.Windows...)
+	return layout, nil
+}
+
+// SaveLayout stores a layout, replacing the one with the same name.
+func (ls *LayoutService) SaveLayout(layout Layout) (Layout, error) {
+	layout, err := checkLayout(layout)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	ls.mu.Lock()
+	ls.layouts[strings.ToLower(layout.Name)] = layout
+	ls.mu.Unlock()
+	ls.changed()
+	return layout, nil
+}
+
+// DeleteLayout removes the layout with the given name.
+func (ls *LayoutService) DeleteLayout(name string) error {
+	ls.mu.Lock()
+	key := strings.ToLower(name)
+	if _, ok := ls.layouts[key]; !ok {
+		ls.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrLayoutNotFound, name)
+	}
+	delete(ls.layouts, key)
+	ls.mu.Unlock()
+	ls.changed()
+	return nil
+}
+
+func (ls *LayoutService) changed() {
+	ls.mu.Lock()
+	onChange, layouts := ls.onChange, ls.sortedLayouts()
+	ls.mu.Unlock()
+
+	if onChange != nil {
+		onChange(layouts)
+	}
+}
+
+// checkLayout trims the names of the layout and checks its windows.
+func checkLayout(layout Layout) (Layout, error) {
+	layout.Name = strings.TrimSpace(layout.Name)
+	if layout.Name == "" {
+		return Layout{}, fmt.Errorf("%w: name is empty", ErrInvalidLayout)
+	}
+
+	windows := make([]LayoutWindow, 0, len(layout.Windows))
+	seen := make(map[string]bool, len(layout.Windows))
+	for i, window := range layout.Windows {
+		window.Character = strings.TrimSpace(window.Character)
+		switch {
+		case window.Character == "":
+			return Layout{}, fmt.Errorf("%w: windows[%d]: character is empty", ErrInvalidLayout, i)
+		case seen[strings.ToLower(window.Character)]:
+			return Layout{}, fmt.Errorf("%w: windows[%d]: %s is placed twice", ErrInvalidLayout, i, window.Character)
+		case window.Rect.Width() <= 0 || window.Rect.Height() <= 0:
+			return Layout{}, fmt.Errorf("%w: windows[%d]: rectangle is empty", ErrInvalidLayout, i)
+		}
+		seen[strings.ToLower(window.Character)] = true
+		windows = append(windows, window)
+	}
+	layout.Windows = windows
+	return layout, nil
+}
+
+// Arrange tiles, cascades or stacks the online team windows over the work
+// area of a monitor, in slot order. Monitors are numbered from 0, the
+// primary monitor, as listed by WindowService.Monitors.
+func (ls *LayoutService) Arrange(arrangement string, monitor int) (LayoutResult, error) {
+	team, err := ls.onlineTeam()
+	if err != nil {
+		return LayoutResult{}, err
+	}
+	monitors, err := ls.windowService.Monitors()
+	if err != nil {
+		return LayoutResult{}, err
+	}
+	if monitor < 0 || monitor >= len(monitors) {
+		return LayoutResult{}, fmt.Errorf("%w: monitor %d", ErrMonitorNotFound, monitor)
+	}
+	area := monitors[monitor].WorkArea
+
+	var rects []Rect
+	switch arrangement {
+	case LayoutTile:
+		rects = tileRects(area, len(team))
+	case LayoutCascade:
+		rects = cascadeRects(area, len(team), monitors[monitor].Scaled(cascadeStep))
+	case LayoutStack:
+		rects = make([]Rect, len(team))
+		for i := range rects {
+			rects[i] = area
+		}
+	default:
+		return LayoutResult{}, fmt.Errorf("%w: unknown arrangement %q", ErrInvalidLayout, arrangement)
+	}
+
+	result := LayoutResult{Layout: arrangement, Windows: make([]Placement, 0, len(team))}
+	for i, character := range team {
+		result.Windows = append(result.Windows, ls.place(character, rects[i]))
+	}
+	ls.applied(result)
+	return result, nil
+}
+
+// tileRects splits area in a grid of n cells, filled row by row.
+func tileRects(area Rect, n int) []Rect {
+	if n <= 0 {
+		return nil
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + columns - 1) / columns
+
+	rects := make([]Rect, n)
+	for i := range rects {
+		column, row := i%columns, i/columns
+		rects[i] = Rect{
+			Left:   area.Left + column*area.Width()/columns,
+			Top:    area.Top + row*area.Height()/rows,
+			Right:  area.Left + (column+1)*area.Width()/columns,
+			Bottom: area.Top + (row+1)*area.Height()/rows,
+		}
+	}
+	return rects
+}
+
+// cascadeRects offsets n windows by step, as large as the area allows but no
+// smaller than half of it.
+func cascadeRects(area Rect, n, step int) []Rect {
+	offset := step * max(n-1, 0)
+	width := max(area.Width()-offset, area.Width()/2)
+	height := max(area.Height()-offset, area.Height()/2)
+
+	rects := make([]Rect, n)
+	for i := range rects {
+		left := area.Left + min(i*step, area.Width()-width)
+		top := area.Top + min(i*step, area.Height()-height)
+		rects[i] = Rect{Left: left, Top: top, Right: left + width, Bottom: top + height}
+	}
+	return rects
+}
+
+// ApplyLayout moves the clients of the characters of a saved layout. The
+// characters without an open client are reported and skipped.
+func (ls *LayoutService) ApplyLayout(name string) (LayoutResult, error) {
+	layout, err := ls.GetLayout(name)
+	if err != nil {
+		return LayoutResult{}, err
+	}
+	if err := ls.characterSvc.Refresh(); err != nil {
+		return LayoutResult{}, err
+	}
+
+	result := LayoutResult{Layout: layout.Name, Windows: make([]Placement, 0, len(layout.Windows))}
+	for _, window := range layout.Windows {
+		character, err := ls.characterSvc.GetCharacter(window.Character)
+		if err == nil && !character.Online {
+			err = fmt.Errorf("%w: %s", ErrCharacterOffline, character.Name)
+		}
+		if err != nil {
+			result.Windows = append(result.Windows, Placement{Character: window.Character, Rect: window.Rect, Error: err.Error()})
+			continue
+		}
+		result.Windows = append(result.Windows, ls.place(character, window.Rect))
+	}
+	ls.applied(result)
+	return result, nil
+}
+
+// CaptureLayout saves the current arrangement of the online team windows as
+// a layout with the given name.
+func (ls *LayoutService) CaptureLayout(name string) (Layout, error) {
+	team, err := ls.onlineTeam()
+	if err != nil {
+		return Layout{}, err
+	}
+
+	layout := Layout{Name: name}
+	for _, character := range team {
+		window, err := ls.windowService.GetWindow(character.Handle)
+		if err != nil {
+			return Layout{}, err
+		}
+		if window.Minimized {
+			log.Printf("Window of %s is minimized, it is left out of layout %s", character.Name, name)
+			continue
+		}
+		layout.Windows = append(layout.Windows, LayoutWindow{Character: character.Name, Rect: window.Rect})
+	}
+	return ls.SaveLayout(layout)
+}
+
+// onlineTeam returns the team members with an open client, in slot order.
+func (ls *LayoutService) onlineTeam() ([]Character, error) {
+	if err := ls.characterSvc.Refresh(); err != nil {
+		return nil, err
+	}
+
+	var team []Character
+	for _, character := range ls.characterSvc.GetCharacters() {
+		if character.Online {
+			team = append(team, character)
+		}
+	}
+	if len(team) == 0 {
+		return nil, fmt.Errorf("%w: no team member is online", ErrCharacterOffline)
+	}
+	return team, nil
+}
+
+func (ls *LayoutService) place(character Character, rect Rect) Placement {
+	placement := Placement{Character: character.Name, Handle: character.Handle, Rect: rect}
+	if err := ls.windowService.Backend().MoveWindow(character.Handle, rect); err != nil {
+		log.Printf("Failed to move the window of %s: %v", character.Name, err)
+		placement.Error = err.Error()
+	}
+	return placement
+}
+
+func (ls *LayoutService) applied(result LayoutResult) {
+	log.Printf("Layout %s applied to %d window(s)", result.Layout, len(result.Windows))
+	ls.events.Publish(Event{Type: EventLayoutApplied, Data: result})
+}
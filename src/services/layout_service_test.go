@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// layoutArea is a 1920x1080 work area right of a primary monitor.
+var layoutArea = Rect{Left: 1920, Top: 40, Right: 3840, Bottom: 1120}
+
+// cell returns a rectangle of layoutArea from its offset and size.
+func cell(left, top, width, height int) Rect {
+	return Rect{Left: layoutArea.Left + left, Top: layoutArea.Top + top, Right: layoutArea.Left + left + width, Bottom: layoutArea.Top + top + height}
+}
+
+func TestTileRects(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []Rect
+	}{
+		{0, nil},
+		{1, []Rect{layoutArea}},
+		{2, []Rect{cell(0, 0, 960, 1080), cell(960, 0, 960, 1080)}},
+		{5, []Rect{
+			cell(0, 0, 640, 540), cell(640, 0, 640, 540), cell(1280, 0, 640, 540),
+			cell(0, 540, 640, 540), cell(640, 540, 640, 540),
+		}},
+	}
+	for _, test := range tests {
+		if got := tileRects(layoutArea, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tileRects(%d) = %v, want %v", test.n, got, test.want)
+		}
+	}
+}
+
+func TestCascadeRects(t *testing.T) {
+	tests := []struct {
+		name    string
+		n, step int
+		want    []Rect
+	}{
+		{"none", 0, 30, []Rect{}},
+		{"one", 1, 30, []Rect{layoutArea}},
+		{"three", 3, 30, []Rect{
+			cell(0, 0, 1860, 1020), cell(30, 30, 1860, 1020), cell(60, 60, 1860, 1020),
+		}},
+		// Windows keep half of the area, the last ones pile up in the
+		// bottom right corner.
+		{"clamped", 40, 30, nil},
+	}
+	for _, test := range tests {
+		got := cascadeRects(layoutArea, test.n, test.step)
+		if test.want != nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: cascadeRects(%d, %d) = %v, want %v", test.name, test.n, test.step, got, test.want)
+		}
+		if len(got) != test.n {
+			t.Fatalf("%s: %d rectangles, want %d", test.name, len(got), test.n)
+		}
+		for i, rect := range got {
+			if rect.Width() < layoutArea.Width()/2 || rect.Height() < layoutArea.Height()/2 ||
+				rect.Left < layoutArea.Left || rect.Top < layoutArea.Top || rect.Right > layoutArea.Right || rect.Bottom > layoutArea.Bottom {
+				t.Errorf("%s: rectangle %d is %v, want at least half of %v inside it", test.name, i, rect, layoutArea)
+			}
+		}
+	}
+
+	clamped := cascadeRects(layoutArea, 40, 30)
+	if want := cell(960, 540, 960, 540); clamped[39] != want {
+		t.Errorf("last clamped rectangle is %v, want %v", clamped[39], want)
+	}
+	if want := cell(300, 300, 960, 540); clamped[10] != want {
+		t.Errorf("clamped rectangle 10 is %v, want %v", clamped[10], want)
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	rect := Rect{Right: 800, Bottom: 600}
+	tests := []struct {
+		name   string
+		layout Layout
+		valid  bool
+	}{
+		{"empty name", Layout{Name: " ", Windows: []LayoutWindow{{Character: "Iop", Rect: rect}}}, false},
+		{"no windows", Layout{Name: "empty"}, true},
+		{"empty character", Layout{Name: "grid", Windows: []LayoutWindow{{Character: " ", Rect: rect}}}, false},
+		{"duplicate character", Layout{Name: "grid", Windows: []LayoutWindow{
+			{Character: "Iop", Rect: rect},
+			{Character: " iop ", Rect: rect},
+		}}, false},
+		{"empty rectangle", Layout{Name: "grid", Windows: []LayoutWindow{{Character: "Iop", Rect: Rect{Right: 800}}}}, false},
+		{"inverted rectangle", Layout{Name: "grid", Windows: []LayoutWindow{{Character: "Iop", Rect: Rect{Left: 800, Bottom: 600}}}}, false},
+		{"overlapping windows", Layout{Name: "grid", Windows: []LayoutWindow{
+			{Character: "Iop", Rect: rect},
+			{Character: "Cra", Rect: rect},
+		}}, true},
+	}
+	for _, test := range tests {
+		_, err := checkLayout(test.layout)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%s: checkLayout: %v", test.name, err)
+		case !test.valid && !errors.Is(err, ErrInvalidLayout):
+			t.Errorf("%s: checkLayout: got %v, want ErrInvalidLayout", test.name, err)
+		}
+	}
+}
+
+func TestCheckLayoutTrimsNames(t *testing.T) {
+	layout, err := checkLayout(Layout{Name: " grid ", Windows: []LayoutWindow{
+		{Character: " Iop ", Rect: Rect{Right: 800, Bottom: 600}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Name != "grid" || layout.Windows[0].Character != "Iop" {
+		t.Fatalf("checkLayout = %+v, want the names trimmed", layout)
+	}
+}
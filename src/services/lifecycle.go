@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrServiceNotFound   = errors.New("service not found")
+	ErrServiceRunning    = errors.New("service already running")
+	ErrServiceNotRunning = errors.New("service not running")
+)
+
+// ServiceState is the state of a background service.
+type ServiceState string
+
+const (
+	ServiceStopped  ServiceState = "stopped"
+	ServiceStarting ServiceState = "starting"
+	ServiceRunning  ServiceState = "running"
+	// ServiceFailed is the state of a service that stopped on an error.
+	ServiceFailed ServiceState = "failed"
+)
+
+// ServiceStatus reports the state of a background service.
+type ServiceStatus struct {
+	Name        string       `json:"name"`
+	State       ServiceState `json:"state"`
+	StartedAt   *time.Time   `json:"startedAt,omitempty"`
+	Uptime      string       `json:"uptime,omitempty"`
+	LastError   string       `json:"lastError,omitempty"`
+	LastErrorAt *time.Time   `json:"lastErrorAt,omitempty"`
+	// Restarts is the number of restarts since the service last failed.
+	Restarts int `json:"restarts,omitempty"`
+	// NextRestartAt is set while a failed service waits to be restarted.
+	NextRestartAt *time.Time `json:"nextRestartAt,omitempty"`
+}
+
+// Service is the lifecycle contract of the background services.
+type Service interface {
+	// Name identifies the service, e.g. in GET /services.
+	Name() string
+	// Start runs the service until Stop is called or ctx is cancelled. It
+	// fails with ErrServiceRunning when the service already runs.
+	Start(ctx context.Context) error
+	// Stop stops the service and waits for it to end. It fails with
+	// ErrServiceNotRunning when the service does not run.
+	Stop() error
+	// Status reports the state, uptime and last error of the service.
+	Status() ServiceStatus
+}
+
+// serviceRunner implements the Service lifecycle for a service running a
+// loop in a goroutine. It never calls into the service while holding its
+// lock, so that services may check the state under their own lock.
+type serviceRunner struct {
+	name   string
+	events *EventBus
+
+	mu          sync.Mutex
+	state       ServiceState
+	startedAt   time.Time
+	lastError   error
+	lastErrorAt time.Time
+	cancel      context.CancelFunc
+	done        chan struct{}
+	onFailure   func(err error)
+}
+
+func newServiceRunner(name string, events *EventBus) *serviceRunner {
+	return &serviceRunner{name: name, events: events, state: ServiceStopped}
+}
+
+// start runs setup, then loop in a goroutine until its context is cancelled.
+// A setup error fails the start, and like a loop error fails the service.
+func (r *serviceRunner) start(ctx context.Context, setup func(ctx context.Context) error, loop func(ctx context.Context) error) error {
+	r.mu.Lock()
+	if r.state == ServiceRunning || r.state == ServiceStarting {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrServiceRunning, r.name)
+	}
+	r.state = ServiceStarting
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	if setup != nil {
+		if err := setup(ctx); err != nil {
+			cancel()
+			r.failed(err)
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.state, r.startedAt, r.cancel, r.done = ServiceRunning, time.Now(), cancel, done
+	r.mu.Unlock()
+	log.Printf("Service %s started", r.name)
+	r.events.publishServiceState(r.name, string(ServiceRunning), nil)
+
+	go func() {
+		err := loop(ctx)
+		if ctx.Err() != nil {
+			err = nil
+		}
+		cancel()
+		r.exited(err)
+		close(done)
+	}()
+	return nil
+}
+
+// stop cancels the loop and waits for it to end.
+func (r *serviceRunner) stop() error {
+	r.mu.Lock()
+	if r.state != ServiceRunning {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrServiceNotRunning, r.name)
+	}
+	cancel, done := r.cancel, r.done
+	r.mu.Unlock()
+
+	cancel()
+	<-done
+	return nil
+}
+
+// exited records the end of the loop, failed if err is not nil.
+func (r *serviceRunner) exited(err error) {
+	if err != nil {
+		r.failed(err)
+		return
+	}
+
+	r.mu.Lock()
+	r.state = ServiceStopped
+	r.mu.Unlock()
+	log.Printf("Service %s stopped", r.name)
+	r.events.publishServiceState(r.name, string(ServiceStopped), nil)
+}
+
+// failed records the failure of the service and reports it to the
+// supervisor.
+func (r *serviceRunner) failed(err error) {
+	r.mu.Lock()
+	r.state, r.lastError, r.lastErrorAt = ServiceFailed, err, time.Now()
+	onFailure := r.onFailure
+	r.mu.Unlock()
+	log.Printf("Service %s failed: %v", r.name, err)
+	r.events.publishServiceState(r.name, string(ServiceFailed), err)
+
+	if onFailure != nil {
+		onFailure(err)
+	}
+}
+
+// supervise registers the function called when the setup or the loop fails.
+func (r *serviceRunner) supervise(onFailure func(err error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFailure = onFailure
+}
+
+// running reports whether the loop runs.
+func (r *serviceRunner) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state == ServiceRunning
+}
+
+func (r *serviceRunner) status() ServiceStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := ServiceStatus{Name: r.name, State: r.state}
+	if r.state == ServiceRunning {
+		startedAt := r.startedAt
+		status.StartedAt = &startedAt
+		status.Uptime = time.Since(startedAt).Round(time.Second).String()
+	}
+	if r.lastError != nil {
+		lastErrorAt := r.lastErrorAt
+		status.LastError = r.lastError.Error()
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
+
+// supervisedService is a Service whose failures can be reported to the
+// ServiceManager.
+type supervisedService interface {
+	Service
+	lifecycle() *serviceRunner
+}
+
+// RestartPolicy configures the restart of the services failing to start or
+// while they run. The delay before a restart doubles from Backoff up to
+// MaxBackoff.
+type RestartPolicy struct {
+	// MaxAttempts is the number of restarts before giving up, 0 disables
+	// the restarts.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRestartPolicy is the restart policy of a new ServiceManager.
+var DefaultRestartPolicy = RestartPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: time.Minute}
+
+// minStableRun is the shortest run after which the restarts of a service are
+// counted again, whatever MaxBackoff.
+const minStableRun = time.Second
+
+// delay returns the delay before the given restart attempt, starting at 1.
+func (p RestartPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// restartState tracks the restarts of a failed service.
+type restartState struct {
+	attempts    int
+	restartedAt time.Time
+	next        time.Time
+	timer       *time.Timer
+}
+
+// ServiceManager starts and stops the background services by name, all
+// under the same parent context. It supervises them: a service failing to
+// start or while it runs is restarted according to the restart policy.
+type ServiceManager struct {
+	ctx      context.Context
+	services []Service
+	events   *EventBus
+
+	mu       sync.Mutex
+	policy   RestartPolicy
+	restarts map[string]*restartState
+}
+
+// NewServiceManager creates a new instance of the ServiceManager. The
+// services are listed, and stopped by StopAll, in the given order.
+func NewServiceManager(ctx context.Context, events *EventBus, services ...Service) *ServiceManager {
+	m := &ServiceManager{
+		ctx:      ctx,
+		services: services,
+		events:   events,
+		policy:   DefaultRestartPolicy,
+		restarts: make(map[string]*restartState),
+	}
+	for _, service := range services {
+		if supervised, ok := service.(supervisedService); ok {
+			service := service
+			supervised.lifecycle().supervise(func(err error) {
+				m.failed(service, err)
+			})
+		}
+	}
+	return m
+}
+
+// SetRestartPolicy changes the restart policy, from the next failure.
+func (m *ServiceManager) SetRestartPolicy(policy RestartPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = policy
+}
+
+// Get returns the service with the given name.
+func (m *ServiceManager) Get(name string) (Service, error) {
+	for _, service := range m.services {
+		if service.Name() == name {
+			return service, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+}
+
+// Start starts the service with the given name. A pending restart is
+// replaced by the start.
+func (m *ServiceManager) Start(name string) error {
+	service, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	m.cancelRestart(name)
+	return service.Start(m.ctx)
+}
+
+// Stop stops the service with the given name. Stopping a failed service
+// waiting to be restarted cancels the restart.
+func (m *ServiceManager) Stop(name string) error {
+	service, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	if m.cancelRestart(name) {
+		return nil
+	}
+	return service.Stop()
+}
+
+// Toggle starts the service with the given name if it does not run, and
+// stops it otherwise. It returns the new state.
+func (m *ServiceManager) Toggle(name string) (ServiceState, error) {
+	service, err := m.Get(name)
+	if err != nil {
+		return "", err
+	}
+	if service.Status().State == ServiceRunning {
+		if err := m.Stop(name); err != nil {
+			return "", err
+		}
+		return ServiceStopped, nil
+	}
+	if err := m.Start(name); err != nil {
+		return "", err
+	}
+	return ServiceRunning, nil
+}
+
+// Status returns the status of the service with the given name.
+func (m *ServiceManager) Status(name string) (ServiceStatus, error) {
+	service, err := m.Get(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	return m.status(service), nil
+}
+
+// Statuses returns the status of every service.
+func (m *ServiceManager) Statuses() []ServiceStatus {
+	statuses := make([]ServiceStatus, 0, len(m.services))
+	for _, service := range m.services {
+		statuses = append(statuses, m.status(service))
+	}
+	return statuses
+}
+
+// status returns the status of the service with its restarts.
+func (m *ServiceManager) status(service Service) ServiceStatus {
+	status := service.Status()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if restart, ok := m.restarts[service.Name()]; ok {
+		status.Restarts = restart.attempts
+		if restart.timer != nil {
+			next := restart.next
+			status.NextRestartAt = &next
+		}
+	}
+	return status
+}
+
+// StopAll cancels the pending restarts and stops the running services in
+// order.
+func (m *ServiceManager) StopAll() {
+	for _, service := range m.services {
+		m.cancelRestart(service.Name())
+		if err := service.Stop(); err != nil && !errors.Is(err, ErrServiceNotRunning) {
+			log.Printf("Failed to stop service %s: %v", service.Name(), err)
+		}
+	}
+}
+
+// failed schedules the restart of a service which failed, unless it failed
+// too many times in a row. The attempts are counted again once the service
+// ran for MaxBackoff, and at least minStableRun.
+func (m *ServiceManager) failed(service Service, err error) {
+	name := service.Name()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return
+	}
+	restart, ok := m.restarts[name]
+	if !ok || time.Since(restart.restartedAt) >= max(m.policy.MaxBackoff, minStableRun) {
+		restart = &restartState{}
+		m.restarts[name] = restart
+	}
+	if restart.attempts >= m.policy.MaxAttempts {
+		if m.policy.MaxAttempts > 0 {
+			log.Printf("Service %s failed %d times, giving up", name, restart.attempts+1)
+			m.events.publishServiceState(name, string(ServiceFailed), fmt.Errorf("giving up after %d restarts: %w", restart.attempts, err))
+		}
+		return
+	}
+
+	restart.attempts++
+	attempt := restart.attempts
+	delay := m.policy.delay(attempt)
+	restart.next = time.Now().Add(delay)
+	log.Printf("Restarting service %s in %s (attempt %d/%d)", name, delay, attempt, m.policy.MaxAttempts)
+	m.events.Publish(Event{Type: EventServiceState, Data: ServiceStateData{
+		Service: name,
+		State:   "restarting",
+		Error:   err.Error(),
+		Attempt: attempt,
+	}})
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		if restart.timer != timer || m.ctx.Err() != nil {
+			m.mu.Unlock()
+			return
+		}
+		restart.timer = nil
+		restart.restartedAt = time.Now()
+		m.mu.Unlock()
+
+		// Supervised services report their own setup failures.
+		err := service.Start(m.ctx)
+		if _, supervised := service.(supervisedService); !supervised && err != nil && !errors.Is(err, ErrServiceRunning) {
+			m.failed(service, err)
+		}
+	})
+	restart.timer = timer
+}
+
+// cancelRestart cancels the pending restart of a service and forgets its
+// failures. It reports whether a restart was pending.
+func (m *ServiceManager) cancelRestart(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restart, ok := m.restarts[name]
+	if !ok {
+		return false
+	}
+	delete(m.restarts, name)
+	if restart.timer == nil {
+		return false
+	}
+	restart.timer.Stop()
+	log.Printf("Restart of service %s cancelled", name)
+	return true
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// crashingService fails as soon as it starts.
+type crashingService struct {
+	runner *serviceRunner
+	starts atomic.Int32
+}
+
+func (cs *crashingService) Name() string { return "crashing" }
+
+func (cs *crashingService) Start(ctx context.Context) error {
+	cs.starts.Add(1)
+	return cs.runner.start(ctx, nil, func(context.Context) error {
+		return errors.New("crashed")
+	})
+}
+
+func (cs *crashingService) Stop() error { return cs.runner.stop() }
+
+func (cs *crashingService) Status() ServiceStatus { return cs.runner.status() }
+
+func (cs *crashingService) lifecycle() *serviceRunner { return cs.runner }
+
+// flakyService fails to start until its setup ran a number of times.
+type flakyService struct {
+	runner   *serviceRunner
+	failures int32
+	starts   atomic.Int32
+}
+
+func (fs *flakyService) Name() string { return "flaky" }
+
+func (fs *flakyService) Start(ctx context.Context) error {
+	return fs.runner.start(ctx, func(context.Context) error {
+		if fs.starts.Add(1) <= fs.failures {
+			return errors.New("not ready")
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+}
+
+func (fs *flakyService) Stop() error { return fs.runner.stop() }
+
+func (fs *flakyService) Status() ServiceStatus { return fs.runner.status() }
+
+func (fs *flakyService) lifecycle() *serviceRunner { return fs.runner }
+
+func TestServiceManagerRestartsServicesFailingToStart(t *testing.T) {
+	events := NewEventBus()
+	service := &flakyService{runner: newServiceRunner("flaky", events), failures: 2}
+	manager := NewServiceManager(context.Background(), events, service)
+	manager.SetRestartPolicy(RestartPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond})
+	defer manager.StopAll()
+	states := events.Subscribe(EventFilter{Types: []EventType{EventServiceState}})
+	defer states.Unsubscribe()
+
+	if err := manager.Start(service.Name()); err == nil {
+		t.Fatal("Start: no error for a failing setup")
+	}
+	var restarts []int
+	for {
+		data := nextEvent(t, states).Data.(ServiceStateData)
+		if data.State == "restarting" {
+			restarts = append(restarts, data.Attempt)
+		}
+		if data.State == string(ServiceRunning) {
+			break
+		}
+	}
+	if len(restarts) != 2 || restarts[0] != 1 || restarts[1] != 2 {
+		t.Fatalf("restart attempts %v, want 1 and 2", restarts)
+	}
+	if starts := service.starts.Load(); starts != 3 {
+		t.Fatalf("service started %d times, want 3", starts)
+	}
+	if status, _ := manager.Status(service.Name()); status.State != ServiceRunning || status.Restarts != 2 {
+		t.Fatalf("status = %+v, want running after 2 restarts", status)
+	}
+}
+
+func TestServiceManagerGivesUpAfterMaxAttempts(t *testing.T) {
+	for _, policy := range []RestartPolicy{
+		{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond},
+		// Without a delay, the failures still follow each other too fast
+		// to be counted again.
+		{MaxAttempts: 3},
+	} {
+		events := NewEventBus()
+		service := &crashingService{runner: newServiceRunner("crashing", events)}
+		manager := NewServiceManager(context.Background(), events, service)
+		manager.SetRestartPolicy(policy)
+		states := events.Subscribe(EventFilter{Types: []EventType{EventServiceState}})
+
+		if err := manager.Start(service.Name()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		for {
+			data := nextEvent(t, states).Data.(ServiceStateData)
+			if data.State == string(ServiceFailed) && strings.Contains(data.Error, "giving up") {
+				break
+			}
+		}
+		// No restart may follow.
+		time.Sleep(50 * time.Millisecond)
+		if starts := service.starts.Load(); starts != int32(policy.MaxAttempts)+1 {
+			t.Errorf("%+v: service started %d times, want %d", policy, starts, policy.MaxAttempts+1)
+		}
+		if state := service.Status().State; state != ServiceFailed {
+			t.Errorf("%+v: service is %s, want %s", policy, state, ServiceFailed)
+		}
+		states.Unsubscribe()
+		manager.StopAll()
+	}
+}
@@ -0,0 +1,687 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMacroNotFound  = errors.New("macro not found")
+	ErrMacroRecording = errors.New("a macro is already being recorded")
+	ErrNotRecording   = errors.New("no macro is being recorded")
+	ErrMacroPlaying   = errors.New("a macro is already playing")
+	ErrNotPlaying     = errors.New("no macro is playing")
+	ErrInvalidMacro   = errors.New("invalid macro")
+)
+
+// Kinds of macro steps.
+const (
+	MacroKeyDown   = "keyDown"
+	MacroKeyUp     = "keyUp"
+	MacroKeyTyped  = "keyTyped"
+	MacroMouseDown = "mouseDown"
+	MacroMouseUp   = "mouseUp"
+	// MacroClick is a left click, recorded by previous versions.
+	MacroClick = "click"
+)
+
+// MacroStep is a key event or a mouse button event of a macro.
+type MacroStep struct {
+	// DelayMs is the time since the previous step, in milliseconds.
+	DelayMs int    `json:"delayMs"`
+	Kind    string `json:"kind"`
+	// Keycode, Rawcode and Keychar are those of the key events recorded.
+	Keycode uint16 `json:"keycode,omitempty"`
+	Rawcode uint16 `json:"rawcode,omitempty"`
+	Keychar rune   `json:"keychar,omitempty"`
+	// Button is the button of the mouse events.
+	Button MouseButton `json:"button,omitempty"`
+	// Position is the position of the mouse events relative to the client
+	// area.
+	Position *RelativePoint `json:"position,omitempty"`
+}
+
+// Macro is a sequence of input events replayed on team windows.
+type Macro struct {
+	Name string `json:"name"`
+	// Window is the character the macro was recorded on.
+	Window     string      `json:"window,omitempty"`
+	RecordedAt time.Time   `json:"recordedAt"`
+	Steps      []MacroStep `json:"steps"`
+}
+
+// Duration returns the time the macro takes to play at normal speed.
+func (m Macro) Duration() time.Duration {
+	var total time.Duration
+	for _, step := range m.Steps {
+		total += time.Duration(step.DelayMs) * time.Millisecond
+	}
+	return total
+}
+
+// validate checks the name and the steps of the macro.
+func (m Macro) validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidMacro)
+	}
+	if len(m.Steps) == 0 {
+		return fmt.Errorf("%w: %s has no steps", ErrInvalidMacro, m.Name)
+	}
+	for i, step := range m.Steps {
+		if step.DelayMs < 0 {
+			return fmt.Errorf("%w: steps[%d]: negative delay", ErrInvalidMacro, i)
+		}
+		if (step.Kind == MacroMouseDown || step.Kind == MacroMouseUp) && (step.Button < MouseLeft || step.Button > MouseX2) {
+			return fmt.Errorf("%w: steps[%d]: unknown mouse button %d", ErrInvalidMacro, i, step.Button)
+		}
+		switch step.Kind {
+		case MacroKeyDown, MacroKeyUp, MacroKeyTyped:
+		case MacroClick, MacroMouseDown:
+			if step.Position == nil || !step.Position.Inside() {
+				return fmt.Errorf("%w: steps[%d]: click position outside the client area", ErrInvalidMacro, i)
+			}
+		case MacroMouseUp:
+			// The button may be released after the pointer left the window.
+			if step.Position == nil {
+				return fmt.Errorf("%w: steps[%d]: mouse position missing", ErrInvalidMacro, i)
+			}
+		default:
+			return fmt.Errorf("%w: steps[%d]: unknown kind %q", ErrInvalidMacro, i, step.Kind)
+		}
+	}
+	return nil
+}
+
+// MacroInfo summarizes a macro in listings.
+type MacroInfo struct {
+	Name       string `json:"name"`
+	Window     string `json:"window,omitempty"`
+	Steps      int    `json:"steps"`
+	DurationMs int    `json:"durationMs"`
+}
+
+// PlayOptions configures the playback of a macro.
+type PlayOptions struct {
+	// Windows lists the characters the macro is played on, the whole team
+	// when empty.
+	Windows []string `json:"windows"`
+	// Speed scales the playback, 2 playing twice as fast. Defaults to 1.
+	Speed float64 `json:"speed"`
+	// Repeat is the number of times the macro is played, at least once.
+	Repeat int `json:"repeat"`
+	// Loop plays the macro until the playback is stopped. Macros without
+	// delays between their steps cannot loop.
+	Loop bool `json:"loop"`
+}
+
+// Playback is the state of a macro playback.
+type Playback struct {
+	Macro     string    `json:"macro"`
+	Windows   []string  `json:"windows"`
+	Speed     float64   `json:"speed"`
+	Repeat    int       `json:"repeat"`
+	Loop      bool      `json:"loop"`
+	Iteration int       `json:"iteration"`
+	StartedAt time.Time `json:"startedAt"`
+	// Cancelled and Error report how a finished playback ended.
+	Cancelled bool   `json:"cancelled,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// macroRecording is a recording in progress. It is owned by the recording
+// goroutine until done is closed.
+type macroRecording struct {
+	macro        Macro
+	subscription *InputSubscription
+	last         time.Time
+	held         map[uint16]bool
+	// pressed holds the button presses until their release.
+	pressed map[MouseButton]InputEvent
+	done    chan struct{}
+}
+
+// MacroService records input events into named macros, stored in a JSON
+// file, and replays them on team windows in the background.
+type MacroService struct {
+	windowService *WindowService
+	characterSvc  *CharacterService
+	input         *InputDispatcher
+	events        *EventBus
+	path          string
+
+	mu             sync.Mutex
+	macros         map[string]Macro // keyed by lower-cased name
+	recording      *macroRecording
+	playback       *Playback
+	cancelPlayback context.CancelFunc
+	playbackDone   chan struct{}
+}
+
+// NewMacroService creates a new instance of the MacroService, storing the
+// macros in the file at path.
+func NewMacroService(ws *WindowService, cs *CharacterService, input *InputDispatcher, events *EventBus, path string) *MacroService {
+	return &MacroService{
+		windowService: ws,
+		characterSvc:  cs,
+		input:         input,
+		events:        events,
+		path:          path,
+		macros:        make(map[string]Macro),
+	}
+}
+
+// Load reads the macro file. A missing file is not an error.
+func (ms *MacroService) Load() error {
+	data, err := os.ReadFile(ms.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var macros []Macro
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", ms.path, err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, macro := range macros {
+		if err := macro.validate(); err != nil {
+			log.Printf("Skipping macro %q from %s: %v", macro.Name, ms.path, err)
+			continue
+		}
+		ms.macros[strings.ToLower(macro.Name)] = macro
+	}
+	return nil
+}
+
+// save writes the macro file. It must be called with the mutex held.
+func (ms *MacroService) save() error {
+	macros := make([]Macro, 0, len(ms.macros))
+	for _, macro := range ms.macros {
+		macros = append(macros, macro)
+	}
+	sort.Slice(macros, func(i, j int) bool {
+		return macros[i].Name < macros[j].Name
+	})
+
+	data, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ms.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ms.path)
+}
+
+// Macros returns a summary of the stored macros ordered by name.
+func (ms *MacroService) Macros() []MacroInfo {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	infos := make([]MacroInfo, 0, len(ms.macros))
+	for _, macro := range ms.macros {
+		infos = append(infos, MacroInfo{
+			Name:       macro.Name,
+			Window:     macro.Window,
+			Steps:      len(macro.Steps),
+			DurationMs: int(macro.Duration() / time.Millisecond),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// GetMacro returns the macro with the given name.
+func (ms *MacroService) GetMacro(name string) (Macro, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	macro, ok := ms.macros[strings.ToLower(name)]
+	if !ok {
+		return Macro{}, fmt.Errorf("%w: %s", ErrMacroNotFound, name)
+	}
+	return macro, nil
+}
+
+// SaveMacro stores a macro, replacing the one with the same name.
+func (ms *MacroService) SaveMacro(macro Macro) error {
+	macro.Name = strings.TrimSpace(macro.Name)
+	if err := macro.validate(); err != nil {
+		return err
+	}
+	if macro.RecordedAt.IsZero() {
+		macro.RecordedAt = time.Now()
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.macros[strings.ToLower(macro.Name)] = macro
+	return ms.save()
+}
+
+// DeleteMacro removes the macro with the given name.
+func (ms *MacroService) DeleteMacro(name string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if _, ok := ms.macros[key]; !ok {
+		return fmt.Errorf("%w: %s", ErrMacroNotFound, name)
+	}
+	delete(ms.macros, key)
+	return ms.save()
+}
+
+// StartRecording records the key events and mouse button events into a
+// macro with the given name, until StopRecording is called.
+func (ms *MacroService) StartRecording(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidMacro)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.recording != nil {
+		return fmt.Errorf("%w: %s", ErrMacroRecording, ms.recording.macro.Name)
+	}
+	recording := &macroRecording{
+		macro:        Macro{Name: name, RecordedAt: time.Now(), Steps: []MacroStep{}},
+		subscription: ms.input.Subscribe(InputFilter{Kinds: InputKeyEvents | InputMouseDown | InputMouseUp}),
+		last:         time.Now(),
+		held:         make(map[uint16]bool),
+		pressed:      make(map[MouseButton]InputEvent),
+		done:         make(chan struct{}),
+	}
+	ms.recording = recording
+
+	go func() {
+		defer close(recording.done)
+		for ev := range recording.subscription.C {
+			ms.record(recording, ev)
+		}
+	}()
+	log.Printf("Recording macro %s", name)
+	ms.events.Publish(Event{Type: EventMacroRecording, Data: MacroInfo{Name: name}})
+	return nil
+}
+
+// record adds an input event to the recording. Only complete key presses
+// are kept, so that the hotkeys starting and stopping the recording are not.
+// Button presses are recorded with their release, once the window clicked
+// is in the foreground: positions are relative to its client area.
+func (ms *MacroService) record(recording *macroRecording, ev InputEvent) {
+	when := ev.When
+	if when.IsZero() {
+		when = time.Now()
+	}
+	step := MacroStep{DelayMs: int(when.Sub(recording.last) / time.Millisecond)}
+	if step.DelayMs < 0 {
+		step.DelayMs = 0
+	}
+
+	switch ev.Kind {
+	case InputKeyDown:
+		recording.held[ev.Keycode] = true
+		step.Kind, step.Keycode, step.Rawcode = MacroKeyDown, ev.Keycode, ev.Rawcode
+	case InputKeyUp:
+		if !recording.held[ev.Keycode] {
+			return
+		}
+		delete(recording.held, ev.Keycode)
+		step.Kind, step.Keycode, step.Rawcode = MacroKeyUp, ev.Keycode, ev.Rawcode
+	case InputKeyTyped:
+		if len(recording.held) == 0 {
+			return
+		}
+		step.Kind, step.Keychar = MacroKeyTyped, ev.Keychar
+	case InputMouseDown:
+		ev.When = when
+		recording.pressed[ev.Button] = ev
+		return
+	case InputMouseUp:
+		press, ok := recording.pressed[ev.Button]
+		if !ok {
+			return
+		}
+		delete(recording.pressed, ev.Button)
+
+		foreground := ms.windowService.GetForegroundWindow()
+		down, err := ms.windowService.ScreenToRelative(foreground, press.X, press.Y)
+		if err != nil || !down.Inside() {
+			log.Printf("Click at X=%d, Y=%d not recorded, it is outside the foreground window", press.X, press.Y)
+			return
+		}
+		up, err := ms.windowService.ScreenToRelative(foreground, ev.X, ev.Y)
+		if err != nil {
+			log.Printf("Button release at X=%d, Y=%d not recorded: %v", ev.X, ev.Y, err)
+			return
+		}
+		delay := max(int(press.When.Sub(recording.last)/time.Millisecond), 0)
+		recording.macro.Steps = append(recording.macro.Steps, MacroStep{DelayMs: delay, Kind: MacroMouseDown, Button: press.Button, Position: &down})
+		step.DelayMs = max(step.DelayMs-delay, 0)
+		step.Kind, step.Button, step.Position = MacroMouseUp, ev.Button, &up
+	default:
+		return
+	}
+
+	if recording.macro.Window == "" {
+		if character, ok := findOnline(ms.characterSvc.GetCharacters(), ms.windowService.GetForegroundWindow()); ok {
+			recording.macro.Window = character.Name
+		}
+	}
+	recording.macro.Steps = append(recording.macro.Steps, step)
+	recording.last = when
+}
+
+// StopRecording ends the recording and stores the macro.
+func (ms *MacroService) StopRecording() (Macro, error) {
+	ms.mu.Lock()
+	recording := ms.recording
+	ms.recording = nil
+	ms.mu.Unlock()
+
+	if recording == nil {
+		return Macro{}, ErrNotRecording
+	}
+	recording.subscription.Unsubscribe()
+	<-recording.done
+
+	macro := recording.macro
+	macro.Steps = trimHeldKeys(macro.Steps, recording.held)
+	if err := ms.SaveMacro(macro); err != nil {
+		return Macro{}, err
+	}
+	info := MacroInfo{Name: macro.Name, Window: macro.Window, Steps: len(macro.Steps), DurationMs: int(macro.Duration() / time.Millisecond)}
+	log.Printf("Recorded macro %s: %d steps in %s", macro.Name, info.Steps, macro.Duration())
+	ms.events.Publish(Event{Type: EventMacroRecorded, Data: info})
+	return macro, nil
+}
+
+// trimHeldKeys removes the presses of the keys still held when the
+// recording stopped, and the characters they typed.
+func trimHeldKeys(steps []MacroStep, held map[uint16]bool) []MacroStep {
+	if len(held) == 0 {
+		return steps
+	}
+
+	trimmed := make([]MacroStep, 0, len(steps))
+	delay, dropping := 0, false
+	for i, step := range steps {
+		switch {
+		case step.Kind == MacroKeyDown && held[step.Keycode] && !releasedAfter(steps[i+1:], step.Keycode):
+			dropping = true
+		case step.Kind == MacroKeyTyped && dropping:
+		default:
+			dropping = false
+			step.DelayMs += delay
+			delay = 0
+			trimmed = append(trimmed, step)
+			continue
+		}
+		delay += step.DelayMs
+	}
+	return trimmed
+}
+
+func releasedAfter(steps []MacroStep, keycode uint16) bool {
+	for _, step := range steps {
+		if step.Kind == MacroKeyUp && step.Keycode == keycode {
+			return true
+		}
+	}
+	return false
+}
+
+// Recording returns the name of the macro being recorded, if any.
+func (ms *MacroService) Recording() (string, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.recording == nil {
+		return "", false
+	}
+	return ms.recording.macro.Name, true
+}
+
+// Play replays a macro in the background on the client windows of the given
+// characters, or of the whole team. The windows are not activated.
+func (ms *MacroService) Play(name string, options PlayOptions) (Playback, error) {
+	macro, err := ms.GetMacro(name)
+	if err != nil {
+		return Playback{}, err
+	}
+	// Without delays, a looping playback would never yield.
+	if options.Loop && macro.Duration() == 0 {
+		return Playback{}, fmt.Errorf("%w: %s has no delay between its steps and cannot loop", ErrInvalidMacro, macro.Name)
+	}
+	targets, err := ms.targets(options.Windows)
+	if err != nil {
+		return Playback{}, err
+	}
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+	if options.Repeat < 1 {
+		options.Repeat = 1
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.playback != nil {
+		return Playback{}, fmt.Errorf("%w: %s", ErrMacroPlaying, ms.playback.Macro)
+	}
+	playback := &Playback{
+		Macro:     macro.Name,
+		Speed:     options.Speed,
+		Repeat:    options.Repeat,
+		Loop:      options.Loop,
+		StartedAt: time.Now(),
+	}
+	for _, target := range targets {
+		playback.Windows = append(playback.Windows, target.Name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	ms.playback, ms.cancelPlayback, ms.playbackDone = playback, cancel, done
+
+	go func() {
+		defer close(done)
+		err := ms.play(ctx, macro, targets, playback)
+
+		ms.mu.Lock()
+		ended := *playback
+		ms.playback, ms.cancelPlayback, ms.playbackDone = nil, nil, nil
+		ms.mu.Unlock()
+		cancel()
+
+		ended.Cancelled = errors.Is(err, context.Canceled)
+		if err != nil && !ended.Cancelled {
+			ended.Error = err.Error()
+		}
+		log.Printf("Macro %s played %d time(s)", ended.Macro, ended.Iteration)
+		ms.events.Publish(Event{Type: EventMacroPlayed, Data: ended})
+	}()
+
+	log.Printf("Playing macro %s on %s", macro.Name, strings.Join(playback.Windows, ", "))
+	ms.events.Publish(Event{Type: EventMacroPlaying, Data: *playback})
+	return *playback, nil
+}
+
+// targets returns the online characters designated by names, or the whole
+// team when none is given.
+func (ms *MacroService) targets(names []string) ([]Character, error) {
+	if err := ms.characterSvc.Refresh(); err != nil {
+		return nil, err
+	}
+
+	var targets []Character
+	if len(names) == 0 {
+		for _, character := range ms.characterSvc.GetCharacters() {
+			if character.Online {
+				targets = append(targets, character)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("%w: no team member is online", ErrCharacterOffline)
+		}
+		return targets, nil
+	}
+
+	for _, name := range names {
+		character, err := ms.characterSvc.GetCharacter(name)
+		if err != nil {
+			return nil, err
+		}
+		if !character.Online {
+			return nil, fmt.Errorf("%w: %s", ErrCharacterOffline, character.Name)
+		}
+		targets = append(targets, character)
+	}
+	return targets, nil
+}
+
+// play replays the steps until the repetitions are done or ctx is cancelled.
+// Keys and buttons still held down when it ends are released.
+func (ms *MacroService) play(ctx context.Context, macro Macro, targets []Character, playback *Playback) error {
+	held := make(map[uint16]InputEvent)
+	pressed := make(map[MouseButton]*RelativePoint)
+	defer func() {
+		for _, ev := range held {
+			ev.Kind = InputKeyUp
+			ms.deliver(targets, ev, nil)
+		}
+		for button, position := range pressed {
+			ms.deliver(targets, InputEvent{Kind: InputMouseUp, Button: button}, position)
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+
+	for iteration := 1; playback.Loop || iteration <= playback.Repeat; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ms.mu.Lock()
+		playback.Iteration = iteration
+		ms.mu.Unlock()
+
+		for _, step := range macro.Steps {
+			timer.Reset(time.Duration(float64(step.DelayMs) * float64(time.Millisecond) / playback.Speed))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+
+			ev := InputEvent{Keycode: step.Keycode, Rawcode: step.Rawcode, Keychar: step.Keychar, Button: step.Button}
+			switch step.Kind {
+			case MacroKeyDown:
+				ev.Kind = InputKeyDown
+				held[step.Keycode] = ev
+			case MacroKeyUp:
+				ev.Kind = InputKeyUp
+				delete(held, step.Keycode)
+			case MacroKeyTyped:
+				ev.Kind = InputKeyTyped
+			case MacroMouseDown:
+				ev.Kind = InputMouseDown
+				pressed[step.Button] = step.Position
+			case MacroMouseUp:
+				ev.Kind = InputMouseUp
+				delete(pressed, step.Button)
+			case MacroClick:
+				ev.Kind = InputMouseClick
+			}
+			ms.deliver(targets, ev, step.Position)
+		}
+	}
+	return nil
+}
+
+// deliver sends a key event, or a mouse event at position, to the target
+// windows.
+func (ms *MacroService) deliver(targets []Character, ev InputEvent, position *RelativePoint) {
+	backend := ms.windowService.Backend()
+	for _, target := range targets {
+		var err error
+		switch ev.Kind {
+		case InputMouseClick, InputMouseDown, InputMouseUp:
+			var x, y int
+			if x, y, err = ms.windowService.RelativeToClient(target.Handle, *position); err != nil {
+				break
+			}
+			if ev.Kind == InputMouseClick {
+				err = backend.PostClick(target.Handle, x, y)
+			} else {
+				err = backend.PostMouse(target.Handle, ev, x, y)
+			}
+		default:
+			err = backend.PostKey(target.Handle, ev)
+		}
+		if err != nil {
+			log.Printf("Failed to replay %s on %s: %v", ev.Kind, target.Name, err)
+		}
+	}
+}
+
+// PlaybackStatus returns the playback in progress, if any.
+func (ms *MacroService) PlaybackStatus() (Playback, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.playback == nil {
+		return Playback{}, false
+	}
+	return *ms.playback, true
+}
+
+// StopPlayback cancels the playback in progress and waits for it to end.
+func (ms *MacroService) StopPlayback() error {
+	ms.mu.Lock()
+	cancel, done := ms.cancelPlayback, ms.playbackDone
+	ms.mu.Unlock()
+
+	if cancel == nil {
+		return ErrNotPlaying
+	}
+	cancel()
+	<-done
+	return nil
+}
+
+// Close cancels the playback and the recording in progress. The recording
+// is discarded.
+func (ms *MacroService) Close() {
+	if err := ms.StopPlayback(); err != nil && !errors.Is(err, ErrNotPlaying) {
+		log.Printf("Failed to stop the macro playback: %v", err)
+	}
+
+	ms.mu.Lock()
+	recording := ms.recording
+	ms.recording = nil
+	ms.mu.Unlock()
+	if recording != nil {
+		recording.subscription.Unsubscribe()
+		<-recording.done
+	}
+}
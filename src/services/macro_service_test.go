@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newMacroService stores the macros of the team in a temporary directory.
+// Input events are dispatched by the test, the hook is not installed.
+func newMacroService(t *testing.T, names ...string) (*FakeWindowBackend, *MacroService, *InputDispatcher, *EventBus, []WindowHandle) {
+	t.Helper()
+	fake, characters, handles := newFakeTeam(t, names...)
+	input := NewInputDispatcher()
+	input.started = true
+	events := NewEventBus()
+	ms := NewMacroService(characters.windowService, characters, input, events, filepath.Join(t.TempDir(), "macros.json"))
+	t.Cleanup(ms.Close)
+	return fake, ms, input, events, handles
+}
+
+func TestMacroWithoutStepsIsInvalid(t *testing.T) {
+	_, ms, _, _, _ := newMacroService(t, "Alpha")
+	if err := ms.SaveMacro(Macro{Name: "empty"}); !errors.Is(err, ErrInvalidMacro) {
+		t.Fatalf("SaveMacro: got %v, want ErrInvalidMacro", err)
+	}
+}
+
+func TestLoopingPlaybackStops(t *testing.T) {
+	fake, ms, _, _, _ := newMacroService(t, "Alpha")
+	err := ms.SaveMacro(Macro{Name: "loop", Steps: []MacroStep{{Kind: MacroKeyTyped, Keychar: 'a', DelayMs: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.Play("loop", PlayOptions{Loop: true}); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for playback, _ := ms.PlaybackStatus(); playback.Iteration < 3; playback, _ = ms.PlaybackStatus() {
+		if time.Now().After(deadline) {
+			t.Fatalf("looping playback at iteration %d, want it to go on", playback.Iteration)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- ms.StopPlayback() }()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("StopPlayback: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("looping playback did not stop")
+	}
+	// The third iteration may be stopped before its key is sent.
+	if len(fake.Keys()) < 2 {
+		t.Fatalf("%d keys sent, want one per iteration", len(fake.Keys()))
+	}
+}
+
+func TestMacrosWithoutDelaysCannotLoop(t *testing.T) {
+	fake, ms, _, _, _ := newMacroService(t, "Alpha")
+	if err := ms.SaveMacro(Macro{Name: "burst", Steps: []MacroStep{{Kind: MacroKeyTyped, Keychar: 'a'}}}); err != nil {
+		t.Fatal(err)
+	}
+	// Macros stored by previous versions may have no steps.
+	ms.macros["empty"] = Macro{Name: "empty"}
+
+	for _, name := range []string{"burst", "empty"} {
+		if _, err := ms.Play(name, PlayOptions{Loop: true}); !errors.Is(err, ErrInvalidMacro) {
+			t.Fatalf("Play(%s) in a loop: got %v, want ErrInvalidMacro", name, err)
+		}
+		if playback, playing := ms.PlaybackStatus(); playing {
+			t.Fatalf("Play(%s) in a loop started %+v", name, playback)
+		}
+	}
+
+	// They are still played a finite number of times.
+	if _, err := ms.Play("burst", PlayOptions{Repeat: 3}); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.Keys()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d keys sent, want 3", len(fake.Keys()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMacroRecordsEveryMouseButton(t *testing.T) {
+	fake, ms, input, events, handles := newMacroService(t, "Alpha", "Beta")
+	if err := fake.SetForegroundWindow(handles[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.StartRecording("buttons"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i, ev := range []InputEvent{
+		{Kind: InputMouseDown, Button: MouseRight, X: 400, Y: 300},
+		{Kind: InputMouseUp, Button: MouseRight, X: 400, Y: 300},
+		{Kind: InputMouseDown, Button: MouseMiddle, X: 100, Y: 50},
+		{Kind: InputMouseUp, Button: MouseMiddle, X: 120, Y: 60},
+		// Released after the recording stopped.
+		{Kind: InputMouseDown, Button: MouseLeft, X: 10, Y: 10},
+	} {
+		ev.When = start.Add(time.Duration(i) * 10 * time.Millisecond)
+		input.dispatch(ev)
+	}
+	macro, err := ms.StopRecording()
+	if err != nil {
+		t.Fatalf("StopRecording: %v", err)
+	}
+
+	want := []MacroStep{
+		{Kind: MacroMouseDown, Button: MouseRight},
+		{Kind: MacroMouseUp, Button: MouseRight},
+		{Kind: MacroMouseDown, Button: MouseMiddle},
+		{Kind: MacroMouseUp, Button: MouseMiddle},
+	}
+	if len(macro.Steps) != len(want) {
+		t.Fatalf("steps = %+v, want %+v", macro.Steps, want)
+	}
+	for i, step := range macro.Steps {
+		if step.Kind != want[i].Kind || step.Button != want[i].Button || step.Position == nil {
+			t.Fatalf("step #%d = %+v, want %+v with a position", i, step, want[i])
+		}
+	}
+
+	played := events.Subscribe(EventFilter{Types: []EventType{EventMacroPlayed}})
+	defer played.Unsubscribe()
+	if _, err := ms.Play("buttons", PlayOptions{Windows: []string{"Beta"}}); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	nextEvent(t, played)
+
+	wantClicks := []FakeClick{
+		{Window: handles[1], X: 400, Y: 300, Posted: true, Kind: InputMouseDown, Button: MouseRight},
+		{Window: handles[1], X: 400, Y: 300, Posted: true, Kind: InputMouseUp, Button: MouseRight},
+		{Window: handles[1], X: 100, Y: 50, Posted: true, Kind: InputMouseDown, Button: MouseMiddle},
+		{Window: handles[1], X: 120, Y: 60, Posted: true, Kind: InputMouseUp, Button: MouseMiddle},
+	}
+	clicks := fake.Clicks()
+	if len(clicks) != len(wantClicks) {
+		t.Fatalf("clicks = %+v, want %+v", clicks, wantClicks)
+	}
+	for i := range wantClicks {
+		if clicks[i] != wantClicks[i] {
+			t.Errorf("click #%d = %+v, want %+v", i, clicks[i], wantClicks[i])
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetMessage                = user32.NewProc("GetMessageW")
+	procTranslateMessage          = user32.NewProc("TranslateMessage")
+	procDispatchMessage           = user32.NewProc("DispatchMessageW")
+	procRegisterShellHookWindow   = user32.NewProc("RegisterShellHookWindow")
+	procDeregisterShellHookWindow = user32.NewProc("DeregisterShellHookWindow")
+	procRegisterWindowMessage     = user32.NewProc("RegisterWindowMessageW")
+	procPostQuitMessage           = user32.NewProc("PostQuitMessage")
+	procPostMessage               = user32.NewProc("PostMessageW")
+	procRegisterClassEx           = user32.NewProc("RegisterClassExW")
+	procCreateWindowEx            = user32.NewProc("CreateWindowExW")
+	procDefWindowProc             = user32.NewProc("DefWindowProcW")
+	procDestroyWindow             = user32.NewProc("DestroyWindow")
+)
+
+// Constants for Windows messages and shell hook messages
+const (
+	WM_DESTROY = 0x0002
+	WM_CLOSE   = 0x0010
+
+	HSHELL_HIGHBIT = 0x8000
+
+	HSHELL_WINDOWCREATED       = 1
+	HSHELL_WINDOWDESTROYED     = 2
+	HSHELL_ACTIVATESHELLWINDOW = 3
+	HSHELL_WINDOWACTIVATED     = 4
+	HSHELL_GETMINRECT          = 5
+	HSHELL_REDRAW              = 6
+	HSHELL_FLASH               = HSHELL_REDRAW | HSHELL_HIGHBIT
+)
+
+// MSG structure for Windows messages
+type MSG struct {
+	HWnd    windows.Handle
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      POINT
+}
+
+type POINT struct {
+	X, Y int32
+}
+
+type WNDCLASSEX struct {
+	CbSize        uint32
+	Style         uint32
+	LpfnWndProc   uintptr
+	CbClsExtra    int32
+	CbWndExtra    int32
+	HInstance     windows.Handle
+	HIcon         windows.Handle
+	HCursor       windows.Handle
+	HbrBackground windows.Handle
+	LpszMenuName  *uint16
+	LpszClassName *uint16
+	HIconSm       windows.Handle
+}
+
+// watchShellEvents creates a message-only window registered as shell hook
+// window and forwards the shell notifications it receives until stop is closed.
+func watchShellEvents(stop <-chan struct{}) (<-chan ShellEvent, error) {
+	// Register WM_SHELLHOOKMESSAGE
+	wmShellHookMsg, err := registerWindowMessage("SHELLHOOK")
+	if err != nil || wmShellHookMsg == 0 {
+		return nil, fmt.Errorf("failed to register WM_SHELLHOOKMESSAGE: %v", err)
+	}
+	log.Printf("Registered WM_SHELLHOOKMESSAGE: %d", wmShellHookMsg)
+
+	events := make(chan ShellEvent, 64)
+	ready := make(chan error, 1)
+	var hwnd windows.HWND
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(events)
+
+		// Create message-only window
+		var err error
+		hwnd, err = createMessageOnlyWindow()
+		if err != nil {
+			ready <- fmt.Errorf("failed to create message-only window: %v", err)
+			return
+		}
+
+		// Register shell hook window
+		log.Printf("Registering shell hook window for HWND: %d", hwnd)
+		if err := registerShellHookWindow(hwnd); err != nil {
+			destroyMessageOnlyWindow(hwnd)
+			ready <- fmt.Errorf("failed to register shell hook window: %v", err)
+			return
+		}
+		log.Printf("Shell hook window successfully registered for HWND: %d", hwnd)
+		ready <- nil
+
+		var msg MSG
+		log.Println("Starting to monitor window messages")
+
+		for {
+			ret, err := getMessage(&msg)
+			if ret == 0 {
+				log.Println("WM_QUIT received, exiting message loop.")
+				return
+			} else if ret == -1 {
+				log.Printf("GetMessage returned an error: %v", err)
+				continue
+			}
+
+			if msg.Message == wmShellHookMsg {
+				if event, ok := toShellEvent(msg.WParam, msg.LParam); ok {
+					select {
+					case events <- event:
+					default:
+						log.Printf("Shell event dropped, consumer is too slow: %+v", event)
+					}
+				}
+			}
+
+			translateMessage(&msg)
+			dispatchMessage(&msg)
+		}
+	}()
+
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+
+	// Closing the window from another thread ends the message loop through
+	// WM_DESTROY, since PostQuitMessage only targets the calling thread.
+	go func() {
+		<-stop
+		postMessage(hwnd, WM_CLOSE, 0, 0)
+	}()
+
+	return events, nil
+}
+
+// toShellEvent converts WM_SHELLHOOKMESSAGE parameters to a ShellEvent.
+func toShellEvent(wParam, lParam uintptr) (ShellEvent, bool) {
+	event := ShellEvent{Window: WindowHandle(lParam)}
+	// HSHELL_FLASH is a redraw with HSHELL_HIGHBIT set, the other codes
+	// carry it when a full-screen window is open.
+	if wParam == HSHELL_FLASH {
+		event.Code = ShellWindowFlash
+		return event, true
+	}
+
+	// Extraire le code du message en masquant HSHELL_HIGHBIT
+	messageCode := uint32(wParam & ^uintptr(HSHELL_HIGHBIT))
+	switch messageCode {
+	case HSHELL_WINDOWCREATED:
+		event.Code = ShellWindowCreated
+	case HSHELL_WINDOWDESTROYED:
+		event.Code = ShellWindowDestroyed
+	case HSHELL_WINDOWACTIVATED:
+		event.Code = ShellWindowActivated
+	case HSHELL_REDRAW:
+		event.Code = ShellWindowRedraw
+	default:
+		return ShellEvent{}, false
+	}
+	return event, true
+}
+
+// Helper function to register a window message
+func registerWindowMessage(lpString string) (uint32, error) {
+	ret, _, err := procRegisterWindowMessage.Call(uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(lpString))))
+	if ret == 0 {
+		if err == nil {
+			return 0, windows.GetLastError()
+		}
+		return 0, err
+	}
+	return uint32(ret), nil
+}
+
+// Helper function to register the shell hook window
+func registerShellHookWindow(hwnd windows.HWND) error {
+	ret, _, err := procRegisterShellHookWindow.Call(uintptr(hwnd))
+	if ret == 0 {
+		if err == nil {
+			return windows.GetLastError()
+		}
+		return err
+	}
+	return nil
+}
+
+// Helper function to unregister the shell hook window
+func deregisterShellHookWindow(hwnd windows.HWND) {
+	procDeregisterShellHookWindow.Call(uintptr(hwnd))
+}
+
+// Helper function to post a quit message to the message loop
+func postQuitMessage(exitCode int32) {
+	procPostQuitMessage.Call(uintptr(exitCode))
+}
+
+// Helper function to post a message to a window's queue
+func postMessage(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) {
+	procPostMessage.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+}
+
+// Implementing GetMessage
+func getMessage(msg *MSG) (int32, error) {
+	ret, _, err := procGetMessage.Call(
+		uintptr(unsafe.Pointer(msg)),
+		0,
+		0,
+		0,
+	)
+	if ret == 0 {
+		return 0, nil // WM_QUIT received
+	}
+	if ret == ^uintptr(0) { // -1 cast to uintptr
+		return -1, err
+	}
+	return int32(ret), nil
+}
+
+// Implementing TranslateMessage
+func translateMessage(msg *MSG) {
+	procTranslateMessage.Call(uintptr(unsafe.Pointer(msg)))
+}
+
+// Implementing DispatchMessage
+func dispatchMessage(msg *MSG) {
+	procDispatchMessage.Call(uintptr(unsafe.Pointer(msg)))
+}
+
+// messageOnlyWndProcCallback is created once, callbacks are a limited resource.
+var messageOnlyWndProcCallback = syscall.NewCallback(messageOnlyWndProc)
+
+// Create a message-only window
+func createMessageOnlyWindow() (windows.HWND, error) {
+	var className = windows.StringToUTF16Ptr("MessageOnlyWindowClass")
+
+	var wcex WNDCLASSEX
+	wcex.CbSize = uint32(unsafe.Sizeof(wcex))
+	wcex.LpfnWndProc = messageOnlyWndProcCallback
+	wcex.HInstance = windows.Handle(0)
+	wcex.LpszClassName = className
+
+	// The class survives a previous run of the service.
+	atom, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wcex)))
+	if atom == 0 && !errors.Is(err, windows.ERROR_CLASS_ALREADY_EXISTS) {
+		return 0, err
+	}
+
+	hwnd, _, err := procCreateWindowEx.Call(
+		0,
+		uintptr(unsafe.Pointer(className)),
+		0,
+		0,
+		0,
+		0,
+		0,
+		0,
+		uintptr(HWND_MESSAGE), // Parent window
+		0,
+		0,
+		0,
+	)
+	if hwnd == 0 {
+		return 0, err
+	}
+
+	return windows.HWND(hwnd), nil
+}
+
+// destroyMessageOnlyWindow destroys a message-only window created by the
+// calling thread before its message loop runs. The WM_QUIT posted on
+// WM_DESTROY is consumed, so that the thread does not carry it over to the
+// next message loop it runs.
+func destroyMessageOnlyWindow(hwnd windows.HWND) {
+	if ret, _, err := procDestroyWindow.Call(uintptr(hwnd)); ret == 0 {
+		log.Printf("Failed to destroy message-only window %d: %v", hwnd, err)
+		return
+	}
+	var msg MSG
+	getMessage(&msg)
+}
+
+// Window procedure for the message-only window
+func messageOnlyWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case WM_DESTROY:
+		// Stop receiving shell notifications before the window goes away.
+		deregisterShellHookWindow(hwnd)
+		postQuitMessage(0)
+		return 0
+	default:
+		ret, _, _ := procDefWindowProc.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+		return ret
+	}
+}
+
+// Constants
+const (
+	HWND_MESSAGE = windows.Handle(^uintptr(2)) // Define HWND_MESSAGE as (HWND)-3
+)
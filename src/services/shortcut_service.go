@@ -1,83 +1,308 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+)
 
-	hook "github.com/robotn/gohook"
+var (
+	ErrShortcutNotFound = errors.New("shortcut not found")
+	ErrShortcutExists   = errors.New("key already bound to a shortcut")
+	ErrUnknownAction    = errors.New("unknown shortcut action")
 )
 
+// ShortcutActionFocus is the default shortcut action, focusing WindowName.
+const ShortcutActionFocus = "focus"
+
+// Shortcut is a hotkey running an action, by default bringing a window to
+// the foreground. Key is a key combination such as "F1", "Ctrl+Alt+F3" or
+// "Numpad4".
 type Shortcut struct {
-	ID         int
-	Key        string
-	WindowName string
+	ID         int    `json:"id"`
+	Key        string `json:"key"`
+	Action     string `json:"action,omitempty"`
+	WindowName string `json:"windowName,omitempty"`
+	// Argument is passed to the action, e.g. the name of a macro.
+	Argument string `json:"argument,omitempty"`
+
+	combo KeyCombo
 }
 
+// ShortcutService keeps the registered shortcuts, keyed by ID, and listens
+// for their keys while it runs.
 type ShortcutService struct {
 	mu            sync.Mutex
-	shortcut      Shortcut
+	shortcuts     map[int]Shortcut
+	nextID        int
+	runner        *serviceRunner
 	windowService *WindowService
+	input         *InputDispatcher
+	events        *EventBus
+	actions       map[string]func(argument string) error
+	onChange      func([]Shortcut)
+}
+
+func NewShortcutService(windowService *WindowService, input *InputDispatcher, events *EventBus) *ShortcutService {
+	return &ShortcutService{
+		shortcuts:     make(map[int]Shortcut),
+		nextID:        1,
+		windowService: windowService,
+		input:         input,
+		events:        events,
+		actions:       make(map[string]func(argument string) error),
+		runner:        newServiceRunner("shortcuts", events),
+	}
+}
+
+// Name identifies the service.
+func (ss *ShortcutService) Name() string {
+	return "shortcuts"
+}
+
+// Start listens for the keys of the shortcuts until Stop is called or ctx is
+// cancelled.
+func (ss *ShortcutService) Start(ctx context.Context) error {
+	return ss.runner.start(ctx, nil, ss.listenForKeys)
+}
+
+// Stop stops listening for the keys of the shortcuts.
+func (ss *ShortcutService) Stop() error {
+	return ss.runner.stop()
+}
+
+// Status reports the state of the service.
+func (ss *ShortcutService) Status() ServiceStatus {
+	return ss.runner.status()
+}
+
+func (ss *ShortcutService) lifecycle() *serviceRunner {
+	return ss.runner
+}
+
+// RegisterAction makes an action available to shortcuts under the given
+// name. Actions must be registered before the shortcuts using them, and
+// receive the argument of the shortcut.
+func (ss *ShortcutService) RegisterAction(name string, run func(argument string) error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.actions[name] = run
 }
 
-func NewShortcutService() *ShortcutService {
-	return &ShortcutService{}
+// SetChangeHandler registers a function called with the registered shortcuts
+// whenever they change, e.g. to persist them.
+func (ss *ShortcutService) SetChangeHandler(onChange func([]Shortcut)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.onChange = onChange
 }
 
-func (ss *ShortcutService) GenerateHotkeyID() int {
+// Shortcuts returns the registered shortcuts ordered by ID.
+func (ss *ShortcutService) Shortcuts() []Shortcut {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
-	return ss.shortcut.ID + 1
+	return ss.list()
+}
+
+func (ss *ShortcutService) list() []Shortcut {
+	shortcuts := make([]Shortcut, 0, len(ss.shortcuts))
+	for _, shortcut := range ss.shortcuts {
+		shortcuts = append(shortcuts, shortcut)
+	}
+	sort.Slice(shortcuts, func(i, j int) bool {
+		return shortcuts[i].ID < shortcuts[j].ID
+	})
+	return shortcuts
+}
+
+// notifyChange calls the change handler outside of the lock.
+func (ss *ShortcutService) notifyChange() {
+	ss.mu.Lock()
+	onChange, shortcuts := ss.onChange, ss.list()
+	ss.mu.Unlock()
+
+	if onChange != nil {
+		onChange(shortcuts)
+	}
 }
-func (ss *ShortcutService) RegisterShortcut(shortcut Shortcut) error {
+
+// GetShortcut returns the shortcut with the given ID.
+func (ss *ShortcutService) GetShortcut(id int) (Shortcut, error) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	// Make sure to log the shortcut details
+	shortcut, ok := ss.shortcuts[id]
+	if !ok {
+		return Shortcut{}, fmt.Errorf("%w: %d", ErrShortcutNotFound, id)
+	}
+	return shortcut, nil
+}
+
+// RegisterShortcut adds a shortcut and returns it with its assigned ID.
+func (ss *ShortcutService) RegisterShortcut(shortcut Shortcut) (Shortcut, error) {
+	ss.mu.Lock()
+	shortcut, err := ss.validate(shortcut, 0)
+	if err != nil {
+		ss.mu.Unlock()
+		return Shortcut{}, err
+	}
+
+	shortcut.ID = ss.nextID
+	ss.nextID++
 	log.Printf("Registering shortcut: %+v", shortcut)
+	ss.shortcuts[shortcut.ID] = shortcut
+	ss.mu.Unlock()
+
+	ss.notifyChange()
+	return shortcut, nil
+}
 
-	// Remplacer le raccourci existant
-	ss.shortcut = shortcut
+// UpdateShortcut changes the key and window of an existing shortcut.
+func (ss *ShortcutService) UpdateShortcut(id int, shortcut Shortcut) (Shortcut, error) {
+	ss.mu.Lock()
+	if _, ok := ss.shortcuts[id]; !ok {
+		ss.mu.Unlock()
+		return Shortcut{}, fmt.Errorf("%w: %d", ErrShortcutNotFound, id)
+	}
+	shortcut, err := ss.validate(shortcut, id)
+	if err != nil {
+		ss.mu.Unlock()
+		return Shortcut{}, err
+	}
+
+	shortcut.ID = id
+	log.Printf("Updating shortcut: %+v", shortcut)
+	ss.shortcuts[id] = shortcut
+	ss.mu.Unlock()
+
+	ss.notifyChange()
+	return shortcut, nil
+}
 
-	// Configurer l'écoute sur la nouvelle touche
-	go ss.listenForKey(shortcut)
+// UnregisterShortcut removes the shortcut with the given ID.
+func (ss *ShortcutService) UnregisterShortcut(id int) error {
+	ss.mu.Lock()
+	if _, ok := ss.shortcuts[id]; !ok {
+		ss.mu.Unlock()
+		return fmt.Errorf("%w: %d", ErrShortcutNotFound, id)
+	}
+	log.Printf("Unregistering shortcut %d", id)
+	delete(ss.shortcuts, id)
+	ss.mu.Unlock()
 
+	ss.notifyChange()
 	return nil
 }
 
-func (ss *ShortcutService) listenForKey(shortcut Shortcut) {
-	evChan := hook.Start()
-	defer hook.End()
+// SetShortcuts replaces every registered shortcut with those of the
+// configuration, without calling the change handler. Invalid shortcuts are
+// skipped and reported in the returned error.
+func (ss *ShortcutService) SetShortcuts(shortcuts []Shortcut) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
 
-	keyChar := int32(shortcut.Key[0])
+	ss.shortcuts = make(map[int]Shortcut, len(shortcuts))
+	var errs []error
+	for _, shortcut := range shortcuts {
+		shortcut, err := ss.validate(shortcut, 0)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		shortcut.ID = ss.nextID
+		ss.nextID++
+		ss.shortcuts[shortcut.ID] = shortcut
+	}
 
-	log.Printf("Listening for key '%s' with Keychar %d", shortcut.Key, keyChar)
+	return errors.Join(errs...)
+}
 
-	for ev := range evChan {
-		if ev.Kind == hook.KeyDown && ev.Keychar == keyChar {
-			log.Printf("Key '%s' pressed", shortcut.Key)
+// validate checks a shortcut and that its key combination is not bound to
+// another one. It returns the shortcut with its parsed combination.
+func (ss *ShortcutService) validate(shortcut Shortcut, id int) (Shortcut, error) {
+	combo, err := ParseKeyCombo(shortcut.Key)
+	if err != nil {
+		return Shortcut{}, err
+	}
+	switch shortcut.Action {
+	case "", ShortcutActionFocus:
+		if shortcut.WindowName == "" {
+			return Shortcut{}, fmt.Errorf("shortcut window name is empty")
+		}
+	default:
+		if _, ok := ss.actions[shortcut.Action]; !ok {
+			return Shortcut{}, fmt.Errorf("%w: %s", ErrUnknownAction, shortcut.Action)
+		}
+	}
+	for _, other := range ss.shortcuts {
+		if other.ID != id && other.combo == combo {
+			return Shortcut{}, fmt.Errorf("%w: %s (shortcut %d)", ErrShortcutExists, combo, other.ID)
+		}
+	}
+	shortcut.combo = combo
+	return shortcut, nil
+}
 
-			// Log the current shortcut window name
-			log.Printf("Current shortcut window name: '%s'", shortcut.WindowName)
+func (ss *ShortcutService) listenForKeys(ctx context.Context) error {
+	subscription := ss.input.Subscribe(InputFilter{Kinds: InputKeyDown})
+	defer subscription.Unsubscribe()
 
-			// Ensure WindowName is not empty
-			if shortcut.WindowName == "" {
-				log.Println("WindowName is empty. Cannot focus.")
-				return
-			}
+	log.Println("Listening for shortcut keys")
 
-			// Attempt to focus the window
-			err := ss.windowService.FocusWindowWithTitle(shortcut.WindowName)
-			if err != nil {
-				log.Printf("Failed to focus window '%s': %v", shortcut.WindowName, err)
+	for {
+		select {
+		case ev, ok := <-subscription.C:
+			if !ok {
+				return ErrInputClosed
+			}
+			for _, shortcut := range ss.matchingShortcuts(ev.Keycode, ev.Mask) {
+				ss.run(shortcut)
 			}
+		case <-ctx.Done():
+			log.Println("Stopped listening for shortcut keys")
+			return nil
 		}
 	}
 }
 
-func (ss *ShortcutService) UnregisterShortcut() {
+// run performs the action of a shortcut whose key was pressed.
+func (ss *ShortcutService) run(shortcut Shortcut) {
+	ss.events.Publish(Event{Type: EventHotkey, Data: shortcut})
+
+	switch shortcut.Action {
+	case "", ShortcutActionFocus:
+		log.Printf("Key '%s' pressed, focusing window '%s'", shortcut.Key, shortcut.WindowName)
+
+		// Attempt to focus the window
+		err := ss.windowService.FocusWindowWithTitle(shortcut.WindowName)
+		if err != nil {
+			log.Printf("Failed to focus window '%s': %v", shortcut.WindowName, err)
+		}
+	default:
+		ss.mu.Lock()
+		action := ss.actions[shortcut.Action]
+		ss.mu.Unlock()
+
+		log.Printf("Key '%s' pressed, running action '%s'", shortcut.Key, shortcut.Action)
+		if err := action(shortcut.Argument); err != nil {
+			log.Printf("Action '%s' failed: %v", shortcut.Action, err)
+		}
+	}
+}
+
+// matchingShortcuts returns the shortcuts bound to the key combination pressed.
+func (ss *ShortcutService) matchingShortcuts(keycode, mask uint16) []Shortcut {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	// Réinitialiser le raccourci
-	ss.shortcut = Shortcut{}
+	var matching []Shortcut
+	for _, shortcut := range ss.shortcuts {
+		if shortcut.combo.Matches(keycode, mask) {
+			matching = append(matching, shortcut)
+		}
+	}
+	return matching
 }
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newShortcutService records the shortcuts passed to the change handler.
+// Input events are dispatched by the test, the hook is not installed.
+func newShortcutService(t *testing.T, names ...string) (*FakeWindowBackend, *ShortcutService, *InputDispatcher, *[][]Shortcut, []WindowHandle) {
+	t.Helper()
+	fake, characters, handles := newFakeTeam(t, names...)
+	input := NewInputDispatcher()
+	input.started = true
+	ss := NewShortcutService(characters.windowService, input, NewEventBus())
+	var changes [][]Shortcut
+	ss.SetChangeHandler(func(shortcuts []Shortcut) {
+		changes = append(changes, shortcuts)
+	})
+	return fake, ss, input, &changes, handles
+}
+
+func TestShortcutRegistry(t *testing.T) {
+	_, ss, _, changes, _ := newShortcutService(t)
+	ss.RegisterAction("focusNext", func(string) error { return nil })
+
+	first, err := ss.RegisterShortcut(Shortcut{Key: "F1", WindowName: "Alpha"})
+	if err != nil {
+		t.Fatalf("RegisterShortcut: %v", err)
+	}
+	second, err := ss.RegisterShortcut(Shortcut{Key: "Ctrl+Tab", Action: "focusNext"})
+	if err != nil {
+		t.Fatalf("RegisterShortcut: %v", err)
+	}
+	if first.ID != 1 || second.ID != 2 {
+		t.Fatalf("shortcuts have the IDs %d and %d, want 1 and 2", first.ID, second.ID)
+	}
+
+	updated, err := ss.UpdateShortcut(first.ID, Shortcut{Key: "F2", WindowName: "Beta"})
+	if err != nil {
+		t.Fatalf("UpdateShortcut: %v", err)
+	}
+	if got, _ := ss.GetShortcut(first.ID); got.Key != "F2" || got.WindowName != "Beta" || updated.ID != first.ID {
+		t.Fatalf("GetShortcut = %+v, want F2 focusing Beta", got)
+	}
+
+	if err := ss.UnregisterShortcut(second.ID); err != nil {
+		t.Fatalf("UnregisterShortcut: %v", err)
+	}
+	if _, err := ss.GetShortcut(second.ID); !errors.Is(err, ErrShortcutNotFound) {
+		t.Fatalf("GetShortcut after UnregisterShortcut: got %v, want ErrShortcutNotFound", err)
+	}
+
+	if len(*changes) != 4 {
+		t.Fatalf("change handler called %d times, want once per change", len(*changes))
+	}
+	if last := (*changes)[3]; len(last) != 1 || last[0].Key != "F2" {
+		t.Fatalf("change handler last called with %+v, want the shortcut F2", last)
+	}
+}
+
+func TestRejectedShortcutChangesAreNotNotified(t *testing.T) {
+	_, ss, _, changes, _ := newShortcutService(t)
+	shortcut, err := ss.RegisterShortcut(Shortcut{Key: "F1", WindowName: "Alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	*changes = nil
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   error
+	}{
+		{"register a bound key", func() error {
+			_, err := ss.RegisterShortcut(Shortcut{Key: "f1", WindowName: "Beta"})
+			return err
+		}, ErrShortcutExists},
+		{"register an unknown action", func() error {
+			_, err := ss.RegisterShortcut(Shortcut{Key: "F2", Action: "dance"})
+			return err
+		}, ErrUnknownAction},
+		{"register an invalid key", func() error {
+			_, err := ss.RegisterShortcut(Shortcut{Key: "Hyper+F2", WindowName: "Beta"})
+			return err
+		}, nil},
+		{"register without a window", func() error {
+			_, err := ss.RegisterShortcut(Shortcut{Key: "F2"})
+			return err
+		}, nil},
+		{"update a missing shortcut", func() error {
+			_, err := ss.UpdateShortcut(42, Shortcut{Key: "F2", WindowName: "Beta"})
+			return err
+		}, ErrShortcutNotFound},
+		{"update with an invalid key", func() error {
+			_, err := ss.UpdateShortcut(shortcut.ID, Shortcut{Key: "", WindowName: "Beta"})
+			return err
+		}, nil},
+		{"unregister a missing shortcut", func() error {
+			return ss.UnregisterShortcut(42)
+		}, ErrShortcutNotFound},
+	}
+	for _, test := range tests {
+		err := test.change()
+		switch {
+		case err == nil:
+			t.Errorf("%s: no error", test.name)
+		case test.want != nil && !errors.Is(err, test.want):
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	if len(*changes) != 0 {
+		t.Fatalf("change handler called %d times for rejected changes", len(*changes))
+	}
+	if shortcuts := ss.Shortcuts(); len(shortcuts) != 1 || shortcuts[0].Key != "F1" {
+		t.Fatalf("Shortcuts() = %+v, want F1 unchanged", shortcuts)
+	}
+}
+
+func TestUpdateShortcutKeepsItsOwnKey(t *testing.T) {
+	_, ss, _, _, _ := newShortcutService(t)
+	shortcut, err := ss.RegisterShortcut(Shortcut{Key: "F1", WindowName: "Alpha"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.UpdateShortcut(shortcut.ID, Shortcut{Key: "F1", WindowName: "Beta"}); err != nil {
+		t.Fatalf("UpdateShortcut with the same key: %v", err)
+	}
+}
+
+func TestSetShortcutsIsNotNotified(t *testing.T) {
+	_, ss, _, changes, _ := newShortcutService(t)
+	err := ss.SetShortcuts([]Shortcut{
+		{Key: "F1", WindowName: "Alpha"},
+		{Key: "F1", WindowName: "Beta"},
+	})
+	if !errors.Is(err, ErrShortcutExists) {
+		t.Fatalf("SetShortcuts: got %v, want ErrShortcutExists for the second shortcut", err)
+	}
+	if len(*changes) != 0 {
+		t.Fatalf("change handler called %d times by SetShortcuts", len(*changes))
+	}
+	if shortcuts := ss.Shortcuts(); len(shortcuts) != 1 || shortcuts[0].WindowName != "Alpha" {
+		t.Fatalf("Shortcuts() = %+v, want the first shortcut only", shortcuts)
+	}
+}
+
+func TestShortcutKeyFocusesItsWindow(t *testing.T) {
+	fake, ss, input, _, handles := newShortcutService(t, "Alpha", "Beta")
+	if _, err := ss.RegisterShortcut(Shortcut{Key: "F2", WindowName: "Beta"}); err != nil {
+		t.Fatal(err)
+	}
+	combo, err := ParseKeyCombo("F2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ss.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ss.Stop()
+
+	// The key may be pressed before the service listens.
+	deadline := time.Now().Add(2 * time.Second)
+	for fake.GetForegroundWindow() != handles[1] {
+		if time.Now().After(deadline) {
+			t.Fatal("pressing F2 did not focus Beta")
+		}
+		input.dispatch(InputEvent{Kind: InputKeyDown, Keycode: combo.Keycode})
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,342 +1,344 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"runtime"
+	"strings"
 	"sync"
-	"syscall"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
 )
 
 var (
-	procGetMessage              = user32.NewProc("GetMessageW")
-	procTranslateMessage        = user32.NewProc("TranslateMessage")
-	procDispatchMessage         = user32.NewProc("DispatchMessageW")
-	procRegisterShellHookWindow = user32.NewProc("RegisterShellHookWindow")
-	procRegisterWindowMessage   = user32.NewProc("RegisterWindowMessageW")
-	procPostQuitMessage         = user32.NewProc("PostQuitMessage")
-	procRegisterClassEx         = user32.NewProc("RegisterClassExW")
-	procCreateWindowEx          = user32.NewProc("CreateWindowExW")
-	procDefWindowProc           = user32.NewProc("DefWindowProcW")
+	ErrTurnWindowNotFound = errors.New("window not monitored")
+	ErrTurnWindowExists   = errors.New("window already monitored")
 )
 
-// Constants for Windows messages and shell hook messages
-const (
-	WM_NCACTIVATE = 0x0086
-	WM_DESTROY    = 0x0002
-
-	HSHELL_HIGHBIT = 0x8000
-
-	HSHELL_WINDOWCREATED       = 1
-	HSHELL_WINDOWDESTROYED     = 2
-	HSHELL_ACTIVATESHELLWINDOW = 3
-	HSHELL_WINDOWACTIVATED     = 4
-	HSHELL_GETMINRECT          = 5
-	HSHELL_REDRAW              = 6
-	HSHELL_FLASH               = HSHELL_REDRAW
-)
-
-// MSG structure for Windows messages
-type MSG struct {
-	HWnd    windows.Handle
-	Message uint32
-	WParam  uintptr
-	LParam  uintptr
-	Time    uint32
-	Pt      POINT
-}
-
-type POINT struct {
-	X, Y int32
+// TurnWindow is a window monitored for turn starts, designated by a character
+// name or a part of its title.
+type TurnWindow struct {
+	Name    string       `json:"name"`
+	Enabled bool         `json:"enabled"`
+	Online  bool         `json:"online"`
+	Handle  WindowHandle `json:"handle" swaggertype:"integer"`
 }
 
-type WNDCLASSEX struct {
-	CbSize        uint32
-	Style         uint32
-	LpfnWndProc   uintptr
-	CbClsExtra    int32
-	CbWndExtra    int32
-	HInstance     windows.Handle
-	HIcon         windows.Handle
-	HCursor       windows.Handle
-	HbrBackground windows.Handle
-	LpszMenuName  *uint16
-	LpszClassName *uint16
-	HIconSm       windows.Handle
+// TurnStatus is the state of the StartTurnService.
+type TurnStatus struct {
+	Running bool         `json:"running"`
+	Windows []TurnWindow `json:"windows"`
 }
 
-// StartTurnService monitors window events for a specific window
+// StartTurnService monitors the windows of the team and focuses the one
+// requesting attention, which happens when its turn starts in a fight.
 type StartTurnService struct {
-	hwnd                windows.HWND
-	targetHwnd          windows.HWND
-	running             bool
-	mutex               sync.Mutex
-	windowTitle         string
-	windowSvc           *WindowService
-	lastLParam          uintptr
-	lastWParam          uintptr
-	lastWMNCActivate    bool
-	WM_SHELLHOOKMESSAGE uint32
+	mutex        sync.Mutex
+	configured   []string
+	windows      []*TurnWindow
+	windowSvc    *WindowService
+	characterSvc *CharacterService
+	events       *EventBus
+	runner       *serviceRunner
 }
 
 // NewStartTurnService creates a new StartTurnService
-func NewStartTurnService(ws *WindowService) *StartTurnService {
+func NewStartTurnService(ws *WindowService, cs *CharacterService, events *EventBus) *StartTurnService {
 	return &StartTurnService{
-		windowSvc: ws,
+		windowSvc:    ws,
+		characterSvc: cs,
+		events:       events,
+		runner:       newServiceRunner("startTurn", events),
 	}
 }
 
-// Start initiates the service and registers the shell hook
-func (sts *StartTurnService) Start(windowTitle string) error {
+// Name identifies the service.
+func (sts *StartTurnService) Name() string {
+	return "startTurn"
+}
+
+// SetWindows sets the windows monitored from the next start, or every
+// registered character when none is given.
+func (sts *StartTurnService) SetWindows(windows []string) {
 	sts.mutex.Lock()
 	defer sts.mutex.Unlock()
 
-	if sts.running {
-		log.Println("Service is already running.")
-		return nil
-	}
-	sts.windowTitle = windowTitle
-	log.Printf("Starting service with window title: %s", windowTitle)
-
-	// Register WM_SHELLHOOKMESSAGE
-	wmShellHookMsg, err := registerWindowMessage("SHELLHOOK")
-	if err != nil || wmShellHookMsg == 0 {
-		return fmt.Errorf("failed to register WM_SHELLHOOKMESSAGE: %v", err)
-	}
-	sts.WM_SHELLHOOKMESSAGE = wmShellHookMsg
-	log.Printf("Registered WM_SHELLHOOKMESSAGE: %d", wmShellHookMsg)
-
-	// Find the target window by partial title
-	log.Printf("Searching for window with title containing: %s", windowTitle)
-	hwndTarget, err := sts.windowSvc.FindWindowByPartialTitle(windowTitle)
-	if err != nil {
-		return fmt.Errorf("could not find window with title %s: %v", windowTitle, err)
-	}
-	log.Printf("Window found: HWND = %d", hwndTarget)
-	sts.targetHwnd = windows.HWND(hwndTarget)
-
-	// Start monitoring window events
-	go sts.monitorEvents()
-
-	return nil
+	sts.configured = append([]string(nil), windows...)
 }
 
-// monitorEvents listens for Windows messages in a message loop
-func (sts *StartTurnService) monitorEvents() {
-	runtime.LockOSThread()
+// Start initiates the service and registers the shell hook, until Stop is
+// called or ctx is cancelled.
+func (sts *StartTurnService) Start(ctx context.Context) error {
+	var events <-chan ShellEvent
+	return sts.runner.start(ctx, func(ctx context.Context) error {
+		var err error
+		events, err = sts.watch(ctx)
+		return err
+	}, func(ctx context.Context) error {
+		return sts.monitorEvents(events)
+	})
+}
 
-	// Create message-only window
-	hwnd, err := createMessageOnlyWindow()
-	if err != nil {
-		log.Fatalf("failed to create message-only window: %v", err)
-	}
+// watch resolves the monitored windows and starts watching shell events.
+func (sts *StartTurnService) watch(ctx context.Context) (<-chan ShellEvent, error) {
+	sts.mutex.Lock()
+	defer sts.mutex.Unlock()
 
-	// Register shell hook window
-	log.Printf("Registering shell hook window for HWND: %d", hwnd)
-	err = registerShellHookWindow(hwnd)
-	if err != nil {
-		log.Fatalf("failed to register shell hook window: %v", err)
+	windows := sts.configured
+	if len(windows) == 0 {
+		if err := sts.characterSvc.Refresh(); err != nil {
+			return nil, fmt.Errorf("could not list the characters: %v", err)
+		}
+		for _, character := range sts.characterSvc.GetCharacters() {
+			windows = append(windows, character.Name)
+		}
 	}
-	log.Printf("Shell hook window successfully registered for HWND: %d", hwnd)
-
-	sts.hwnd = hwnd
-	sts.running = true
-
-	var msg MSG
-	log.Println("Starting to monitor window messages")
-
-	for {
-		ret, err := getMessage(&msg)
-		if ret == 0 {
-			log.Println("WM_QUIT received, exiting message loop.")
-			return
-		} else if ret == -1 {
-			log.Printf("GetMessage returned an error: %v", err)
-			continue
+	sts.windows = nil
+	for _, name := range windows {
+		if sts.find(name) == nil {
+			sts.windows = append(sts.windows, &TurnWindow{Name: name, Enabled: true})
 		}
+	}
+	log.Printf("Starting service for windows: %s", strings.Join(windows, ", "))
+	sts.resolve()
 
-		log.Printf("Message received: HWND=%d, Message=%d, WParam=%d, LParam=%d", msg.HWnd, msg.Message, msg.WParam, msg.LParam)
-
-		// Handle messages
-		sts.handleMessage(&msg)
-
-		translateMessage(&msg)
-		dispatchMessage(&msg)
+	// Start monitoring window events
+	events, err := sts.windowSvc.Backend().WatchShellEvents(ctx.Done())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch shell events: %v", err)
 	}
+	return events, nil
 }
 
-// handleMessage processes incoming Windows messages
-func (sts *StartTurnService) handleMessage(msg *MSG) {
-	// Handle WM_NCACTIVATE
-	if msg.Message == WM_NCACTIVATE {
-		sts.lastWMNCActivate = msg.WParam != 0
-		log.Printf("WM_NCACTIVATE detected: Active=%t", sts.lastWMNCActivate)
+// resolve binds the monitored windows to their current window handle, by
+// character name first, then by partial title, so that restarted clients
+// are picked up. Changes are published as turn.bound and turn.lost events.
+func (sts *StartTurnService) resolve() {
+	if err := sts.characterSvc.Refresh(); err != nil {
+		log.Printf("Failed to refresh characters: %v", err)
 	}
 
-	// Intercept WM_SHELLHOOKMESSAGE
-	if msg.Message == sts.WM_SHELLHOOKMESSAGE {
-		log.Printf("WM_SHELLHOOKMESSAGE detected: WParam=%d, LParam=%d", msg.WParam, msg.LParam)
-		sts.handleShellHookMessage(msg.WParam, msg.LParam)
+	for _, window := range sts.windows {
+		var hwnd WindowHandle
+		if character, err := sts.characterSvc.GetCharacter(window.Name); err == nil {
+			hwnd = character.Handle
+		} else if found, err := sts.windowSvc.FindWindowByPartialTitle(window.Name); err == nil {
+			hwnd = found
+		}
+		sts.bind(window, hwnd)
 	}
 }
 
-// handleShellHookMessage processes WM_SHELLHOOKMESSAGE events
-func (sts *StartTurnService) handleShellHookMessage(wParam, lParam uintptr) {
-	log.Printf("Handling WM_SHELLHOOKMESSAGE with WParam=%d, LParam=%d", wParam, lParam)
-
-	// Extraire le code du message en masquant HSHELL_HIGHBIT
-	messageCode := uint32(wParam & ^uintptr(HSHELL_HIGHBIT))
-	log.Printf("Extracted message code: %d", messageCode)
-
-	if windows.HWND(lParam) != sts.targetHwnd {
-		log.Printf("Received shell hook message for a different window: %d", lParam)
+// bind binds a monitored window to hwnd, or unbinds it when hwnd is 0.
+// Changes are only reported while the service runs.
+func (sts *StartTurnService) bind(window *TurnWindow, hwnd WindowHandle) {
+	if window.Handle == hwnd {
 		return
 	}
 
-	switch messageCode {
-	case HSHELL_WINDOWACTIVATED:
-		log.Printf("Target window activated, lParam=%d", lParam)
-		// Vous pouvez ajouter un traitement ici si nécessaire
-
-	case HSHELL_REDRAW:
-		log.Printf("Notification captured: The target window has requested attention (HSHELL_REDRAW), lParam=%d", lParam)
-		// Appeler FocusWindowWithTitle pour mettre la fenêtre au premier plan
-		err := sts.windowSvc.FocusWindowWithTitle(sts.windowTitle)
-		if err != nil {
-			log.Printf("Failed to focus window: %v", err)
-		} else {
-			log.Println("Window focused successfully.")
-		}
-
-	default:
-		log.Printf("Other shell event received: messageCode=%d, lParam=%d", messageCode, lParam)
+	previous := window.Handle
+	window.Online, window.Handle = hwnd != 0, hwnd
+	if !sts.runner.running() {
+		return
 	}
-}
-
-// Stop stops the service of message capturing
-func (sts *StartTurnService) Stop() {
-	sts.mutex.Lock()
-	defer sts.mutex.Unlock()
-
-	if !sts.running {
-		log.Println("Service is not running.")
+	if hwnd == 0 {
+		log.Printf("Window %s closed, waiting for it to reopen", window.Name)
+		sts.events.Publish(Event{Type: EventTurnLost, Window: previous, Data: *window})
 		return
 	}
+	log.Printf("Window %s bound to hwnd=%d", window.Name, hwnd)
+	sts.events.Publish(Event{Type: EventTurnBound, Window: hwnd, Title: sts.windowSvc.GetWindowText(hwnd), Data: *window})
+}
 
-	log.Println("Stopping the service")
-	postQuitMessage(0)
-	sts.running = false
-	log.Println("Service stopped.")
+// find returns the monitored window designated by name, or nil.
+func (sts *StartTurnService) find(name string) *TurnWindow {
+	for _, window := range sts.windows {
+		if strings.EqualFold(window.Name, name) {
+			return window
+		}
+	}
+	return nil
 }
 
-// Helper function to register a window message
-func registerWindowMessage(lpString string) (uint32, error) {
-	ret, _, err := procRegisterWindowMessage.Call(uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(lpString))))
-	if ret == 0 {
-		if err == nil {
-			return 0, windows.GetLastError()
+// mayMonitor reports whether hwnd may be one of the monitored windows, so
+// that windows of other applications do not refresh the characters: its
+// title is the one of a game client or contains the name of a window.
+func (sts *StartTurnService) mayMonitor(hwnd WindowHandle) bool {
+	title := sts.windowSvc.GetWindowText(hwnd)
+	if _, _, ok := ParseGameTitle(title); ok {
+		return true
+	}
+	for _, window := range sts.windows {
+		if strings.Contains(title, window.Name) {
+			return true
 		}
-		return 0, err
 	}
-	return uint32(ret), nil
+	return false
 }
 
-// Helper function to register the shell hook window
-func registerShellHookWindow(hwnd windows.HWND) error {
-	ret, _, err := procRegisterShellHookWindow.Call(uintptr(hwnd))
-	if ret == 0 {
-		if err == nil {
-			return windows.GetLastError()
+// findHandle returns the monitored window bound to hwnd, or nil.
+func (sts *StartTurnService) findHandle(hwnd WindowHandle) *TurnWindow {
+	for _, window := range sts.windows {
+		if window.Online && window.Handle == hwnd {
+			return window
 		}
-		return err
 	}
 	return nil
 }
 
-// Helper function to post a quit message to the message loop
-func postQuitMessage(exitCode int32) {
-	procPostQuitMessage.Call(uintptr(exitCode))
+// Windows returns the monitored windows.
+func (sts *StartTurnService) Windows() []TurnWindow {
+	return sts.TurnStatus().Windows
 }
 
-// Implementing GetMessage
-func getMessage(msg *MSG) (int32, error) {
-	ret, _, err := procGetMessage.Call(
-		uintptr(unsafe.Pointer(msg)),
-		0,
-		0,
-		0,
-	)
-	if ret == 0 {
-		return 0, nil // WM_QUIT received
+// TurnStatus returns whether the service runs and the monitored windows.
+func (sts *StartTurnService) TurnStatus() TurnStatus {
+	sts.mutex.Lock()
+	defer sts.mutex.Unlock()
+
+	running := sts.runner.running()
+	if !running {
+		sts.resolve()
 	}
-	if ret == ^uintptr(0) { // -1 cast to uintptr
-		return -1, err
+	windows := make([]TurnWindow, 0, len(sts.windows))
+	for _, window := range sts.windows {
+		windows = append(windows, *window)
 	}
-	return int32(ret), nil
+	return TurnStatus{Running: running, Windows: windows}
 }
 
-// Implementing TranslateMessage
-func translateMessage(msg *MSG) {
-	procTranslateMessage.Call(uintptr(unsafe.Pointer(msg)))
+// AddWindow monitors another window, designated by a character name or a
+// part of its title. It may be called while the service runs.
+func (sts *StartTurnService) AddWindow(name string) (TurnWindow, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return TurnWindow{}, fmt.Errorf("window name is empty")
+	}
+
+	sts.mutex.Lock()
+	defer sts.mutex.Unlock()
+
+	if sts.find(name) != nil {
+		return TurnWindow{}, fmt.Errorf("%w: %s", ErrTurnWindowExists, name)
+	}
+	window := &TurnWindow{Name: name, Enabled: true}
+	sts.windows = append(sts.windows, window)
+	sts.resolve()
+	log.Printf("Monitoring window %s", name)
+	return *window, nil
 }
 
-// Implementing DispatchMessage
-func dispatchMessage(msg *MSG) {
-	procDispatchMessage.Call(uintptr(unsafe.Pointer(msg)))
+// RemoveWindow stops monitoring a window.
+func (sts *StartTurnService) RemoveWindow(name string) error {
+	sts.mutex.Lock()
+	defer sts.mutex.Unlock()
+
+	for i, window := range sts.windows {
+		if strings.EqualFold(window.Name, name) {
+			sts.windows = append(sts.windows[:i], sts.windows[i+1:]...)
+			log.Printf("No longer monitoring window %s", window.Name)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTurnWindowNotFound, name)
 }
 
-// Create a message-only window
-func createMessageOnlyWindow() (windows.HWND, error) {
-	var className = windows.StringToUTF16Ptr("MessageOnlyWindowClass")
+// SetWindowEnabled enables or disables the focus of a monitored window,
+// without removing it.
+func (sts *StartTurnService) SetWindowEnabled(name string, enabled bool) (TurnWindow, error) {
+	sts.mutex.Lock()
+	defer sts.mutex.Unlock()
 
-	var wcex WNDCLASSEX
-	wcex.CbSize = uint32(unsafe.Sizeof(wcex))
-	wcex.LpfnWndProc = syscall.NewCallback(messageOnlyWndProc)
-	wcex.HInstance = windows.Handle(0)
-	wcex.LpszClassName = className
+	window := sts.find(name)
+	if window == nil {
+		return TurnWindow{}, fmt.Errorf("%w: %s", ErrTurnWindowNotFound, name)
+	}
+	window.Enabled = enabled
+	return *window, nil
+}
 
-	atom, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wcex)))
-	if atom == 0 {
-		return 0, err
+// monitorEvents handles shell events until the watch ends
+func (sts *StartTurnService) monitorEvents(events <-chan ShellEvent) error {
+	log.Println("Starting to monitor shell events")
+	for event := range events {
+		sts.handleShellEvent(event)
 	}
+	log.Println("Shell event watch ended.")
+	return errors.New("shell event watch ended")
+}
 
-	hwnd, _, err := procCreateWindowEx.Call(
-		0,
-		uintptr(unsafe.Pointer(className)),
-		0,
-		0,
-		0,
-		0,
-		0,
-		0,
-		uintptr(HWND_MESSAGE), // Parent window
-		0,
-		0,
-		0,
-	)
-	if hwnd == 0 {
-		return 0, err
+// handleShellEvent re-binds the monitored windows when clients are closed or
+// opened, and focuses the monitored window requesting attention
+func (sts *StartTurnService) handleShellEvent(event ShellEvent) {
+	switch event.Code {
+	case ShellWindowCreated:
+		sts.mutex.Lock()
+		sts.resolve()
+		sts.mutex.Unlock()
+		return
+	case ShellWindowDestroyed:
+		sts.mutex.Lock()
+		if window := sts.findHandle(event.Window); window != nil {
+			sts.bind(window, 0)
+			// Another client of the same character may still be open.
+			sts.resolve()
+		}
+		sts.mutex.Unlock()
+		return
+	case ShellWindowRedraw:
+		// The title of a reopened client may have changed to the character
+		// name.
+		sts.mutex.Lock()
+		if sts.findHandle(event.Window) == nil && sts.mayMonitor(event.Window) {
+			sts.resolve()
+		}
+		sts.mutex.Unlock()
+		return
+	case ShellWindowFlash:
+	default:
+		return
 	}
 
-	return windows.HWND(hwnd), nil
-}
+	sts.mutex.Lock()
+	window := sts.findHandle(event.Window)
+	if window == nil && sts.mayMonitor(event.Window) {
+		// A client reopened without its title changing yet.
+		sts.resolve()
+		window = sts.findHandle(event.Window)
+	}
+	var target TurnWindow
+	if window != nil {
+		target = *window
+	}
+	sts.mutex.Unlock()
 
-// Window procedure for the message-only window
-func messageOnlyWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
-	switch msg {
-	case WM_DESTROY:
-		postQuitMessage(0)
-		return 0
+	switch {
+	case window == nil:
+		log.Printf("Received shell event for a window not monitored: %d", event.Window)
+	case !target.Enabled:
+		log.Printf("Window %s requested attention, but is disabled", target.Name)
 	default:
-		ret, _, _ := procDefWindowProc.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
-		return ret
+		log.Printf("Notification captured: %s has requested attention, hwnd=%d", target.Name, event.Window)
+		sts.events.Publish(Event{Type: EventTurnStart, Window: event.Window, Title: sts.windowSvc.GetWindowText(event.Window), Data: target})
+		// Mettre la fenêtre au premier plan
+		if err := sts.windowSvc.FocusWindow(event.Window); err != nil {
+			log.Printf("Failed to focus window: %v", err)
+		} else {
+			log.Println("Window focused successfully.")
+		}
 	}
 }
 
-// Constants
-const (
-	HWND_MESSAGE = windows.Handle(^uintptr(2)) // Define HWND_MESSAGE as (HWND)-3
-)
+// Stop stops the service of message capturing. The mutex is not held, since
+// the shell event handler may be waiting for it.
+func (sts *StartTurnService) Stop() error {
+	return sts.runner.stop()
+}
+
+// Status reports the state of the service.
+func (sts *StartTurnService) Status() ServiceStatus {
+	return sts.runner.status()
+}
+
+func (sts *StartTurnService) lifecycle() *serviceRunner {
+	return sts.runner
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextEvent waits for an event on the subscription.
+func nextEvent(t *testing.T, subscription *EventSubscription) Event {
+	t.Helper()
+	select {
+	case event := <-subscription.C:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+// startTurnService starts a StartTurnService monitoring the team.
+func startTurnService(t *testing.T, names ...string) (*FakeWindowBackend, *StartTurnService, *EventBus, []WindowHandle) {
+	t.Helper()
+	fake, characters, handles := newFakeTeam(t, names...)
+	events := NewEventBus()
+	sts := NewStartTurnService(characters.windowService, characters, events)
+	if err := sts.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { sts.Stop() })
+	return fake, sts, events, handles
+}
+
+func TestStartTurnFocusesTheFlashingClient(t *testing.T) {
+	fake, _, events, handles := startTurnService(t, "Alpha", "Beta")
+	turns := events.Subscribe(EventFilter{Types: []EventType{EventTurnStart}})
+	defer turns.Unsubscribe()
+
+	fake.Flash(handles[1])
+	event := nextEvent(t, turns)
+	if event.Window != handles[1] {
+		t.Fatalf("turn started for %d, want %d", event.Window, handles[1])
+	}
+	if fake.GetForegroundWindow() != handles[1] {
+		t.Fatalf("foreground = %d, want %d", fake.GetForegroundWindow(), handles[1])
+	}
+}
+
+func TestStartTurnIgnoresDisabledWindows(t *testing.T) {
+	fake, sts, events, handles := startTurnService(t, "Alpha", "Beta")
+	if _, err := sts.SetWindowEnabled("Beta", false); err != nil {
+		t.Fatal(err)
+	}
+	turns := events.Subscribe(EventFilter{Types: []EventType{EventTurnStart}})
+	defer turns.Unsubscribe()
+
+	fake.Flash(handles[1])
+	fake.Flash(handles[0])
+	if event := nextEvent(t, turns); event.Window != handles[0] {
+		t.Fatalf("turn started for %d, want only %d", event.Window, handles[0])
+	}
+	for _, hwnd := range fake.FocusHistory() {
+		if hwnd == handles[1] {
+			t.Fatalf("disabled window %d was focused", hwnd)
+		}
+	}
+}
+
+func TestStartTurnRebindsReopenedClients(t *testing.T) {
+	fake, sts, events, handles := startTurnService(t, "Alpha", "Beta")
+	bound := events.Subscribe(EventFilter{Types: []EventType{EventTurnLost, EventTurnBound}})
+	defer bound.Unsubscribe()
+
+	fake.CloseWindow(handles[1])
+	if event := nextEvent(t, bound); event.Type != EventTurnLost {
+		t.Fatalf("got %s, want %s", event.Type, EventTurnLost)
+	}
+	reopened := fake.AddWindow("Beta - Dofus 2.70.5")
+	if event := nextEvent(t, bound); event.Type != EventTurnBound || event.Window != reopened {
+		t.Fatalf("got %s for %d, want %s for %d", event.Type, event.Window, EventTurnBound, reopened)
+	}
+
+	for _, window := range sts.Windows() {
+		if window.Name == "Beta" && (!window.Online || window.Handle != reopened) {
+			t.Fatalf("Beta = %+v, want bound to %d", window, reopened)
+		}
+	}
+}
+
+func TestStartTurnRebindsRenamedClients(t *testing.T) {
+	fake, _, events, handles := startTurnService(t, "Alpha", "Beta")
+	bound := events.Subscribe(EventFilter{Types: []EventType{EventTurnLost, EventTurnBound}})
+	defer bound.Unsubscribe()
+
+	fake.CloseWindow(handles[1])
+	nextEvent(t, bound)
+	// Clients are named after the character once logged in.
+	reopened := fake.AddWindow("Dofus")
+	fake.SetWindowTitle(reopened, "Beta - Dofus 2.70.5")
+	if event := nextEvent(t, bound); event.Type != EventTurnBound || event.Window != reopened {
+		t.Fatalf("got %s for %d, want %s for %d", event.Type, event.Window, EventTurnBound, reopened)
+	}
+}
+
+func TestStartTurnStartsForClientsNotBoundYet(t *testing.T) {
+	fake, _, events, handles := startTurnService(t, "Alpha", "Beta")
+	turns := events.Subscribe(EventFilter{Types: []EventType{EventTurnStart}})
+	defer turns.Unsubscribe()
+
+	fake.CloseWindow(handles[1])
+	reopened := fake.AddWindow("Dofus")
+	// Renamed without a redraw being reported: the flash binds it.
+	fake.UpdateWindow(reopened, func(w *Window) { w.Title = "Beta - Dofus 2.70.5" })
+	fake.Flash(reopened)
+	if event := nextEvent(t, turns); event.Window != reopened {
+		t.Fatalf("turn started for %d, want %d", event.Window, reopened)
+	}
+}
@@ -25,6 +25,7 @@ var (
 	procFindWindow             = user32.NewProc("FindWindowW")
 	procGetCursorPos           = user32.NewProc("GetCursorPos")
 	procScreenToClient         = user32.NewProc("ScreenToClient")
+	procClientToScreen         = user32.NewProc("ClientToScreen")
 	procCreateCompatibleDC     = syscall.NewLazyDLL("gdi32.dll").NewProc("CreateCompatibleDC")
 	procCreateCompatibleBitmap = syscall.NewLazyDLL("gdi32.dll").NewProc("CreateCompatibleBitmap")
 	procSelectObject           = syscall.NewLazyDLL("gdi32.dll").NewProc("SelectObject")
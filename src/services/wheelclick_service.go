@@ -1,130 +1,512 @@
 package services
 
 import (
+	"context"
 	"log"
+	"math/rand/v2"
+	"slices"
+	"sort"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
-	"unsafe"
+)
 
-	hook "github.com/robotn/gohook"
+const (
+	VK_MBUTTON = 0x04 // Virtual key code for middle mouse button
 )
 
+// Delivery modes of the broadcast clicks.
 const (
-	WM_LBUTTONDOWN = 0x0201
-	WM_LBUTTONUP   = 0x0202
-	VK_MBUTTON     = 0x04 // Virtual key code for middle mouse button
+	// ClickForeground brings each window to the foreground and moves the
+	// cursor over the click. The cursor and the foreground window are
+	// restored once every window was clicked.
+	ClickForeground = "foreground"
+	// ClickBackground posts mouse messages to each window, leaving the
+	// cursor and the foreground window alone.
+	ClickBackground = "background"
 )
 
-type WheelClickService struct {
-	stopChan chan struct{}
+// Orders of the windows receiving a broadcast click.
+const (
+	// OrderWindows clicks the windows in z-order, topmost first.
+	OrderWindows = "windows"
+	// OrderTeam clicks the team members by slot, then the other windows in
+	// z-order.
+	OrderTeam = "team"
+)
+
+// Places of the window clicked among the windows receiving the broadcast.
+const (
+	LeaderFirst = "first"
+	LeaderLast  = "last"
+)
+
+// BroadcastSettings configures how clicks are broadcast to the game windows.
+type BroadcastSettings struct {
+	// TitleFilter selects the windows receiving the clicks, unless Targets
+	// is set.
+	TitleFilter string
+	// Targets lists the windows receiving the clicks, by character name,
+	// slot or part of the title.
+	Targets []string
+	// ExcludeSource leaves out the window clicked.
+	ExcludeSource bool
+	// Mode is the delivery mode, ClickForeground or ClickBackground.
+	Mode string
+	// CursorDelay is the pause between moving the cursor and clicking, in
+	// the foreground mode.
+	CursorDelay time.Duration
+	// Order is the order of the windows, OrderWindows or OrderTeam.
+	Order string
+	// Leader moves the window clicked first or last, LeaderFirst or
+	// LeaderLast. It keeps its place in the order when empty.
+	Leader string
+	// Delay is the pause before clicking each window after the first.
+	Delay time.Duration
+	// Jitter is the upper bound of a random pause added to each delay.
+	Jitter time.Duration
+	// Parallel clicks every window at once, each after its own delay, in
+	// the background mode. The foreground mode is always sequential.
+	Parallel bool
 }
 
-func GetCursorPos() (int, int) {
-	var pt Point
-	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
-	return int(pt.X), int(pt.Y)
+// DefaultBroadcastSettings returns the settings used until others are applied.
+func DefaultBroadcastSettings() BroadcastSettings {
+	return BroadcastSettings{
+		TitleFilter: "Dofus",
+		Mode:        ClickForeground,
+		CursorDelay: 50 * time.Millisecond,
+		Order:       OrderWindows,
+	}
 }
 
-func ConvertScreenToClient(hWnd syscall.Handle, x, y int) (int, int) {
-	var point struct {
-		X, Y int32
+// pause returns the delay before clicking a window, jitter included.
+func (s BroadcastSettings) pause() time.Duration {
+	delay := s.Delay
+	if s.Jitter > 0 {
+		delay += time.Duration(rand.Int64N(int64(s.Jitter) + 1))
 	}
-	point.X = int32(x)
-	point.Y = int32(y)
+	return delay
+}
 
-	ret, _, _ := procScreenToClient.Call(uintptr(hWnd), uintptr(unsafe.Pointer(&point)))
-	if ret == 0 {
-		log.Printf("Erreur lors de la conversion des coordonnées écran en coordonnées client")
+// BroadcastReport describes a click broadcast and the timing of each window.
+type BroadcastReport struct {
+	// X and Y are the screen coordinates of the click broadcast.
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Source and Relative are the window clicked and the position relative to
+	// its client area, when the click is over a window receiving it.
+	Source    WindowHandle   `json:"source,omitempty" swaggertype:"integer"`
+	Relative  *RelativePoint `json:"relative,omitempty"`
+	Mode      string         `json:"mode"`
+	Order     string         `json:"order"`
+	Parallel  bool           `json:"parallel"`
+	StartedAt time.Time      `json:"startedAt"`
+	// DurationMs is the time taken by the whole broadcast.
+	DurationMs float64        `json:"durationMs"`
+	Targets    []TargetTiming `json:"targets"`
+}
+
+// TargetTiming is the timing of the click delivered to a window.
+type TargetTiming struct {
+	Window WindowHandle `json:"window" swaggertype:"integer"`
+	Title  string       `json:"title"`
+	// X and Y are the screen coordinates clicked in the window.
+	X int `json:"x"`
+	Y int `json:"y"`
+	// DelayMs is the pause before the click, jitter included.
+	DelayMs float64 `json:"delayMs"`
+	// StartMs is the time from the start of the broadcast to the click.
+	StartMs float64 `json:"startMs"`
+	// DurationMs is the time taken to deliver the click.
+	DurationMs float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+type WheelClickService struct {
+	windowService *WindowService
+	characterSvc  *CharacterService
+	input         *InputDispatcher
+	events        *EventBus
+	runner        *serviceRunner
+	mu            sync.Mutex
+	settings      BroadcastSettings
+	lastReport    *BroadcastReport
+}
+
+// NewWheelClickService creates a new instance of the WheelClickService.
+func NewWheelClickService(windowService *WindowService, characterService *CharacterService, input *InputDispatcher, events *EventBus) *WheelClickService {
+	return &WheelClickService{
+		windowService: windowService,
+		characterSvc:  characterService,
+		input:         input,
+		events:        events,
+		runner:        newServiceRunner("wheelClick", events),
+		settings:      DefaultBroadcastSettings(),
 	}
+}
+
+// SetBroadcastSettings replaces the broadcast settings, taking effect on the next click.
+func (wcs *WheelClickService) SetBroadcastSettings(settings BroadcastSettings) {
+	settings.Targets = append([]string(nil), settings.Targets...)
 
-	return int(point.X), int(point.Y)
+	wcs.mu.Lock()
+	defer wcs.mu.Unlock()
+	wcs.settings = settings
 }
 
-func SimulateClick(hWnd syscall.Handle, x, y int) {
+// BroadcastSettings returns the current broadcast settings.
+func (wcs *WheelClickService) BroadcastSettings() BroadcastSettings {
+	wcs.mu.Lock()
+	defer wcs.mu.Unlock()
+	settings := wcs.settings
+	settings.Targets = append([]string(nil), settings.Targets...)
+	return settings
+}
+
+// LastReport returns the report of the last broadcast, if any.
+func (wcs *WheelClickService) LastReport() (BroadcastReport, bool) {
+	wcs.mu.Lock()
+	defer wcs.mu.Unlock()
+
+	if wcs.lastReport == nil {
+		return BroadcastReport{}, false
+	}
+	return *wcs.lastReport, true
+}
+
+// SimulateClick brings the window to the foreground and clicks at the given
+// screen coordinates.
+func (wcs *WheelClickService) SimulateClick(hWnd WindowHandle, x, y int) error {
+	backend := wcs.windowService.Backend()
+
 	// Mettre la fenêtre au premier plan
-	procSetForegroundWindow.Call(uintptr(hWnd))
+	if err := backend.SetForegroundWindow(hWnd); err != nil {
+		log.Printf("Impossible de mettre la fenêtre au premier plan : %v", err)
+	}
 
 	// Convertir les coordonnées d'écran en coordonnées client
-	clientX, clientY := ConvertScreenToClient(hWnd, x, y)
+	clientX, clientY, err := backend.ScreenToClient(hWnd, x, y)
+	if err != nil {
+		log.Printf("Erreur lors de la conversion des coordonnées écran en coordonnées client")
+	}
 
 	// Journaliser les coordonnées utilisées pour définir la position du curseur
 	log.Printf("Définir la position du curseur à : X=%d, Y=%d", x, y)
 
 	// Définir la position du curseur
-	ret, _, _ := procSetCursorPos.Call(uintptr(x), uintptr(y))
-	if ret == 0 {
+	if err := backend.SetCursorPos(x, y); err != nil {
 		log.Printf("Échec de la définition de la position du curseur à : X=%d, Y=%d", x, y)
 	}
 
 	// Vérifier la position actuelle du curseur
-	actualX, actualY := GetCursorPos()
+	actualX, actualY := backend.GetCursorPos()
 	log.Printf("Position actuelle du curseur après la définition : X=%d, Y=%d", actualX, actualY)
 
 	// Introduire un léger délai pour s'assurer que le curseur a bougé
-	time.Sleep(50 * time.Millisecond)
+	time.Sleep(wcs.BroadcastSettings().CursorDelay)
 
-	// Utiliser SendMessage pour simuler le clic aux coordonnées client
-	log.Println("Envoi du message WM_LBUTTONDOWN")
-	procSendMessage.Call(uintptr(hWnd), WM_LBUTTONDOWN, 0, uintptr(clientY<<16|clientX))
-	time.Sleep(15 * time.Millisecond)
-	log.Println("Envoi du message WM_LBUTTONUP")
-	procSendMessage.Call(uintptr(hWnd), WM_LBUTTONUP, 0, uintptr(clientY<<16|clientX))
+	// Simuler le clic aux coordonnées client
+	log.Println("Envoi du clic gauche")
+	if err := backend.SendClick(hWnd, clientX, clientY); err != nil {
+		log.Printf("Échec de l'envoi du clic : %v", err)
+		return err
+	}
+	return nil
 }
 
-// Start detects middle mouse clicks.
-func (wcs *WheelClickService) Start() {
-	wcs.stopChan = make(chan struct{})
-	go wcs.DetectMiddleClick()
+// PostClick clicks the window at the given screen coordinates in the
+// background, without moving the cursor nor activating the window.
+func (wcs *WheelClickService) PostClick(hWnd WindowHandle, x, y int) error {
+	backend := wcs.windowService.Backend()
+
+	clientX, clientY, err := backend.ScreenToClient(hWnd, x, y)
+	if err != nil {
+		log.Printf("Failed to convert screen coordinates to client coordinates: %v", err)
+		return err
+	}
+	if err := backend.PostClick(hWnd, clientX, clientY); err != nil {
+		log.Printf("Failed to post the click: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Name identifies the service.
+func (wcs *WheelClickService) Name() string {
+	return "wheelClick"
+}
+
+// Start detects middle mouse clicks until Stop is called or ctx is cancelled.
+func (wcs *WheelClickService) Start(ctx context.Context) error {
+	return wcs.runner.start(ctx, nil, wcs.DetectMiddleClick)
 }
 
 // Stop stops detecting middle mouse clicks.
-func (wcs *WheelClickService) Stop() {
-	close(wcs.stopChan) // Signal to stop
+func (wcs *WheelClickService) Stop() error {
+	return wcs.runner.stop()
+}
+
+// Status reports the state of the service.
+func (wcs *WheelClickService) Status() ServiceStatus {
+	return wcs.runner.status()
 }
 
-// DetectMiddleClick listens for mouse button presses.
-func (wcs *WheelClickService) DetectMiddleClick() {
-	evChan := hook.Start()
-	defer hook.End()
+func (wcs *WheelClickService) lifecycle() *serviceRunner {
+	return wcs.runner
+}
+
+// DetectMiddleClick listens for middle mouse button releases until ctx is cancelled.
+func (wcs *WheelClickService) DetectMiddleClick(ctx context.Context) error {
+	subscription := wcs.input.Subscribe(InputFilter{Kinds: InputMouseUp, Button: MouseMiddle})
+	defer subscription.Unsubscribe()
 
 	for {
 		select {
-		case ev := <-evChan:
-			if ev.Kind == hook.MouseDown && ev.Button == 3 { // Check for middle mouse button
-				x, y := ev.X, ev.Y
-				log.Printf("Middle click detected at: (%d, %d)", x, y)
-				wcs.SendClickToDofusWindows(int(x), int(y)) // Convert to int
+		case ev, ok := <-subscription.C:
+			if !ok {
+				return ErrInputClosed
 			}
-		case <-wcs.stopChan:
+			log.Printf("Middle click detected at: (%d, %d)", ev.X, ev.Y)
+			wcs.SendClickToDofusWindows(ev.X, ev.Y)
+		case <-ctx.Done():
 			log.Println("Stopping middle click detection")
-			return
+			return nil
 		}
 	}
 }
 
-// SendClickToDofusWindows finds all windows containing "Dofus" and sends a click.
-func (wcs *WheelClickService) SendClickToDofusWindows(x, y int) {
-	windowService := &WindowService{}
-	windows, err := windowService.GetWindows()
+// SendClickToDofusWindows sends a click to the target windows: those of the
+// broadcast settings, or all windows containing the title filter ("Dofus" by
+// default). The click is made at the same position relative to the client
+// area of each window as in the window clicked, so that windows may be tiled
+// or differently sized. Screen coordinates are replayed as is when the click
+// is not over one of them. Coordinates are physical pixels, so that windows
+// on monitors with different scaling are mapped alike. The windows are
+// clicked in the order, with the delays, of the broadcast settings, and the
+// timing of each click is reported.
+func (wcs *WheelClickService) SendClickToDofusWindows(x, y int) BroadcastReport {
+	settings := wcs.BroadcastSettings()
+	report := BroadcastReport{
+		X:         x,
+		Y:         y,
+		Mode:      settings.Mode,
+		Order:     settings.Order,
+		Parallel:  settings.Parallel && settings.Mode == ClickBackground,
+		StartedAt: time.Now(),
+		Targets:   []TargetTiming{},
+	}
+	targets, err := wcs.targetWindows(settings)
 	if err != nil {
 		log.Printf("Error getting windows: %v", err)
-		return
+		return report
 	}
 
-	for _, windowTitle := range windows {
-		if strings.Contains(windowTitle, "Dofus") {
-			log.Printf("Sending click to window: %s", windowTitle)
-			hWnd := GetWindowHandle(windowTitle)
-			log.Printf("Clicking at: X=%d, Y=%d", x, y)
-			SimulateClick(hWnd, x, y)
+	source, point, found := wcs.sourceWindow(targets, x, y)
+	if found {
+		log.Printf("Click over window %d at relative position (%.3f, %.3f)", source, point.X, point.Y)
+		report.Source, report.Relative = source, &point
+		if settings.ExcludeSource {
+			targets = slices.DeleteFunc(targets, func(window Window) bool {
+				return window.Handle == source
+			})
 		}
+	} else {
+		log.Printf("Click at X=%d, Y=%d is not over a target window, replaying screen coordinates", x, y)
 	}
+	targets = wcs.order(targets, source, settings)
+
+	if settings.Mode != ClickBackground {
+		backend := wcs.windowService.Backend()
+		cursorX, cursorY := backend.GetCursorPos()
+		foreground := backend.GetForegroundWindow()
+		defer wcs.restore(foreground, cursorX, cursorY)
+	}
+
+	report.Targets = make([]TargetTiming, len(targets))
+	if report.Parallel {
+		var wg sync.WaitGroup
+		for i, window := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				report.Targets[i] = wcs.clickWindow(window, &report, settings.pause())
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i, window := range targets {
+			var delay time.Duration
+			if i > 0 {
+				delay = settings.pause()
+			}
+			report.Targets[i] = wcs.clickWindow(window, &report, delay)
+		}
+	}
+	report.DurationMs = milliseconds(time.Since(report.StartedAt))
+
+	wcs.mu.Lock()
+	wcs.lastReport = &report
+	wcs.mu.Unlock()
+	wcs.events.Publish(Event{Type: EventClickReport, Window: report.Source, Data: report})
+	return report
+}
+
+// clickWindow waits for the delay, then clicks the window at the position of
+// the broadcast click.
+func (wcs *WheelClickService) clickWindow(window Window, report *BroadcastReport, delay time.Duration) (timing TargetTiming) {
+	time.Sleep(delay)
+	start := time.Now()
+	windowTitle := window.Title
+	timing = TargetTiming{
+		Window:  window.Handle,
+		Title:   windowTitle,
+		DelayMs: milliseconds(delay),
+		StartMs: milliseconds(start.Sub(report.StartedAt)),
+	}
+	defer func() {
+		timing.DurationMs = milliseconds(time.Since(start))
+	}()
+
+	log.Printf("Sending click to window: %s", windowTitle)
+	hWnd := window.Handle
+	data := ClickData{X: report.X, Y: report.Y}
+	var err error
+	if report.Relative != nil {
+		data.X, data.Y, err = wcs.windowService.RelativeToScreen(hWnd, *report.Relative)
+		if err != nil {
+			log.Printf("Error mapping the click to window %s: %v", windowTitle, err)
+			timing.Error = err.Error()
+			return timing
+		}
+		data.Source, data.Relative = report.Source, report.Relative
+	}
+	timing.X, timing.Y = data.X, data.Y
+	log.Printf("Clicking at: X=%d, Y=%d", data.X, data.Y)
+	if report.Mode == ClickBackground {
+		err = wcs.PostClick(hWnd, data.X, data.Y)
+	} else {
+		err = wcs.SimulateClick(hWnd, data.X, data.Y)
+	}
+	if err != nil {
+		timing.Error = err.Error()
+	}
+	wcs.events.Publish(Event{Type: EventClickBroadcast, Window: hWnd, Title: windowTitle, Data: data})
+	return timing
+}
+
+// targetWindows returns the windows receiving the clicks, in z-order. Each
+// target is a character, whose client is used, or else a part of the title
+// of windows. Windows are identified by handle, so that clients with the
+// same title are all clicked.
+func (wcs *WheelClickService) targetWindows(settings BroadcastSettings) ([]Window, error) {
+	if len(settings.Targets) == 0 {
+		return wcs.windowService.ListWindows(WindowFilter{Title: settings.TitleFilter})
+	}
+
+	windows, err := wcs.windowService.GetWindows()
+	if err != nil {
+		return nil, err
+	}
+	if err := wcs.characterSvc.Refresh(); err != nil {
+		log.Printf("Failed to refresh characters: %v", err)
+	}
+
+	selected := make(map[WindowHandle]bool)
+	for _, target := range settings.Targets {
+		if character, err := wcs.characterSvc.GetCharacter(target); err == nil {
+			if character.Online {
+				selected[character.Handle] = true
+			} else {
+				log.Printf("Broadcast target %s has no open client", character.Name)
+			}
+			continue
+		}
+		for _, window := range windows {
+			if strings.Contains(window.Title, target) {
+				selected[window.Handle] = true
+			}
+		}
+	}
+
+	var targets []Window
+	for _, window := range windows {
+		if selected[window.Handle] {
+			targets = append(targets, window)
+		}
+	}
+	return targets, nil
 }
 
-// GetWindowHandle retrieves the handle of the window with the given title.
-func GetWindowHandle(title string) syscall.Handle {
-	titleUTF16, _ := syscall.UTF16PtrFromString(title)
-	hWnd, _, _ := procFindWindow.Call(0, uintptr(unsafe.Pointer(titleUTF16)))
-	return syscall.Handle(hWnd)
+// order sorts the windows according to the broadcast settings, source being
+// the window clicked.
+func (wcs *WheelClickService) order(windows []Window, source WindowHandle, settings BroadcastSettings) []Window {
+	ordered := append([]Window(nil), windows...)
+	if settings.Order == OrderTeam {
+		if err := wcs.characterSvc.Refresh(); err != nil {
+			log.Printf("Failed to refresh characters: %v", err)
+		}
+		rank := make(map[WindowHandle]int)
+		for i, character := range wcs.characterSvc.GetCharacters() {
+			if character.Online {
+				rank[character.Handle] = i
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, inTeamI := rank[ordered[i].Handle]
+			rj, inTeamJ := rank[ordered[j].Handle]
+			if inTeamI != inTeamJ {
+				return inTeamI
+			}
+			return ri < rj
+		})
+	}
+
+	if source == 0 || settings.Leader == "" {
+		return ordered
+	}
+	for i, window := range ordered {
+		if window.Handle != source {
+			continue
+		}
+		rest := append(append([]Window(nil), ordered[:i]...), ordered[i+1:]...)
+		if settings.Leader == LeaderFirst {
+			return append([]Window{window}, rest...)
+		}
+		return append(rest, window)
+	}
+	return ordered
+}
+
+// restore brings back the foreground window and the cursor position of
+// before a broadcast in the foreground mode.
+func (wcs *WheelClickService) restore(foreground WindowHandle, cursorX, cursorY int) {
+	backend := wcs.windowService.Backend()
+	if err := backend.SetCursorPos(cursorX, cursorY); err != nil {
+		log.Printf("Failed to restore the cursor position: %v", err)
+	}
+	if foreground != 0 && backend.GetForegroundWindow() != foreground {
+		if err := backend.SetForegroundWindow(foreground); err != nil {
+			log.Printf("Failed to restore the foreground window: %v", err)
+		}
+	}
+}
+
+// sourceWindow returns the topmost of the windows whose client area contains
+// the screen coordinates, and the position relative to it.
+func (wcs *WheelClickService) sourceWindow(windows []Window, x, y int) (WindowHandle, RelativePoint, bool) {
+	for _, window := range windows {
+		if window.Minimized {
+			continue
+		}
+		point, err := wcs.windowService.ScreenToRelative(window.Handle, x, y)
+		if err == nil && point.Inside() {
+			return window.Handle, point, true
+		}
+	}
+	return 0, RelativePoint{}, false
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+)
+
+// newFakeBroadcast opens two game clients side by side and returns a wheel
+// click service broadcasting to them in the background.
+func newFakeBroadcast(t *testing.T, settings BroadcastSettings) (*FakeWindowBackend, *WheelClickService, WindowHandle, WindowHandle) {
+	t.Helper()
+	fake := NewFakeWindowBackend()
+	right := fake.AddWindow("Beta - Dofus 2.70.5")
+	left := fake.AddWindow("Alpha - Dofus 2.70.5")
+	fake.UpdateWindow(right, func(w *Window) {
+		w.Rect = Rect{Left: 800, Right: 1600, Bottom: 600}
+	})
+	fake.AddWindow("Notepad")
+
+	ws := NewWindowService(fake)
+	wcs := NewWheelClickService(ws, NewCharacterService(ws), NewInputDispatcher(), NewEventBus())
+	if settings.TitleFilter == "" {
+		settings.TitleFilter = "Dofus"
+	}
+	settings.Mode = ClickBackground
+	wcs.SetBroadcastSettings(settings)
+	return fake, wcs, left, right
+}
+
+func TestBroadcastMapsTheClickToEveryClient(t *testing.T) {
+	fake, wcs, left, right := newFakeBroadcast(t, BroadcastSettings{})
+
+	report := wcs.SendClickToDofusWindows(200, 150)
+	if report.Source != left {
+		t.Fatalf("source = %d, want %d", report.Source, left)
+	}
+
+	want := []FakeClick{
+		{Window: left, X: 200, Y: 150, Posted: true},
+		{Window: right, X: 200, Y: 150, Posted: true},
+	}
+	clicks := fake.Clicks()
+	if len(clicks) != len(want) {
+		t.Fatalf("clicks = %+v, want %+v", clicks, want)
+	}
+	for i := range want {
+		if clicks[i] != want[i] {
+			t.Errorf("click #%d = %+v, want %+v", i, clicks[i], want[i])
+		}
+	}
+	if fake.GetForegroundWindow() != 0 {
+		t.Errorf("background broadcast changed the foreground window to %d", fake.GetForegroundWindow())
+	}
+}
+
+func TestBroadcastExcludesTheSource(t *testing.T) {
+	fake, wcs, _, right := newFakeBroadcast(t, BroadcastSettings{ExcludeSource: true})
+
+	wcs.SendClickToDofusWindows(100, 100)
+	clicks := fake.Clicks()
+	if len(clicks) != 1 || clicks[0].Window != right {
+		t.Fatalf("clicks = %+v, want a single click on %d", clicks, right)
+	}
+}
+
+func TestBroadcastOrdersTheLeaderLast(t *testing.T) {
+	fake, wcs, left, right := newFakeBroadcast(t, BroadcastSettings{Order: OrderTeam, Leader: LeaderLast})
+
+	report := wcs.SendClickToDofusWindows(100, 100)
+	clicks := fake.Clicks()
+	if len(clicks) != 2 || clicks[0].Window != right || clicks[1].Window != left {
+		t.Fatalf("clicks = %+v, want %d then %d", clicks, right, left)
+	}
+	if len(report.Targets) != 2 || report.Targets[1].Window != left {
+		t.Fatalf("report targets = %+v, want the source last", report.Targets)
+	}
+}
+
+func TestBroadcastOutsideClientsReplaysScreenCoordinates(t *testing.T) {
+	fake, wcs, _, _ := newFakeBroadcast(t, BroadcastSettings{})
+
+	report := wcs.SendClickToDofusWindows(1700, 700)
+	if report.Source != 0 || report.Relative != nil {
+		t.Fatalf("report = %+v, want no source", report)
+	}
+	for _, click := range fake.Clicks() {
+		window, _ := fake.GetWindowInfo(click.Window)
+		if click.X != 1700-window.Rect.Left || click.Y != 700-window.Rect.Top {
+			t.Errorf("click = %+v, want the screen point (1700, 700) in client coordinates", click)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"os"
+)
+
+// WindowHandle identifies a top-level window independently of the platform
+// (HWND on Windows).
+type WindowHandle uintptr
+
+// ShellEventCode identifies the kind of shell notification received for a window.
+type ShellEventCode int
+
+const (
+	ShellWindowCreated ShellEventCode = iota + 1
+	ShellWindowDestroyed
+	ShellWindowActivated
+	ShellWindowFlash
+	// ShellWindowRedraw reports a window redrawn, e.g. after its title changed.
+	ShellWindowRedraw
+)
+
+// ShellEvent is a shell notification (window created, destroyed, activated,
+// requesting attention or redrawn) delivered by a WindowBackend.
+type ShellEvent struct {
+	Code   ShellEventCode
+	Window WindowHandle
+}
+
+// WindowBackend abstracts the windowing system used by the services, so that
+// focus and broadcast logic does not depend on user32 directly.
+type WindowBackend interface {
+	// EnumWindows returns the top-level windows in z-order.
+	EnumWindows() ([]WindowHandle, error)
+	GetWindowText(hwnd WindowHandle) string
+	// GetWindowInfo describes a window, Foreground excepted.
+	GetWindowInfo(hwnd WindowHandle) (Window, error)
+	GetForegroundWindow() WindowHandle
+	SetForegroundWindow(hwnd WindowHandle) error
+	IsMinimized(hwnd WindowHandle) bool
+	RestoreWindow(hwnd WindowHandle) error
+	// MoveWindow restores hwnd if it is minimized and gives it the window
+	// rectangle rect, decorations included, without activating it.
+	MoveWindow(hwnd WindowHandle, rect Rect) error
+	// Monitors describes the displays. Coordinates of every method are
+	// physical pixels, whatever the scaling of the monitors.
+	Monitors() ([]Monitor, error)
+	// CaptureClient copies the client area of hwnd, including the parts
+	// covered by other windows.
+	CaptureClient(hwnd WindowHandle) (*image.RGBA, error)
+
+	GetCursorPos() (int, int)
+	SetCursorPos(x, y int) error
+	// ScreenToClient converts screen coordinates to the client area of hwnd.
+	ScreenToClient(hwnd WindowHandle, x, y int) (int, int, error)
+	// ClientToScreen converts client coordinates of hwnd to screen coordinates.
+	ClientToScreen(hwnd WindowHandle, x, y int) (int, int, error)
+	// SendClick delivers a left click at client coordinates of hwnd.
+	SendClick(hwnd WindowHandle, x, y int) error
+	// PostClick delivers a left click at client coordinates of hwnd as
+	// mouse messages, without moving the cursor nor activating the window.
+	PostClick(hwnd WindowHandle, x, y int) error
+	// PostMouse delivers the press (InputMouseDown) or release
+	// (InputMouseUp) of ev.Button at client coordinates of hwnd as mouse
+	// messages, without moving the cursor nor activating the window.
+	PostMouse(hwnd WindowHandle, ev InputEvent, x, y int) error
+	// PostKey delivers a key event (InputKeyDown, InputKeyUp or
+	// InputKeyTyped) to hwnd as key messages, without activating it.
+	PostKey(hwnd WindowHandle, ev InputEvent) error
+
+	// WatchShellEvents streams shell notifications until stop is closed, at
+	// which point the returned channel is closed.
+	WatchShellEvents(stop <-chan struct{}) (<-chan ShellEvent, error)
+}
+
+// Names of the window backends selectable at startup.
+const (
+	BackendAuto  = "auto"
+	BackendWin32 = "win32"
+	BackendX11   = "x11"
+	BackendFake  = "fake"
+)
+
+// NewWindowBackend creates the window backend with the given name, the
+// platform default being used for BackendAuto.
+func NewWindowBackend(name string) (WindowBackend, error) {
+	switch name {
+	case "", BackendAuto:
+		return NewDefaultWindowBackend()
+	case BackendWin32:
+		return newWin32Backend()
+	case BackendX11:
+		return NewX11Backend(os.Getenv("DISPLAY"))
+	case BackendFake:
+		return NewFakeWindowBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown window backend: %s", name)
+	}
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"sync"
+)
+
+// FakeClick records a click delivered through a FakeWindowBackend.
+type FakeClick struct {
+	Window WindowHandle
+	X      int
+	Y      int
+	// Posted is set for clicks delivered with PostClick or PostMouse.
+	Posted bool
+	// Kind and Button are those of the button events delivered with
+	// PostMouse, Kind is 0 for clicks.
+	Kind   InputEventKind
+	Button MouseButton
+}
+
+// FakeKey records a key event delivered through a FakeWindowBackend.
+type FakeKey struct {
+	Window  WindowHandle
+	Kind    InputEventKind
+	Keycode uint16
+	Keychar rune
+}
+
+type fakeWindow struct {
+	handle WindowHandle
+	info   Window
+	image  image.Image
+}
+
+// FakeWindowBackend is an in-memory WindowBackend. Windows, focus and shell
+// events are scripted by the caller, and every focus change and click is
+// recorded so that service logic can be checked without a desktop.
+type FakeWindowBackend struct {
+	mu           sync.Mutex
+	windows      []*fakeWindow
+	nextHandle   WindowHandle
+	foreground   WindowHandle
+	monitors     []Monitor
+	cursorX      int
+	cursorY      int
+	clicks       []FakeClick
+	keys         []FakeKey
+	focusHistory []WindowHandle
+	watchers     map[chan ShellEvent]struct{}
+}
+
+// NewFakeWindowBackend creates a new instance of the FakeWindowBackend.
+func NewFakeWindowBackend() *FakeWindowBackend {
+	return &FakeWindowBackend{
+		nextHandle: 1,
+		monitors: []Monitor{{
+			Name:     "FAKE1",
+			Primary:  true,
+			Bounds:   Rect{Right: 1920, Bottom: 1080},
+			WorkArea: Rect{Right: 1920, Bottom: 1080},
+			DPI:      BaseDPI,
+			Scale:    1,
+		}},
+		watchers: make(map[chan ShellEvent]struct{}),
+	}
+}
+
+// AddWindow opens a visible 800x600 window with the given title on top of the others.
+func (f *FakeWindowBackend) AddWindow(title string) WindowHandle {
+	f.mu.Lock()
+	hwnd := f.nextHandle
+	f.nextHandle++
+	info := Window{
+		Handle:       hwnd,
+		Title:        title,
+		PID:          uint32(1000 + hwnd),
+		Rect:         Rect{Right: 800, Bottom: 600},
+		ClientWidth:  800,
+		ClientHeight: 600,
+		Visible:      true,
+	}
+	f.windows = append([]*fakeWindow{{handle: hwnd, info: info}}, f.windows...)
+	f.mu.Unlock()
+
+	f.Emit(ShellEvent{Code: ShellWindowCreated, Window: hwnd})
+	return hwnd
+}
+
+// CloseWindow removes a window.
+func (f *FakeWindowBackend) CloseWindow(hwnd WindowHandle) {
+	f.mu.Lock()
+	for i, w := range f.windows {
+		if w.handle == hwnd {
+			f.windows = append(f.windows[:i], f.windows[i+1:]...)
+			break
+		}
+	}
+	if f.foreground == hwnd {
+		f.foreground = 0
+	}
+	f.mu.Unlock()
+
+	f.Emit(ShellEvent{Code: ShellWindowDestroyed, Window: hwnd})
+}
+
+// UpdateWindow changes the description of a window (title, process, rectangle...).
+func (f *FakeWindowBackend) UpdateWindow(hwnd WindowHandle, update func(w *Window)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.find(hwnd); w != nil {
+		update(&w.info)
+		w.info.Handle = hwnd
+	}
+}
+
+// SetWindowTitle changes the title of a window, reported as a redraw.
+func (f *FakeWindowBackend) SetWindowTitle(hwnd WindowHandle, title string) {
+	f.UpdateWindow(hwnd, func(w *Window) { w.Title = title })
+	f.Emit(ShellEvent{Code: ShellWindowRedraw, Window: hwnd})
+}
+
+// MinimizeWindow minimizes a window.
+func (f *FakeWindowBackend) MinimizeWindow(hwnd WindowHandle) {
+	f.UpdateWindow(hwnd, func(w *Window) { w.Minimized = true })
+}
+
+// SetWindowImage sets the content of the client area of a window, drawn at
+// its top-left corner by CaptureClient. Windows without content are filled
+// with a color derived from their handle.
+func (f *FakeWindowBackend) SetWindowImage(hwnd WindowHandle, img image.Image) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.find(hwnd); w != nil {
+		w.image = img
+	}
+}
+
+// SetMonitors replaces the monitors, a single 1920x1080 one at 100% scaling
+// by default.
+func (f *FakeWindowBackend) SetMonitors(monitors []Monitor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.monitors = append([]Monitor(nil), monitors...)
+}
+
+// Flash makes a window request attention, as a game client does when a turn starts.
+func (f *FakeWindowBackend) Flash(hwnd WindowHandle) {
+	f.Emit(ShellEvent{Code: ShellWindowFlash, Window: hwnd})
+}
+
+// Emit delivers a shell event to every watcher. As with the real backends,
+// the event is dropped for a watcher whose buffer is full, so that a slow
+// consumer calling back into the backend cannot block it.
+func (f *FakeWindowBackend) Emit(event ShellEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for watcher := range f.watchers {
+		select {
+		case watcher <- event:
+		default:
+			log.Printf("Shell event dropped, consumer is too slow: %+v", event)
+		}
+	}
+}
+
+// Clicks returns the clicks and mouse button events delivered so far.
+func (f *FakeWindowBackend) Clicks() []FakeClick {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeClick(nil), f.clicks...)
+}
+
+// Keys returns the key events delivered so far, in order.
+func (f *FakeWindowBackend) Keys() []FakeKey {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeKey(nil), f.keys...)
+}
+
+// FocusHistory returns the windows brought to the foreground so far, in order.
+func (f *FakeWindowBackend) FocusHistory() []WindowHandle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]WindowHandle(nil), f.focusHistory...)
+}
+
+func (f *FakeWindowBackend) find(hwnd WindowHandle) *fakeWindow {
+	for _, w := range f.windows {
+		if w.handle == hwnd {
+			return w
+		}
+	}
+	return nil
+}
+
+func (f *FakeWindowBackend) EnumWindows() ([]WindowHandle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	handles := make([]WindowHandle, 0, len(f.windows))
+	for _, w := range f.windows {
+		handles = append(handles, w.handle)
+	}
+	return handles, nil
+}
+
+func (f *FakeWindowBackend) GetWindowText(hwnd WindowHandle) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.find(hwnd); w != nil {
+		return w.info.Title
+	}
+	return ""
+}
+
+func (f *FakeWindowBackend) GetWindowInfo(hwnd WindowHandle) (Window, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return Window{}, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	return w.info, nil
+}
+
+func (f *FakeWindowBackend) GetForegroundWindow() WindowHandle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.foreground
+}
+
+func (f *FakeWindowBackend) SetForegroundWindow(hwnd WindowHandle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.find(hwnd) == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	f.foreground = hwnd
+	f.focusHistory = append(f.focusHistory, hwnd)
+	return nil
+}
+
+func (f *FakeWindowBackend) IsMinimized(hwnd WindowHandle) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	return w != nil && w.info.Minimized
+}
+
+func (f *FakeWindowBackend) RestoreWindow(hwnd WindowHandle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	w.info.Minimized = false
+	return nil
+}
+
+// MoveWindow resizes the client area along with the window, which has no
+// decorations.
+func (f *FakeWindowBackend) MoveWindow(hwnd WindowHandle, rect Rect) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	w.info.Minimized = false
+	w.info.Rect = rect
+	w.info.ClientWidth, w.info.ClientHeight = rect.Width(), rect.Height()
+	return nil
+}
+
+func (f *FakeWindowBackend) Monitors() ([]Monitor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Monitor(nil), f.monitors...), nil
+}
+
+func (f *FakeWindowBackend) CaptureClient(hwnd WindowHandle) (*image.RGBA, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return nil, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	if w.info.ClientWidth <= 0 || w.info.ClientHeight <= 0 {
+		return nil, fmt.Errorf("window %d has an empty client area", hwnd)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w.info.ClientWidth, w.info.ClientHeight))
+	fill := color.RGBA{R: uint8(hwnd * 80), G: uint8(hwnd * 150), B: uint8(hwnd * 40), A: 0xFF}
+	draw.Draw(img, img.Bounds(), image.NewUniform(fill), image.Point{}, draw.Src)
+	if w.image != nil {
+		draw.Draw(img, img.Bounds(), w.image, w.image.Bounds().Min, draw.Src)
+	}
+	return img, nil
+}
+
+func (f *FakeWindowBackend) GetCursorPos() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cursorX, f.cursorY
+}
+
+func (f *FakeWindowBackend) SetCursorPos(x, y int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cursorX, f.cursorY = x, y
+	return nil
+}
+
+// ScreenToClient treats the window rectangle as the client area.
+func (f *FakeWindowBackend) ScreenToClient(hwnd WindowHandle, x, y int) (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return x, y, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	return x - w.info.Rect.Left, y - w.info.Rect.Top, nil
+}
+
+// ClientToScreen treats the window rectangle as the client area.
+func (f *FakeWindowBackend) ClientToScreen(hwnd WindowHandle, x, y int) (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.find(hwnd)
+	if w == nil {
+		return x, y, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	return x + w.info.Rect.Left, y + w.info.Rect.Top, nil
+}
+
+func (f *FakeWindowBackend) SendClick(hwnd WindowHandle, x, y int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.find(hwnd) == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	f.clicks = append(f.clicks, FakeClick{Window: hwnd, X: x, Y: y})
+	return nil
+}
+
+func (f *FakeWindowBackend) PostClick(hwnd WindowHandle, x, y int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.find(hwnd) == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	f.clicks = append(f.clicks, FakeClick{Window: hwnd, X: x, Y: y, Posted: true})
+	return nil
+}
+
+func (f *FakeWindowBackend) PostMouse(hwnd WindowHandle, ev InputEvent, x, y int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.find(hwnd) == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	f.clicks = append(f.clicks, FakeClick{Window: hwnd, X: x, Y: y, Posted: true, Kind: ev.Kind, Button: ev.Button})
+	return nil
+}
+
+func (f *FakeWindowBackend) PostKey(hwnd WindowHandle, ev InputEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.find(hwnd) == nil {
+		return fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	f.keys = append(f.keys, FakeKey{Window: hwnd, Kind: ev.Kind, Keycode: ev.Keycode, Keychar: ev.Keychar})
+	return nil
+}
+
+func (f *FakeWindowBackend) WatchShellEvents(stop <-chan struct{}) (<-chan ShellEvent, error) {
+	watcher := make(chan ShellEvent, 64)
+
+	f.mu.Lock()
+	f.watchers[watcher] = struct{}{}
+	f.mu.Unlock()
+
+	go func() {
+		<-stop
+		f.mu.Lock()
+		delete(f.watchers, watcher)
+		close(watcher)
+		f.mu.Unlock()
+	}()
+
+	return watcher, nil
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package services
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// NewDefaultWindowBackend returns the window backend of the current platform.
+func NewDefaultWindowBackend() (WindowBackend, error) {
+	return NewX11Backend(os.Getenv("DISPLAY"))
+}
+
+func newWin32Backend() (WindowBackend, error) {
+	return nil, fmt.Errorf("win32 window backend is not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	SW_RESTORE = 9
+
+	WM_MOUSEMOVE   = 0x0200
+	WM_LBUTTONDOWN = 0x0201
+	WM_LBUTTONUP   = 0x0202
+	WM_RBUTTONDOWN = 0x0204
+	WM_RBUTTONUP   = 0x0205
+	WM_MBUTTONDOWN = 0x0207
+	WM_MBUTTONUP   = 0x0208
+	WM_XBUTTONDOWN = 0x020B
+	WM_XBUTTONUP   = 0x020C
+	WM_MOUSEHOVER  = 0x02A1
+
+	MK_LBUTTON  = 0x0001
+	MK_RBUTTON  = 0x0002
+	MK_MBUTTON  = 0x0010
+	MK_XBUTTON1 = 0x0020
+	MK_XBUTTON2 = 0x0040
+
+	XBUTTON1 = 0x0001
+	XBUTTON2 = 0x0002
+
+	SWP_NOZORDER   = 0x0004
+	SWP_NOACTIVATE = 0x0010
+
+	PW_CLIENTONLY        = 0x1
+	PW_RENDERFULLCONTENT = 0x2
+	BI_RGB               = 0
+	DIB_RGB_COLORS       = 0
+
+	MONITORINFOF_PRIMARY = 0x1
+	MDT_EFFECTIVE_DPI    = 0
+
+	WM_KEYDOWN = 0x0100
+	WM_KEYUP   = 0x0101
+	WM_CHAR    = 0x0102
+)
+
+var (
+	procGetClientRect       = user32.NewProc("GetClientRect")
+	procIsWindowVisible     = user32.NewProc("IsWindowVisible")
+	procGetClassNameW       = user32.NewProc("GetClassNameW")
+	procIsWindow            = user32.NewProc("IsWindow")
+	procSetWindowPos        = user32.NewProc("SetWindowPos")
+	procEnumDisplayMonitors = user32.NewProc("EnumDisplayMonitors")
+	procGetMonitorInfoW     = user32.NewProc("GetMonitorInfoW")
+	procGetDC               = user32.NewProc("GetDC")
+	procReleaseDC           = user32.NewProc("ReleaseDC")
+
+	procSetProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext")
+	procSetProcessDPIAware            = user32.NewProc("SetProcessDPIAware")
+	procSetProcessDpiAwareness        = syscall.NewLazyDLL("shcore.dll").NewProc("SetProcessDpiAwareness")
+	procGetDpiForMonitor              = syscall.NewLazyDLL("shcore.dll").NewProc("GetDpiForMonitor")
+)
+
+// RECT structure for window rectangles
+type RECT struct {
+	Left, Top, Right, Bottom int32
+}
+
+// Win32Backend implements WindowBackend on top of user32.
+type Win32Backend struct{}
+
+// NewWin32Backend creates a new instance of the Win32Backend. The process is
+// made per-monitor DPI aware, so that window rectangles, cursor positions
+// and hooked mouse events all are in physical pixels. Otherwise Windows
+// scales the coordinates of the first but not of the last on monitors above
+// 100%, and clicks land off target.
+func NewWin32Backend() *Win32Backend {
+	dpiAwareness.Do(enableDPIAwareness)
+	return &Win32Backend{}
+}
+
+var dpiAwareness sync.Once
+
+// DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2, a pseudo handle.
+const dpiAwarenessContextPerMonitorV2 = ^uintptr(3)
+
+// enableDPIAwareness declares the process per-monitor DPI aware with the API
+// of the running Windows version: 10 (1703), 8.1, then Vista which only
+// knows about the system DPI.
+func enableDPIAwareness() {
+	if procSetProcessDpiAwarenessContext.Find() == nil {
+		if ret, _, _ := procSetProcessDpiAwarenessContext.Call(dpiAwarenessContextPerMonitorV2); ret != 0 {
+			return
+		}
+	}
+	if procSetProcessDpiAwareness.Find() == nil {
+		// PROCESS_PER_MONITOR_DPI_AWARE
+		if ret, _, _ := procSetProcessDpiAwareness.Call(2); ret == 0 {
+			return
+		}
+	}
+	if ret, _, _ := procSetProcessDPIAware.Call(); ret == 0 {
+		log.Println("Failed to make the process DPI aware, clicks may land off target on scaled monitors")
+	}
+}
+
+// NewDefaultWindowBackend returns the window backend of the current platform.
+func NewDefaultWindowBackend() (WindowBackend, error) {
+	return NewWin32Backend(), nil
+}
+
+func newWin32Backend() (WindowBackend, error) {
+	return NewWin32Backend(), nil
+}
+
+var (
+	// enumMutex serializes enumerations, which share a single callback:
+	// syscall.NewCallback cannot be called for every enumeration.
+	enumMutex    sync.Mutex
+	enumHandles  []WindowHandle
+	enumCallback = syscall.NewCallback(func(hwnd syscall.Handle, lParam uintptr) uintptr {
+		enumHandles = append(enumHandles, WindowHandle(hwnd))
+		return 1 // Continue enumeration
+	})
+)
+
+func (b *Win32Backend) EnumWindows() ([]WindowHandle, error) {
+	enumMutex.Lock()
+	defer enumMutex.Unlock()
+
+	enumHandles = nil
+	ret, _, err := procEnumWindows.Call(enumCallback, 0)
+	if ret == 0 && err != syscall.Errno(0) {
+		return nil, fmt.Errorf("error enumerating windows: %v", err)
+	}
+
+	handles := enumHandles
+	enumHandles = nil
+	return handles, nil
+}
+
+func (b *Win32Backend) GetWindowText(hwnd WindowHandle) string {
+	return GetWindowText(syscall.Handle(hwnd))
+}
+
+func (b *Win32Backend) GetWindowInfo(hwnd WindowHandle) (Window, error) {
+	if ret, _, _ := procIsWindow.Call(uintptr(hwnd)); ret == 0 {
+		return Window{}, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+
+	window := Window{
+		Handle:    hwnd,
+		Title:     GetWindowText(syscall.Handle(hwnd)),
+		ClassName: getClassName(hwnd),
+		Minimized: IsIconic(syscall.Handle(hwnd)),
+	}
+
+	var rect RECT
+	procGetWindowRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&rect)))
+	window.Rect = Rect{Left: int(rect.Left), Top: int(rect.Top), Right: int(rect.Right), Bottom: int(rect.Bottom)}
+
+	var clientRect RECT
+	procGetClientRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&clientRect)))
+	window.ClientWidth = int(clientRect.Right - clientRect.Left)
+	window.ClientHeight = int(clientRect.Bottom - clientRect.Top)
+
+	ret, _, _ := procIsWindowVisible.Call(uintptr(hwnd))
+	window.Visible = ret != 0
+
+	_, window.PID = GetWindowThreadProcessId(syscall.Handle(hwnd))
+	window.ExeName = getProcessExeName(window.PID)
+
+	return window, nil
+}
+
+// getClassName returns the window class name of hwnd.
+func getClassName(hwnd WindowHandle) string {
+	buf := make([]uint16, 256)
+	ret, _, _ := procGetClassNameW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:ret])
+}
+
+// getProcessExeName returns the executable file name of a process, or an
+// empty string when the process cannot be queried.
+func getProcessExeName(pid uint32) string {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(process)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(process, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return filepath.Base(syscall.UTF16ToString(buf[:size]))
+}
+
+func (b *Win32Backend) GetForegroundWindow() WindowHandle {
+	return WindowHandle(GetForegroundWindow())
+}
+
+func (b *Win32Backend) SetForegroundWindow(hwnd WindowHandle) error {
+	target := syscall.Handle(hwnd)
+	if SetForegroundWindow(target) {
+		return nil
+	}
+
+	fgWindow := GetForegroundWindow()
+	if fgWindow == 0 {
+		return fmt.Errorf("failed to retrieve the foreground window")
+	}
+
+	fgThreadID, _ := GetWindowThreadProcessId(fgWindow)
+	targetThreadID, _ := GetWindowThreadProcessId(target)
+
+	if AttachThreadInput(fgThreadID, targetThreadID, true) {
+		defer AttachThreadInput(fgThreadID, targetThreadID, false)
+		if SetForegroundWindow(target) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to set window to the foreground")
+}
+
+func (b *Win32Backend) IsMinimized(hwnd WindowHandle) bool {
+	return IsIconic(syscall.Handle(hwnd))
+}
+
+func (b *Win32Backend) RestoreWindow(hwnd WindowHandle) error {
+	ShowWindow(syscall.Handle(hwnd), SW_RESTORE)
+	return nil
+}
+
+func (b *Win32Backend) MoveWindow(hwnd WindowHandle, rect Rect) error {
+	if IsIconic(syscall.Handle(hwnd)) {
+		ShowWindow(syscall.Handle(hwnd), SW_RESTORE)
+	}
+	ret, _, err := procSetWindowPos.Call(uintptr(hwnd), 0,
+		uintptr(int32(rect.Left)), uintptr(int32(rect.Top)), uintptr(int32(rect.Width())), uintptr(int32(rect.Height())),
+		SWP_NOZORDER|SWP_NOACTIVATE)
+	if ret == 0 {
+		return fmt.Errorf("failed to move window %d: %v", hwnd, err)
+	}
+	return nil
+}
+
+var (
+	// monitorMutex serializes monitor enumerations, which share a single
+	// callback like window enumerations.
+	monitorMutex    sync.Mutex
+	monitorHandles  []uintptr
+	monitorCallback = syscall.NewCallback(func(hMonitor, hdc, rect, lParam uintptr) uintptr {
+		monitorHandles = append(monitorHandles, hMonitor)
+		return 1 // Continue enumeration
+	})
+)
+
+// MONITORINFOEXW structure for GetMonitorInfoW
+type MONITORINFOEXW struct {
+	CbSize    uint32
+	RcMonitor RECT
+	RcWork    RECT
+	DwFlags   uint32
+	SzDevice  [32]uint16
+}
+
+func (b *Win32Backend) Monitors() ([]Monitor, error) {
+	monitorMutex.Lock()
+	monitorHandles = nil
+	ret, _, err := procEnumDisplayMonitors.Call(0, 0, monitorCallback, 0)
+	handles := monitorHandles
+	monitorHandles = nil
+	monitorMutex.Unlock()
+	if ret == 0 {
+		return nil, fmt.Errorf("error enumerating monitors: %v", err)
+	}
+
+	monitors := make([]Monitor, 0, len(handles))
+	for _, hMonitor := range handles {
+		info := MONITORINFOEXW{CbSize: uint32(unsafe.Sizeof(MONITORINFOEXW{}))}
+		if ret, _, _ := procGetMonitorInfoW.Call(hMonitor, uintptr(unsafe.Pointer(&info))); ret == 0 {
+			continue
+		}
+		monitor := Monitor{
+			Name:     syscall.UTF16ToString(info.SzDevice[:]),
+			Primary:  info.DwFlags&MONITORINFOF_PRIMARY != 0,
+			Bounds:   Rect{Left: int(info.RcMonitor.Left), Top: int(info.RcMonitor.Top), Right: int(info.RcMonitor.Right), Bottom: int(info.RcMonitor.Bottom)},
+			WorkArea: Rect{Left: int(info.RcWork.Left), Top: int(info.RcWork.Top), Right: int(info.RcWork.Right), Bottom: int(info.RcWork.Bottom)},
+			DPI:      BaseDPI,
+		}
+		// GetDpiForMonitor is missing before Windows 8.1.
+		var dpiX, dpiY uint32
+		if procGetDpiForMonitor.Find() == nil {
+			if ret, _, _ := procGetDpiForMonitor.Call(hMonitor, MDT_EFFECTIVE_DPI, uintptr(unsafe.Pointer(&dpiX)), uintptr(unsafe.Pointer(&dpiY))); ret == 0 {
+				monitor.DPI = int(dpiX)
+			}
+		}
+		monitor.Scale = float64(monitor.DPI) / BaseDPI
+		monitors = append(monitors, monitor)
+	}
+	return monitors, nil
+}
+
+// BITMAPINFO structure for GetDIBits, without a color table as the bitmaps
+// are 32 bits per pixel.
+type BITMAPINFO struct {
+	BiSize          uint32
+	BiWidth         int32
+	BiHeight        int32
+	BiPlanes        uint16
+	BiBitCount      uint16
+	BiCompression   uint32
+	BiSizeImage     uint32
+	BiXPelsPerMeter int32
+	BiYPelsPerMeter int32
+	BiClrUsed       uint32
+	BiClrImportant  uint32
+	BmiColors       [1]uint32
+}
+
+// CaptureClient has the window paint its client area into a memory bitmap
+// with PrintWindow, which works for covered windows. PW_RENDERFULLCONTENT
+// is needed for clients drawn with DirectX, and is ignored before Windows 8.1.
+func (b *Win32Backend) CaptureClient(hwnd WindowHandle) (*image.RGBA, error) {
+	var clientRect RECT
+	if ret, _, err := procGetClientRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&clientRect))); ret == 0 {
+		return nil, fmt.Errorf("failed to get the client area of window %d: %v", hwnd, err)
+	}
+	width, height := int(clientRect.Right-clientRect.Left), int(clientRect.Bottom-clientRect.Top)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("window %d has an empty client area", hwnd)
+	}
+
+	screenDC, _, _ := procGetDC.Call(0)
+	if screenDC == 0 {
+		return nil, fmt.Errorf("failed to get the screen device context")
+	}
+	defer procReleaseDC.Call(0, screenDC)
+	memoryDC, _, _ := procCreateCompatibleDC.Call(screenDC)
+	if memoryDC == 0 {
+		return nil, fmt.Errorf("failed to create a memory device context")
+	}
+	defer procDeleteDC.Call(memoryDC)
+	bitmap, _, _ := procCreateCompatibleBitmap.Call(screenDC, uintptr(width), uintptr(height))
+	if bitmap == 0 {
+		return nil, fmt.Errorf("failed to create a %dx%d bitmap", width, height)
+	}
+	defer procDeleteObject.Call(bitmap)
+
+	previous, _, _ := procSelectObject.Call(memoryDC, bitmap)
+	ret, _, err := procPrintWindow.Call(uintptr(hwnd), memoryDC, PW_CLIENTONLY|PW_RENDERFULLCONTENT)
+	// GetDIBits requires the bitmap not to be selected into a device context.
+	procSelectObject.Call(memoryDC, previous)
+	if ret == 0 {
+		return nil, fmt.Errorf("failed to capture window %d: %v", hwnd, err)
+	}
+
+	// A negative height asks for top-down rows, as in image.RGBA.
+	info := BITMAPINFO{
+		BiWidth:       int32(width),
+		BiHeight:      -int32(height),
+		BiPlanes:      1,
+		BiBitCount:    32,
+		BiCompression: BI_RGB,
+	}
+	info.BiSize = uint32(unsafe.Offsetof(info.BmiColors))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	lines, _, _ := procGetDIBits.Call(memoryDC, bitmap, 0, uintptr(height),
+		uintptr(unsafe.Pointer(&img.Pix[0])), uintptr(unsafe.Pointer(&info)), DIB_RGB_COLORS)
+	if int(lines) != height {
+		return nil, fmt.Errorf("failed to read the capture of window %d", hwnd)
+	}
+
+	// The pixels are BGRX, the alpha byte being undefined.
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+2], img.Pix[i+3] = img.Pix[i+2], img.Pix[i], 0xFF
+	}
+	return img, nil
+}
+
+func (b *Win32Backend) GetCursorPos() (int, int) {
+	var pt Point
+	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+	return int(pt.X), int(pt.Y)
+}
+
+func (b *Win32Backend) SetCursorPos(x, y int) error {
+	ret, _, _ := procSetCursorPos.Call(uintptr(x), uintptr(y))
+	if ret == 0 {
+		return fmt.Errorf("failed to set cursor position to X=%d, Y=%d", x, y)
+	}
+	return nil
+}
+
+func (b *Win32Backend) ScreenToClient(hwnd WindowHandle, x, y int) (int, int, error) {
+	point := Point{X: int32(x), Y: int32(y)}
+
+	ret, _, _ := procScreenToClient.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&point)))
+	if ret == 0 {
+		return x, y, fmt.Errorf("failed to convert screen coordinates to client coordinates")
+	}
+	return int(point.X), int(point.Y), nil
+}
+
+func (b *Win32Backend) ClientToScreen(hwnd WindowHandle, x, y int) (int, int, error) {
+	point := Point{X: int32(x), Y: int32(y)}
+
+	ret, _, _ := procClientToScreen.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&point)))
+	if ret == 0 {
+		return x, y, fmt.Errorf("failed to convert client coordinates to screen coordinates")
+	}
+	return int(point.X), int(point.Y), nil
+}
+
+func (b *Win32Backend) SendClick(hwnd WindowHandle, x, y int) error {
+	lParam := makeLParam(x, y)
+	procSendMessage.Call(uintptr(hwnd), WM_LBUTTONDOWN, 0, lParam)
+	time.Sleep(15 * time.Millisecond)
+	procSendMessage.Call(uintptr(hwnd), WM_LBUTTONUP, 0, lParam)
+	return nil
+}
+
+// PostClick posts the messages of a pointer moving over the client area and
+// clicking, so that hover effects are triggered as for a real click.
+func (b *Win32Backend) PostClick(hwnd WindowHandle, x, y int) error {
+	lParam := makeLParam(x, y)
+	messages := []struct {
+		msg    uint32
+		wParam uintptr
+	}{
+		{WM_MOUSEMOVE, 0},
+		{WM_MOUSEHOVER, 0},
+		{WM_LBUTTONDOWN, MK_LBUTTON},
+		{WM_LBUTTONUP, 0},
+	}
+	for i, m := range messages {
+		if i == len(messages)-1 {
+			time.Sleep(15 * time.Millisecond)
+		}
+		ret, _, err := procPostMessage.Call(uintptr(hwnd), uintptr(m.msg), m.wParam, lParam)
+		if ret == 0 {
+			return fmt.Errorf("failed to post mouse message 0x%04x: %v", m.msg, err)
+		}
+	}
+	return nil
+}
+
+// PostMouse posts the message of a button pressed or released, preceded by
+// the pointer moving over the client area for a press. The wParam of the
+// messages holds the buttons down after the event.
+func (b *Win32Backend) PostMouse(hwnd WindowHandle, ev InputEvent, x, y int) error {
+	var down, up uint32
+	var state, xbutton uintptr
+	switch ev.Button {
+	case MouseLeft:
+		down, up, state = WM_LBUTTONDOWN, WM_LBUTTONUP, MK_LBUTTON
+	case MouseRight:
+		down, up, state = WM_RBUTTONDOWN, WM_RBUTTONUP, MK_RBUTTON
+	case MouseMiddle:
+		down, up, state = WM_MBUTTONDOWN, WM_MBUTTONUP, MK_MBUTTON
+	case MouseX1:
+		down, up, state, xbutton = WM_XBUTTONDOWN, WM_XBUTTONUP, MK_XBUTTON1, XBUTTON1
+	case MouseX2:
+		down, up, state, xbutton = WM_XBUTTONDOWN, WM_XBUTTONUP, MK_XBUTTON2, XBUTTON2
+	default:
+		return fmt.Errorf("unknown mouse button: %d", ev.Button)
+	}
+
+	lParam := makeLParam(x, y)
+	var msg uint32
+	var wParam uintptr
+	switch ev.Kind {
+	case InputMouseDown:
+		procPostMessage.Call(uintptr(hwnd), WM_MOUSEMOVE, 0, lParam)
+		msg, wParam = down, state
+	case InputMouseUp:
+		msg = up
+	default:
+		return fmt.Errorf("not a mouse button event: %s", ev.Kind)
+	}
+	wParam |= xbutton << 16
+
+	ret, _, err := procPostMessage.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+	if ret == 0 {
+		return fmt.Errorf("failed to post mouse message 0x%04x: %v", msg, err)
+	}
+	return nil
+}
+
+// PostKey posts WM_KEYDOWN, WM_KEYUP or WM_CHAR. The raw code of the input
+// events is the virtual key, and their key code the scan code, with 0x0E or
+// 0xE0 in the high byte for extended keys.
+func (b *Win32Backend) PostKey(hwnd WindowHandle, ev InputEvent) error {
+	lParam := uintptr(1) | uintptr(ev.Keycode&0xFF)<<16
+	if high := ev.Keycode >> 8; high == 0x0E || high == 0xE0 {
+		lParam |= 1 << 24
+	}
+
+	var msg uint32
+	var wParam uintptr
+	switch ev.Kind {
+	case InputKeyDown:
+		msg, wParam = WM_KEYDOWN, uintptr(ev.Rawcode)
+	case InputKeyUp:
+		// Previous key state and transition state bits.
+		msg, wParam = WM_KEYUP, uintptr(ev.Rawcode)
+		lParam |= 1<<30 | 1<<31
+	case InputKeyTyped:
+		msg, wParam = WM_CHAR, uintptr(ev.Keychar)
+	default:
+		return fmt.Errorf("not a key event: %s", ev.Kind)
+	}
+	if wParam == 0 {
+		return fmt.Errorf("key event without key code: %s", ev.Kind)
+	}
+
+	ret, _, err := procPostMessage.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+	if ret == 0 {
+		return fmt.Errorf("failed to post key message 0x%04x: %v", msg, err)
+	}
+	return nil
+}
+
+func (b *Win32Backend) WatchShellEvents(stop <-chan struct{}) (<-chan ShellEvent, error) {
+	return watchShellEvents(stop)
+}
+
+// makeLParam packs client coordinates as expected by mouse messages.
+func makeLParam(x, y int) uintptr {
+	return uintptr(uint16(int16(y)))<<16 | uintptr(uint16(int16(x)))
+}
@@ -0,0 +1,856 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/composite"
+	"github.com/jezek/xgb/randr"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// Window manager hint flag signalling a window that needs attention (ICCCM).
+const xUrgencyHint = 1 << 8
+
+// X11Backend implements WindowBackend for X11 desktops following EWMH, which
+// is where the game clients run under Wine or Proton.
+type X11Backend struct {
+	conn     *xgb.Conn
+	display  string
+	root     xproto.Window
+	atoms    map[string]xproto.Atom
+	keycodes map[xproto.Keysym]xproto.Keycode
+	// randr is set when RandR 1.5 monitors are available.
+	randr bool
+	// composite is set when windows can be redirected off-screen, which
+	// keeps the content of covered windows for captures.
+	composite  bool
+	redirected map[xproto.Window]bool
+	mu         sync.Mutex
+}
+
+// NewX11Backend connects to the given X display ($DISPLAY when empty).
+func NewX11Backend(display string) (*X11Backend, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X display %q: %v", display, err)
+	}
+	if err := xtest.Init(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("XTEST extension is not available: %v", err)
+	}
+
+	b := &X11Backend{
+		conn:    conn,
+		display: display,
+		root:    xproto.Setup(conn).DefaultScreen(conn).Root,
+		atoms:   make(map[string]xproto.Atom),
+
+		redirected: make(map[xproto.Window]bool),
+	}
+	if err := randr.Init(conn); err == nil {
+		version, err := randr.QueryVersion(conn, 1, 5).Reply()
+		b.randr = err == nil && (version.MajorVersion > 1 || version.MinorVersion >= 5)
+	}
+	if err := composite.Init(conn); err == nil {
+		_, err := composite.QueryVersion(conn, 0, 2).Reply()
+		b.composite = err == nil
+	}
+	return b, nil
+}
+
+// Close closes the connection to the X server.
+func (b *X11Backend) Close() {
+	b.conn.Close()
+}
+
+// atom interns an atom name, caching the result.
+func (b *X11Backend) atom(name string) xproto.Atom {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if atom, ok := b.atoms[name]; ok {
+		return atom
+	}
+	reply, err := xproto.InternAtom(b.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		log.Printf("Failed to intern atom %s: %v", name, err)
+		return xproto.AtomNone
+	}
+	b.atoms[name] = reply.Atom
+	return reply.Atom
+}
+
+func (b *X11Backend) getProperty(conn *xgb.Conn, win xproto.Window, property string, propertyType xproto.Atom) (*xproto.GetPropertyReply, error) {
+	return xproto.GetProperty(conn, false, win, b.atom(property), propertyType, 0, (1<<32)-1).Reply()
+}
+
+// getWindowList reads a list of windows (or atoms) stored in a 32-bit property.
+func (b *X11Backend) getWindowList(conn *xgb.Conn, win xproto.Window, property string, propertyType xproto.Atom) ([]xproto.Window, error) {
+	reply, err := b.getProperty(conn, win, property, propertyType)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil || reply.Format != 32 {
+		return nil, fmt.Errorf("property %s is not set", property)
+	}
+
+	list := make([]xproto.Window, 0, reply.ValueLen)
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		list = append(list, xproto.Window(xgb.Get32(reply.Value[i:])))
+	}
+	return list, nil
+}
+
+// clientList returns the managed windows listed by the window manager in
+// _NET_CLIENT_LIST_STACKING or _NET_CLIENT_LIST, or the viewable children of
+// the root window when no EWMH window manager is running (e.g. a bare Xvfb
+// server).
+func (b *X11Backend) clientList(conn *xgb.Conn) ([]xproto.Window, error) {
+	for _, property := range []string{"_NET_CLIENT_LIST_STACKING", "_NET_CLIENT_LIST"} {
+		if clients, err := b.getWindowList(conn, b.root, property, xproto.AtomWindow); err == nil {
+			return clients, nil
+		}
+	}
+
+	tree, err := xproto.QueryTree(conn, b.root).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("error enumerating windows: %v", err)
+	}
+
+	var clients []xproto.Window
+	for _, child := range tree.Children {
+		attrs, err := xproto.GetWindowAttributes(conn, child).Reply()
+		if err == nil && attrs.MapState == xproto.MapStateViewable && !attrs.OverrideRedirect {
+			clients = append(clients, child)
+		}
+	}
+	return clients, nil
+}
+
+func (b *X11Backend) EnumWindows() ([]WindowHandle, error) {
+	clients, err := b.clientList(b.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Client lists are ordered from the bottom (or the oldest window), while
+	// WindowBackend enumerates from the top.
+	handles := make([]WindowHandle, 0, len(clients))
+	for i := len(clients) - 1; i >= 0; i-- {
+		handles = append(handles, WindowHandle(clients[i]))
+	}
+	return handles, nil
+}
+
+func (b *X11Backend) GetWindowText(hwnd WindowHandle) string {
+	reply, err := b.getProperty(b.conn, xproto.Window(hwnd), "_NET_WM_NAME", b.atom("UTF8_STRING"))
+	if err == nil && reply != nil && len(reply.Value) > 0 {
+		return string(reply.Value)
+	}
+
+	reply, err = b.getProperty(b.conn, xproto.Window(hwnd), "WM_NAME", xproto.GetPropertyTypeAny)
+	if err == nil && reply != nil {
+		return string(reply.Value)
+	}
+	return ""
+}
+
+func (b *X11Backend) GetWindowInfo(hwnd WindowHandle) (Window, error) {
+	win := xproto.Window(hwnd)
+
+	attrs, err := xproto.GetWindowAttributes(b.conn, win).Reply()
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	geometry, err := xproto.GetGeometry(b.conn, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return Window{}, fmt.Errorf("failed to get window geometry: %v", err)
+	}
+	origin, err := xproto.TranslateCoordinates(b.conn, win, b.root, 0, 0).Reply()
+	if err != nil {
+		return Window{}, fmt.Errorf("failed to get window position: %v", err)
+	}
+
+	window := Window{
+		Handle:       hwnd,
+		Title:        b.GetWindowText(hwnd),
+		ClassName:    b.getClassName(win),
+		ClientWidth:  int(geometry.Width),
+		ClientHeight: int(geometry.Height),
+		Minimized:    b.IsMinimized(hwnd),
+		Visible:      attrs.MapState == xproto.MapStateViewable,
+	}
+
+	// The window rectangle includes the decorations added by the window manager.
+	window.Rect = Rect{
+		Left:   int(origin.DstX),
+		Top:    int(origin.DstY),
+		Right:  int(origin.DstX) + int(geometry.Width),
+		Bottom: int(origin.DstY) + int(geometry.Height),
+	}
+	if extents, err := b.getWindowList(b.conn, win, "_NET_FRAME_EXTENTS", xproto.AtomCardinal); err == nil && len(extents) == 4 {
+		window.Rect.Left -= int(extents[0])
+		window.Rect.Right += int(extents[1])
+		window.Rect.Top -= int(extents[2])
+		window.Rect.Bottom += int(extents[3])
+	}
+
+	if pid, err := b.getWindowList(b.conn, win, "_NET_WM_PID", xproto.AtomCardinal); err == nil && len(pid) > 0 {
+		window.PID = uint32(pid[0])
+		window.ExeName = processExeName(window.PID)
+	}
+
+	return window, nil
+}
+
+// processExeName returns the executable name of a process from its command
+// line, which under Wine is the Windows path of the game rather than the loader.
+func processExeName(pid uint32) string {
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(cmdline) == 0 {
+		return ""
+	}
+	exe := string(bytes.SplitN(cmdline, []byte{0}, 2)[0])
+	return filepath.Base(strings.ReplaceAll(exe, "\\", "/"))
+}
+
+// getClassName returns the class part of WM_CLASS, which Wine sets to the
+// executable name of the game.
+func (b *X11Backend) getClassName(win xproto.Window) string {
+	reply, err := b.getProperty(b.conn, win, "WM_CLASS", xproto.AtomString)
+	if err != nil || reply == nil {
+		return ""
+	}
+	// WM_CLASS holds two NUL terminated strings: instance and class.
+	parts := bytes.Split(bytes.TrimRight(reply.Value, "\x00"), []byte{0})
+	return string(parts[len(parts)-1])
+}
+
+func (b *X11Backend) GetForegroundWindow() WindowHandle {
+	if active, err := b.getWindowList(b.conn, b.root, "_NET_ACTIVE_WINDOW", xproto.AtomWindow); err == nil && len(active) > 0 {
+		return WindowHandle(active[0])
+	}
+
+	focus, err := xproto.GetInputFocus(b.conn).Reply()
+	if err != nil {
+		return 0
+	}
+	return WindowHandle(focus.Focus)
+}
+
+// hasWindowManager reports whether an EWMH compliant window manager is running.
+func (b *X11Backend) hasWindowManager() bool {
+	check, err := b.getWindowList(b.conn, b.root, "_NET_SUPPORTING_WM_CHECK", xproto.AtomWindow)
+	return err == nil && len(check) > 0
+}
+
+// sendRootMessage sends an EWMH client message about win to the window manager.
+func (b *X11Backend) sendRootMessage(win xproto.Window, messageType string, data ...uint32) error {
+	data = append(data, make([]uint32, 5-len(data))...)
+	event := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   b.atom(messageType),
+		Data:   xproto.ClientMessageDataUnionData32New(data),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	return xproto.SendEventChecked(b.conn, false, b.root, mask, string(event.Bytes())).Check()
+}
+
+func (b *X11Backend) SetForegroundWindow(hwnd WindowHandle) error {
+	win := xproto.Window(hwnd)
+
+	if b.hasWindowManager() {
+		// Source indication 2: the request comes from a pager, which window
+		// managers honour without focus stealing prevention.
+		if err := b.sendRootMessage(win, "_NET_ACTIVE_WINDOW", 2, xproto.TimeCurrentTime); err != nil {
+			return fmt.Errorf("failed to set window to the foreground: %v", err)
+		}
+		return nil
+	}
+
+	err := xproto.ConfigureWindowChecked(b.conn, win, xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove}).Check()
+	if err == nil {
+		err = xproto.SetInputFocusChecked(b.conn, xproto.InputFocusPointerRoot, win, xproto.TimeCurrentTime).Check()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set window to the foreground: %v", err)
+	}
+	return nil
+}
+
+func (b *X11Backend) IsMinimized(hwnd WindowHandle) bool {
+	states, err := b.getWindowList(b.conn, xproto.Window(hwnd), "_NET_WM_STATE", xproto.AtomAtom)
+	if err != nil {
+		return false
+	}
+	hidden := xproto.Window(b.atom("_NET_WM_STATE_HIDDEN"))
+	for _, state := range states {
+		if state == hidden {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *X11Backend) RestoreWindow(hwnd WindowHandle) error {
+	// Iconified windows are unmapped, mapping them again lets the window
+	// manager restore them.
+	if err := xproto.MapWindowChecked(b.conn, xproto.Window(hwnd)).Check(); err != nil {
+		return fmt.Errorf("failed to restore window: %v", err)
+	}
+	return nil
+}
+
+// MoveWindow asks the window manager to move the frame of the window, with
+// _NET_MOVERESIZE_WINDOW, or configures the window itself without one.
+func (b *X11Backend) MoveWindow(hwnd WindowHandle, rect Rect) error {
+	win := xproto.Window(hwnd)
+	if b.IsMinimized(hwnd) {
+		if err := b.RestoreWindow(hwnd); err != nil {
+			return err
+		}
+	}
+
+	if !b.hasWindowManager() {
+		values := []uint32{uint32(int32(rect.Left)), uint32(int32(rect.Top)), uint32(rect.Width()), uint32(rect.Height())}
+		mask := uint16(xproto.ConfigWindowX | xproto.ConfigWindowY | xproto.ConfigWindowWidth | xproto.ConfigWindowHeight)
+		if err := xproto.ConfigureWindowChecked(b.conn, win, mask, values).Check(); err != nil {
+			return fmt.Errorf("failed to move window %d: %v", hwnd, err)
+		}
+		return nil
+	}
+
+	// The position is that of the frame (north west gravity), while the size
+	// is that of the client, without the decorations.
+	width, height := rect.Width(), rect.Height()
+	if extents, err := b.getWindowList(b.conn, win, "_NET_FRAME_EXTENTS", xproto.AtomCardinal); err == nil && len(extents) == 4 {
+		width -= int(extents[0] + extents[1])
+		height -= int(extents[2] + extents[3])
+	}
+	const flags = xproto.GravityNorthWest | 0xF<<8 | 2<<12 // x, y, width and height from a pager
+	err := b.sendRootMessage(win, "_NET_MOVERESIZE_WINDOW", flags,
+		uint32(int32(rect.Left)), uint32(int32(rect.Top)), uint32(max(width, 1)), uint32(max(height, 1)))
+	if err != nil {
+		return fmt.Errorf("failed to move window %d: %v", hwnd, err)
+	}
+	return nil
+}
+
+// Monitors lists the RandR monitors, or the whole screen as one monitor
+// when RandR 1.5 is not available. The DPI derives from the physical size
+// reported by the outputs, and the work area of the current desktop is
+// shared by the monitors.
+func (b *X11Backend) Monitors() ([]Monitor, error) {
+	var monitors []Monitor
+	if b.randr {
+		reply, err := randr.GetMonitors(b.conn, b.root, true).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("error enumerating monitors: %v", err)
+		}
+		for _, info := range reply.Monitors {
+			monitor := Monitor{
+				Primary: info.Primary,
+				Bounds: Rect{
+					Left:   int(info.X),
+					Top:    int(info.Y),
+					Right:  int(info.X) + int(info.Width),
+					Bottom: int(info.Y) + int(info.Height),
+				},
+				DPI: x11DPI(int(info.Width), int(info.WidthInMillimeters)),
+			}
+			if name, err := xproto.GetAtomName(b.conn, info.Name).Reply(); err == nil {
+				monitor.Name = name.Name
+			}
+			monitors = append(monitors, monitor)
+		}
+	}
+	if len(monitors) == 0 {
+		screen := xproto.Setup(b.conn).DefaultScreen(b.conn)
+		monitors = []Monitor{{
+			Name:    b.display,
+			Primary: true,
+			Bounds:  Rect{Right: int(screen.WidthInPixels), Bottom: int(screen.HeightInPixels)},
+			DPI:     x11DPI(int(screen.WidthInPixels), int(screen.WidthInMillimeters)),
+		}}
+	}
+
+	workArea, hasWorkArea := b.workArea()
+	for i := range monitors {
+		monitor := &monitors[i]
+		monitor.Scale = float64(monitor.DPI) / BaseDPI
+		monitor.WorkArea = monitor.Bounds
+		if hasWorkArea {
+			monitor.WorkArea = intersect(monitor.Bounds, workArea)
+		}
+	}
+	return monitors, nil
+}
+
+// workArea returns the work area of the current desktop from _NET_WORKAREA.
+func (b *X11Backend) workArea() (Rect, bool) {
+	area, err := b.getWindowList(b.conn, b.root, "_NET_WORKAREA", xproto.AtomCardinal)
+	if err != nil || len(area) < 4 {
+		return Rect{}, false
+	}
+	desktop := 0
+	if current, err := b.getWindowList(b.conn, b.root, "_NET_CURRENT_DESKTOP", xproto.AtomCardinal); err == nil && len(current) > 0 && len(area) >= 4*(int(current[0])+1) {
+		desktop = int(current[0])
+	}
+	x, y := int(int32(area[4*desktop])), int(int32(area[4*desktop+1]))
+	return Rect{Left: x, Top: y, Right: x + int(area[4*desktop+2]), Bottom: y + int(area[4*desktop+3])}, true
+}
+
+// x11DPI computes the resolution from the physical width, BaseDPI when the
+// display does not report it.
+func x11DPI(pixels, millimeters int) int {
+	if millimeters <= 0 {
+		return BaseDPI
+	}
+	return int(math.Round(float64(pixels) * 25.4 / float64(millimeters)))
+}
+
+// intersect returns the part of a inside b, or a when they do not overlap.
+func intersect(a, b Rect) Rect {
+	r := Rect{Left: max(a.Left, b.Left), Top: max(a.Top, b.Top), Right: min(a.Right, b.Right), Bottom: min(a.Bottom, b.Bottom)}
+	if r.Width() <= 0 || r.Height() <= 0 {
+		return a
+	}
+	return r
+}
+
+// CaptureClient reads the content of the window with GetImage. Covered parts
+// are only kept by the server for redirected windows, so the window is
+// redirected off-screen on its first capture when Composite is available:
+// as the client has to repaint first, that capture may still miss them.
+func (b *X11Backend) CaptureClient(hwnd WindowHandle) (*image.RGBA, error) {
+	win := xproto.Window(hwnd)
+	geometry, err := xproto.GetGeometry(b.conn, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("invalid window handle: %d", hwnd)
+	}
+	if b.composite {
+		b.redirect(win)
+	}
+
+	reply, err := xproto.GetImage(b.conn, xproto.ImageFormatZPixmap, xproto.Drawable(win),
+		0, 0, geometry.Width, geometry.Height, math.MaxUint32).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture window %d: %v", hwnd, err)
+	}
+
+	setup := xproto.Setup(b.conn)
+	bitsPerPixel := 0
+	for _, format := range setup.PixmapFormats {
+		if format.Depth == reply.Depth {
+			bitsPerPixel = int(format.BitsPerPixel)
+		}
+	}
+	width, height := int(geometry.Width), int(geometry.Height)
+	if bitsPerPixel != 32 || (reply.Depth != 24 && reply.Depth != 32) || len(reply.Data) < width*height*4 {
+		return nil, fmt.Errorf("unsupported pixel format for window %d: depth %d, %d bits per pixel", hwnd, reply.Depth, bitsPerPixel)
+	}
+
+	// Pixels are XRGB words in the byte order of the server.
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	red, green, blue := 2, 1, 0
+	if setup.ImageByteOrder == xproto.ImageOrderMSBFirst {
+		red, green, blue = 1, 2, 3
+	}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = reply.Data[i+red], reply.Data[i+green], reply.Data[i+blue], 0xFF
+	}
+	return img, nil
+}
+
+// redirect redirects a window off-screen once. The server keeps painting it
+// on screen (automatic update), and a compositing manager having already
+// redirected it is not an error.
+func (b *X11Backend) redirect(win xproto.Window) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.redirected[win] {
+		return
+	}
+	b.redirected[win] = true
+	if err := composite.RedirectWindowChecked(b.conn, win, composite.RedirectAutomatic).Check(); err != nil {
+		log.Printf("Window %d is not redirected, its covered parts may be missing from captures: %v", win, err)
+	}
+}
+
+func (b *X11Backend) GetCursorPos() (int, int) {
+	pointer, err := xproto.QueryPointer(b.conn, b.root).Reply()
+	if err != nil {
+		return 0, 0
+	}
+	return int(pointer.RootX), int(pointer.RootY)
+}
+
+func (b *X11Backend) SetCursorPos(x, y int) error {
+	err := xproto.WarpPointerChecked(b.conn, xproto.WindowNone, b.root, 0, 0, 0, 0, int16(x), int16(y)).Check()
+	if err != nil {
+		return fmt.Errorf("failed to set cursor position to X=%d, Y=%d: %v", x, y, err)
+	}
+	return nil
+}
+
+func (b *X11Backend) ScreenToClient(hwnd WindowHandle, x, y int) (int, int, error) {
+	reply, err := xproto.TranslateCoordinates(b.conn, b.root, xproto.Window(hwnd), int16(x), int16(y)).Reply()
+	if err != nil {
+		return x, y, fmt.Errorf("failed to convert screen coordinates to client coordinates: %v", err)
+	}
+	return int(reply.DstX), int(reply.DstY), nil
+}
+
+func (b *X11Backend) ClientToScreen(hwnd WindowHandle, x, y int) (int, int, error) {
+	reply, err := xproto.TranslateCoordinates(b.conn, xproto.Window(hwnd), b.root, int16(x), int16(y)).Reply()
+	if err != nil {
+		return x, y, fmt.Errorf("failed to convert client coordinates to screen coordinates: %v", err)
+	}
+	return int(reply.DstX), int(reply.DstY), nil
+}
+
+// PostClick sends synthetic pointer events to the window. Unlike XTEST input
+// they leave the pointer alone, but clients may ignore them.
+func (b *X11Backend) PostClick(hwnd WindowHandle, x, y int) error {
+	window := xproto.Window(hwnd)
+	pos, err := xproto.TranslateCoordinates(b.conn, window, b.root, int16(x), int16(y)).Reply()
+	if err != nil {
+		return fmt.Errorf("failed to convert client coordinates to screen coordinates: %v", err)
+	}
+
+	events := []struct {
+		mask  uint32
+		bytes []byte
+	}{
+		{xproto.EventMaskPointerMotion, xproto.MotionNotifyEvent{
+			Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), SameScreen: true,
+		}.Bytes()},
+		{xproto.EventMaskButtonPress, xproto.ButtonPressEvent{
+			Detail: 1, Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), SameScreen: true,
+		}.Bytes()},
+		{xproto.EventMaskButtonRelease, xproto.ButtonReleaseEvent{
+			Detail: 1, Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), State: xproto.ButtonMask1, SameScreen: true,
+		}.Bytes()},
+	}
+	for _, event := range events {
+		if err := xproto.SendEventChecked(b.conn, true, window, event.mask, string(event.bytes)).Check(); err != nil {
+			return fmt.Errorf("failed to send pointer event: %v", err)
+		}
+	}
+	return nil
+}
+
+// x11Buttons maps the mouse buttons to the X button numbers.
+var x11Buttons = map[MouseButton]xproto.Button{
+	MouseLeft:   1,
+	MouseMiddle: 2,
+	MouseRight:  3,
+	MouseX1:     8,
+	MouseX2:     9,
+}
+
+// PostMouse sends a synthetic button event to the window, preceded by the
+// pointer moving over it for a press.
+func (b *X11Backend) PostMouse(hwnd WindowHandle, ev InputEvent, x, y int) error {
+	button, ok := x11Buttons[ev.Button]
+	if !ok {
+		return fmt.Errorf("unknown mouse button: %d", ev.Button)
+	}
+	window := xproto.Window(hwnd)
+	pos, err := xproto.TranslateCoordinates(b.conn, window, b.root, int16(x), int16(y)).Reply()
+	if err != nil {
+		return fmt.Errorf("failed to convert client coordinates to screen coordinates: %v", err)
+	}
+
+	var mask uint32
+	var bytes []byte
+	switch ev.Kind {
+	case InputMouseDown:
+		motion := xproto.MotionNotifyEvent{
+			Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), SameScreen: true,
+		}
+		if err := xproto.SendEventChecked(b.conn, true, window, xproto.EventMaskPointerMotion, string(motion.Bytes())).Check(); err != nil {
+			return fmt.Errorf("failed to send pointer event: %v", err)
+		}
+		mask, bytes = xproto.EventMaskButtonPress, xproto.ButtonPressEvent{
+			Detail: button, Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), SameScreen: true,
+		}.Bytes()
+	case InputMouseUp:
+		var state uint16
+		if button <= 5 {
+			state = xproto.ButtonMask1 << (button - 1)
+		}
+		mask, bytes = xproto.EventMaskButtonRelease, xproto.ButtonReleaseEvent{
+			Detail: button, Time: xproto.TimeCurrentTime, Root: b.root, Event: window, RootX: pos.DstX, RootY: pos.DstY,
+			EventX: int16(x), EventY: int16(y), State: state, SameScreen: true,
+		}.Bytes()
+	default:
+		return fmt.Errorf("not a mouse button event: %s", ev.Kind)
+	}
+	if err := xproto.SendEventChecked(b.conn, true, window, mask, string(bytes)).Check(); err != nil {
+		return fmt.Errorf("failed to send pointer event: %v", err)
+	}
+	return nil
+}
+
+// PostKey sends a synthetic key event to the window. The raw code of the
+// input events is the keysym, typed events are not sent since clients derive
+// characters from key presses.
+func (b *X11Backend) PostKey(hwnd WindowHandle, ev InputEvent) error {
+	var send func(keycode xproto.Keycode) []byte
+	var mask uint32
+	switch ev.Kind {
+	case InputKeyDown:
+		mask = xproto.EventMaskKeyPress
+		send = func(keycode xproto.Keycode) []byte {
+			return xproto.KeyPressEvent{Detail: keycode, Time: xproto.TimeCurrentTime, Root: b.root,
+				Event: xproto.Window(hwnd), SameScreen: true}.Bytes()
+		}
+	case InputKeyUp:
+		mask = xproto.EventMaskKeyRelease
+		send = func(keycode xproto.Keycode) []byte {
+			return xproto.KeyReleaseEvent{Detail: keycode, Time: xproto.TimeCurrentTime, Root: b.root,
+				Event: xproto.Window(hwnd), SameScreen: true}.Bytes()
+		}
+	case InputKeyTyped:
+		return nil
+	default:
+		return fmt.Errorf("not a key event: %s", ev.Kind)
+	}
+
+	keycode, err := b.keycode(xproto.Keysym(ev.Rawcode))
+	if err != nil {
+		return err
+	}
+	err = xproto.SendEventChecked(b.conn, true, xproto.Window(hwnd), mask, string(send(keycode))).Check()
+	if err != nil {
+		return fmt.Errorf("failed to send key event: %v", err)
+	}
+	return nil
+}
+
+// keycode returns the key code producing a keysym, from the keyboard
+// mapping read on first use.
+func (b *X11Backend) keycode(keysym xproto.Keysym) (xproto.Keycode, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.keycodes == nil {
+		setup := xproto.Setup(b.conn)
+		count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+		mapping, err := xproto.GetKeyboardMapping(b.conn, setup.MinKeycode, count).Reply()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the keyboard mapping: %v", err)
+		}
+		b.keycodes = make(map[xproto.Keysym]xproto.Keycode)
+		perKeycode := int(mapping.KeysymsPerKeycode)
+		for i, sym := range mapping.Keysyms {
+			if _, ok := b.keycodes[sym]; !ok && sym != 0 {
+				b.keycodes[sym] = setup.MinKeycode + xproto.Keycode(i/perKeycode)
+			}
+		}
+	}
+
+	keycode, ok := b.keycodes[keysym]
+	if !ok {
+		return 0, fmt.Errorf("no key code for keysym 0x%x", keysym)
+	}
+	return keycode, nil
+}
+
+// SendClick injects a left click through XTEST. Synthetic events sent with
+// SendEvent are ignored by most clients, Wine included.
+func (b *X11Backend) SendClick(hwnd WindowHandle, x, y int) error {
+	pos, err := xproto.TranslateCoordinates(b.conn, xproto.Window(hwnd), b.root, int16(x), int16(y)).Reply()
+	if err != nil {
+		return fmt.Errorf("failed to convert client coordinates to screen coordinates: %v", err)
+	}
+
+	inputs := []struct {
+		kind   byte
+		detail byte
+	}{
+		{xproto.MotionNotify, 0},
+		{xproto.ButtonPress, 1},
+		{xproto.ButtonRelease, 1},
+	}
+	for i, input := range inputs {
+		if i == len(inputs)-1 {
+			time.Sleep(15 * time.Millisecond)
+		}
+		err := xtest.FakeInputChecked(b.conn, input.kind, input.detail, 0, b.root, pos.DstX, pos.DstY, 0).Check()
+		if err != nil {
+			return fmt.Errorf("failed to inject click: %v", err)
+		}
+	}
+	return nil
+}
+
+// WatchShellEvents derives shell events from property changes: activation from
+// _NET_ACTIVE_WINDOW, creation and destruction from _NET_CLIENT_LIST (or root
+// window map notifications without a window manager), and attention requests
+// from _NET_WM_STATE_DEMANDS_ATTENTION or the urgency hint.
+// It uses its own connection so that waiting for events does not interfere
+// with the requests of the backend.
+func (b *X11Backend) WatchShellEvents(stop <-chan struct{}) (<-chan ShellEvent, error) {
+	conn, err := xgb.NewConnDisplay(b.display)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X display %q: %v", b.display, err)
+	}
+
+	err = xproto.ChangeWindowAttributesChecked(conn, b.root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange | xproto.EventMaskSubstructureNotify}).Check()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to select root window events: %v", err)
+	}
+
+	watcher := &x11ShellWatcher{
+		backend:   b,
+		conn:      conn,
+		events:    make(chan ShellEvent, 64),
+		clients:   make(map[xproto.Window]bool),
+		attention: make(map[xproto.Window]bool),
+	}
+	watcher.refreshClients(false)
+
+	go func() {
+		<-stop
+		conn.Close()
+	}()
+	go watcher.run()
+
+	return watcher.events, nil
+}
+
+// x11ShellWatcher tracks client windows to turn X events into shell events.
+type x11ShellWatcher struct {
+	backend   *X11Backend
+	conn      *xgb.Conn
+	events    chan ShellEvent
+	clients   map[xproto.Window]bool
+	attention map[xproto.Window]bool
+}
+
+func (w *x11ShellWatcher) run() {
+	defer close(w.events)
+
+	for {
+		ev, xerr := w.conn.WaitForEvent()
+		if ev == nil && xerr == nil {
+			log.Println("X connection closed, exiting event loop.")
+			return
+		}
+		if xerr != nil {
+			log.Printf("X error received: %v", xerr)
+			continue
+		}
+
+		b := w.backend
+		switch ev.(type) {
+		case xproto.MapNotifyEvent, xproto.UnmapNotifyEvent, xproto.DestroyNotifyEvent:
+			// Without a window manager the client list is never updated.
+			if !b.hasWindowManager() {
+				w.refreshClients(true)
+			}
+			continue
+		}
+
+		notify, ok := ev.(xproto.PropertyNotifyEvent)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case notify.Window == b.root && notify.Atom == b.atom("_NET_ACTIVE_WINDOW"):
+			if active, err := b.getWindowList(w.conn, b.root, "_NET_ACTIVE_WINDOW", xproto.AtomWindow); err == nil && len(active) > 0 && active[0] != 0 {
+				w.emit(ShellEvent{Code: ShellWindowActivated, Window: WindowHandle(active[0])})
+			}
+		case notify.Window == b.root && notify.Atom == b.atom("_NET_CLIENT_LIST"):
+			w.refreshClients(true)
+		case notify.Window != b.root && (notify.Atom == b.atom("_NET_WM_STATE") || notify.Atom == xproto.AtomWmHints):
+			w.checkAttention(notify.Window)
+		case notify.Window != b.root && (notify.Atom == b.atom("_NET_WM_NAME") || notify.Atom == xproto.AtomWmName):
+			w.emit(ShellEvent{Code: ShellWindowRedraw, Window: WindowHandle(notify.Window)})
+		}
+	}
+}
+
+// refreshClients diffs the client list against the known clients and
+// subscribes to the property changes of new ones.
+func (w *x11ShellWatcher) refreshClients(notify bool) {
+	clients, err := w.backend.clientList(w.conn)
+	if err != nil {
+		log.Printf("Failed to refresh client list: %v", err)
+		return
+	}
+
+	current := make(map[xproto.Window]bool, len(clients))
+	for _, client := range clients {
+		current[client] = true
+		if w.clients[client] {
+			continue
+		}
+		xproto.ChangeWindowAttributes(w.conn, client, xproto.CwEventMask, []uint32{xproto.EventMaskPropertyChange})
+		if notify {
+			w.emit(ShellEvent{Code: ShellWindowCreated, Window: WindowHandle(client)})
+		}
+	}
+	for client := range w.clients {
+		if !current[client] {
+			delete(w.attention, client)
+			w.emit(ShellEvent{Code: ShellWindowDestroyed, Window: WindowHandle(client)})
+		}
+	}
+	w.clients = current
+}
+
+// checkAttention emits a flash event when a window starts requesting attention.
+func (w *x11ShellWatcher) checkAttention(win xproto.Window) {
+	b := w.backend
+	demanding := false
+
+	if states, err := b.getWindowList(w.conn, win, "_NET_WM_STATE", xproto.AtomAtom); err == nil {
+		attention := xproto.Window(b.atom("_NET_WM_STATE_DEMANDS_ATTENTION"))
+		for _, state := range states {
+			if state == attention {
+				demanding = true
+			}
+		}
+	}
+	if hints, err := b.getProperty(w.conn, win, "WM_HINTS", xproto.AtomWmHints); err == nil && hints != nil && len(hints.Value) >= 4 {
+		if xgb.Get32(hints.Value)&xUrgencyHint != 0 {
+			demanding = true
+		}
+	}
+
+	if demanding && !w.attention[win] {
+		w.emit(ShellEvent{Code: ShellWindowFlash, Window: WindowHandle(win)})
+	}
+	w.attention[win] = demanding
+}
+
+func (w *x11ShellWatcher) emit(event ShellEvent) {
+	select {
+	case w.events <- event:
+	default:
+		log.Printf("Shell event dropped, consumer is too slow: %+v", event)
+	}
+}
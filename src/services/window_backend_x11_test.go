@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// These tests run against the X server of $DISPLAY, e.g. a local Xvfb
+// server started with "Xvfb :99 &" and DISPLAY=:99, and are skipped when it
+// is not set.
+
+// openX11Window opens a top-level window with the given title on its own
+// connection, reporting button events.
+func openX11Window(t *testing.T, title string) (*xgb.Conn, xproto.Window) {
+	t.Helper()
+	conn, err := xgb.NewConn()
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", os.Getenv("DISPLAY"), err)
+	}
+	t.Cleanup(conn.Close)
+
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = xproto.CreateWindowChecked(conn, screen.RootDepth, win, screen.Root, 10, 10, 200, 100, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual, xproto.CwBackPixel|xproto.CwEventMask,
+		[]uint32{screen.WhitePixel, xproto.EventMaskButtonPress | xproto.EventMaskButtonRelease}).Check()
+	if err != nil {
+		t.Fatalf("failed to create a window: %v", err)
+	}
+
+	utf8, err := xproto.InternAtom(conn, false, uint16(len("UTF8_STRING")), "UTF8_STRING").Reply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	netName, err := xproto.InternAtom(conn, false, uint16(len("_NET_WM_NAME")), "_NET_WM_NAME").Reply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xproto.ChangeProperty(conn, xproto.PropModeReplace, win, xproto.AtomWmName, xproto.AtomString, 8, uint32(len(title)), []byte(title))
+	xproto.ChangeProperty(conn, xproto.PropModeReplace, win, netName.Atom, utf8.Atom, 8, uint32(len(title)), []byte(title))
+	if err := xproto.MapWindowChecked(conn, win).Check(); err != nil {
+		t.Fatalf("failed to map the window: %v", err)
+	}
+	return conn, win
+}
+
+// newX11TestBackend connects an X11Backend to $DISPLAY, skipping the test
+// without one.
+func newX11TestBackend(t *testing.T) *X11Backend {
+	t.Helper()
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY is not set, start Xvfb to run the X11 backend tests")
+	}
+	backend, err := NewX11Backend("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// waitFor polls condition until it holds, the window manager handling
+// requests asynchronously.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestX11BackendEnumeratesAndNamesWindows(t *testing.T) {
+	backend := newX11TestBackend(t)
+	title := fmt.Sprintf("Xtest%d - Dofus 2.70.5", os.Getpid())
+	_, win := openX11Window(t, title)
+	hwnd := WindowHandle(win)
+
+	waitFor(t, "the window to be listed", func() bool {
+		handles, err := backend.EnumWindows()
+		return err == nil && slices.Contains(handles, hwnd)
+	})
+	if got := backend.GetWindowText(hwnd); got != title {
+		t.Fatalf("GetWindowText = %q, want %q", got, title)
+	}
+
+	windows, err := NewWindowService(backend).ListWindows(WindowFilter{Title: title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 || windows[0].Handle != hwnd || windows[0].ClientWidth != 200 || windows[0].ClientHeight != 100 {
+		t.Fatalf("ListWindows = %+v, want the 200x100 window %d", windows, hwnd)
+	}
+}
+
+func TestX11BackendFocusesWindows(t *testing.T) {
+	backend := newX11TestBackend(t)
+	_, first := openX11Window(t, fmt.Sprintf("Xfocus%d-1", os.Getpid()))
+	_, second := openX11Window(t, fmt.Sprintf("Xfocus%d-2", os.Getpid()))
+	ws := NewWindowService(backend)
+
+	for _, win := range []xproto.Window{first, second, first} {
+		hwnd := WindowHandle(win)
+		waitFor(t, "the window to be listed", func() bool {
+			handles, err := backend.EnumWindows()
+			return err == nil && slices.Contains(handles, hwnd)
+		})
+		if err := ws.FocusWindow(hwnd); err != nil {
+			t.Fatalf("FocusWindow(%d): %v", hwnd, err)
+		}
+		waitFor(t, fmt.Sprintf("window %d to have focus", hwnd), func() bool {
+			return backend.GetForegroundWindow() == hwnd
+		})
+	}
+}
+
+func TestX11BackendSendsClicks(t *testing.T) {
+	backend := newX11TestBackend(t)
+	conn, win := openX11Window(t, fmt.Sprintf("Xclick%d", os.Getpid()))
+	hwnd := WindowHandle(win)
+	waitFor(t, "the window to be listed", func() bool {
+		handles, err := backend.EnumWindows()
+		return err == nil && slices.Contains(handles, hwnd)
+	})
+	// The click goes to the window under the pointer, which must be on top.
+	if err := backend.SetForegroundWindow(hwnd); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the window to have focus", func() bool {
+		return backend.GetForegroundWindow() == hwnd
+	})
+
+	if err := backend.SendClick(hwnd, 30, 40); err != nil {
+		t.Fatalf("SendClick: %v", err)
+	}
+
+	received := make(chan xproto.ButtonPressEvent, 1)
+	go func() {
+		for {
+			event, err := conn.WaitForEvent()
+			if event == nil && err == nil {
+				return // connection closed
+			}
+			if press, ok := event.(xproto.ButtonPressEvent); ok {
+				received <- press
+				return
+			}
+		}
+	}()
+	select {
+	case press := <-received:
+		if press.Event != win || press.Detail != 1 || press.EventX != 30 || press.EventY != 40 {
+			t.Fatalf("button press = %+v, want button 1 at (30, 40) in window %d", press, win)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the window received no button press")
+	}
+}
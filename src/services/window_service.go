@@ -3,125 +3,331 @@ package services
 import (
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"strings"
-	"syscall"
 )
 
-const (
-	SW_RESTORE = 9
-)
+// Rect is a rectangle in screen coordinates.
+type Rect struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+// Width returns the width of the rectangle.
+func (r Rect) Width() int {
+	return r.Right - r.Left
+}
+
+// Height returns the height of the rectangle.
+func (r Rect) Height() int {
+	return r.Bottom - r.Top
+}
+
+// Contains reports whether the point is inside the rectangle.
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.Left && x < r.Right && y >= r.Top && y < r.Bottom
+}
+
+// BaseDPI is the resolution of a monitor at 100% scaling.
+const BaseDPI = 96
 
-type WindowService struct{}
+// Monitor describes a display. Coordinates are physical pixels of the
+// virtual screen, negative left of or above the primary monitor.
+type Monitor struct {
+	Name    string `json:"name"`
+	Primary bool   `json:"primary"`
+	Bounds  Rect   `json:"bounds"`
+	// WorkArea is the part of the monitor not covered by task bars and docks.
+	WorkArea Rect `json:"workArea"`
+	// DPI is the effective resolution, BaseDPI at 100% scaling.
+	DPI   int     `json:"dpi"`
+	Scale float64 `json:"scale"`
+}
 
-func (ws *WindowService) GetWindows() ([]string, error) {
-	var windowsList []string
+// Scaled converts a length in pixels at 100% scaling to the monitor scaling.
+func (m Monitor) Scaled(pixels int) int {
+	if m.DPI <= 0 {
+		return pixels
+	}
+	return int(math.Round(float64(pixels) * float64(m.DPI) / BaseDPI))
+}
+
+// Window describes a top-level window.
+type Window struct {
+	Handle       WindowHandle `json:"handle" swaggertype:"integer"`
+	Title        string       `json:"title"`
+	PID          uint32       `json:"pid"`
+	ExeName      string       `json:"exeName"`
+	ClassName    string       `json:"className"`
+	Rect         Rect         `json:"rect"`
+	ClientWidth  int          `json:"clientWidth"`
+	ClientHeight int          `json:"clientHeight"`
+	Minimized    bool         `json:"minimized"`
+	Visible      bool         `json:"visible"`
+	Foreground   bool         `json:"foreground"`
+}
 
-	enumFunc := func(hwnd syscall.Handle, lParam uintptr) uintptr {
-		length := GetWindowTextLength(hwnd)
-		if length > 0 {
-			windowText := GetWindowText(hwnd)
-			windowsList = append(windowsList, windowText)
+// WindowFilter selects windows in ListWindows. Zero values match every window.
+type WindowFilter struct {
+	// Game matches windows whose title or executable name contains it, ignoring case.
+	Game      string
+	Title     string
+	Visible   *bool
+	Minimized *bool
+}
+
+// Match reports whether the window satisfies the filter.
+func (f WindowFilter) Match(w Window) bool {
+	if f.Game != "" {
+		game := strings.ToLower(f.Game)
+		if !strings.Contains(strings.ToLower(w.Title), game) && !strings.Contains(strings.ToLower(w.ExeName), game) {
+			return false
 		}
-		return 1 // Continue enumeration
 	}
+	if f.Title != "" && !strings.Contains(w.Title, f.Title) {
+		return false
+	}
+	if f.Visible != nil && w.Visible != *f.Visible {
+		return false
+	}
+	if f.Minimized != nil && w.Minimized != *f.Minimized {
+		return false
+	}
+	return true
+}
+
+type WindowService struct {
+	backend WindowBackend
+}
+
+// NewWindowService creates a new WindowService on top of the given backend.
+func NewWindowService(backend WindowBackend) *WindowService {
+	return &WindowService{backend: backend}
+}
+
+// Backend returns the window backend used by the service.
+func (ws *WindowService) Backend() WindowBackend {
+	return ws.backend
+}
+
+// GetWindows returns every titled top-level window.
+func (ws *WindowService) GetWindows() ([]Window, error) {
+	return ws.ListWindows(WindowFilter{})
+}
+
+// ListWindows returns the titled top-level windows matching the filter, in z-order.
+func (ws *WindowService) ListWindows(filter WindowFilter) ([]Window, error) {
+	var windowsList []Window
 
-	if err := EnumWindows(enumFunc, 0); err != nil && err.Error() != "The operation completed successfully." {
-		return nil, fmt.Errorf("error enumerating windows: %v", err)
+	handles, err := ws.backend.EnumWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	foreground := ws.backend.GetForegroundWindow()
+	for _, hwnd := range handles {
+		if ws.backend.GetWindowText(hwnd) == "" {
+			continue
+		}
+
+		window, err := ws.backend.GetWindowInfo(hwnd)
+		if err != nil {
+			// The window may have been closed since the enumeration.
+			log.Printf("Failed to describe window %d: %v", hwnd, err)
+			continue
+		}
+		window.Foreground = hwnd == foreground
+
+		if filter.Match(window) {
+			windowsList = append(windowsList, window)
+		}
 	}
 
 	return windowsList, nil
 }
 
+// GetWindow describes a single window.
+func (ws *WindowService) GetWindow(hwnd WindowHandle) (Window, error) {
+	window, err := ws.backend.GetWindowInfo(hwnd)
+	if err != nil {
+		return Window{}, err
+	}
+	window.Foreground = hwnd == ws.backend.GetForegroundWindow()
+	return window, nil
+}
+
 func (ws *WindowService) FocusWindowWithTitle(keyword string) error {
 	if keyword == "" {
 		return fmt.Errorf("window title keyword is empty")
 	}
 
-	var found bool
-	enumFunc := func(hwnd syscall.Handle, lParam uintptr) uintptr {
-		length := GetWindowTextLength(hwnd)
-		if length > 0 {
-			windowText := GetWindowText(hwnd)
-
-			if strings.Contains(windowText, keyword) {
-				log.Printf("Found matching window: '%s'", windowText)
-
-				// Restore the window if it is minimized
-				if IsIconic(hwnd) {
-					ShowWindow(hwnd, SW_RESTORE)
-				}
+	hwnd, err := ws.FindWindowByPartialTitle(keyword)
+	if err != nil {
+		return err
+	}
+	log.Printf("Found matching window: '%s'", ws.backend.GetWindowText(hwnd))
 
-				// Set the window to the foreground
-				err := ws.setForegroundWindow(hwnd)
-				if err != nil {
-					log.Printf("Failed to set foreground window: %v", err)
-				} else {
-					log.Println("Window successfully brought to the foreground")
-				}
+	return ws.FocusWindow(hwnd)
+}
 
-				found = true
-				return 0 // Stop enumeration
-			}
+// FocusWindow restores the window if it is minimized and brings it to the foreground.
+func (ws *WindowService) FocusWindow(hwnd WindowHandle) error {
+	if ws.backend.IsMinimized(hwnd) {
+		if err := ws.backend.RestoreWindow(hwnd); err != nil {
+			log.Printf("Failed to restore window: %v", err)
 		}
-		return 1 // Continue enumeration
 	}
 
-	if err := EnumWindows(enumFunc, 0); err != nil {
-		return fmt.Errorf("error enumerating windows: %v", err)
+	if err := ws.backend.SetForegroundWindow(hwnd); err != nil {
+		log.Printf("Failed to set foreground window: %v", err)
+		return err
 	}
+	log.Println("Window successfully brought to the foreground")
+	return nil
+}
 
-	if !found {
-		return fmt.Errorf("no window found with keyword: %s", keyword)
-	}
+func (ws *WindowService) FindWindowByPartialTitle(partialTitle string) (WindowHandle, error) {
+	return ws.findWindow(func(windowText string) bool {
+		return strings.Contains(windowText, partialTitle)
+	}, partialTitle)
+}
 
-	return nil
+// GetWindowHandle retrieves the handle of the topmost window with the given
+// title. Windows with the same title are not told apart, ListWindows returns
+// each of them.
+func (ws *WindowService) GetWindowHandle(title string) (WindowHandle, error) {
+	return ws.findWindow(func(windowText string) bool {
+		return windowText == title
+	}, title)
 }
 
-func (ws *WindowService) setForegroundWindow(hwnd syscall.Handle) error {
-	if SetForegroundWindow(hwnd) {
-		return nil
+func (ws *WindowService) findWindow(match func(windowText string) bool, title string) (WindowHandle, error) {
+	handles, err := ws.backend.EnumWindows()
+	if err != nil {
+		return 0, err
 	}
 
-	fgWindow := GetForegroundWindow()
-	if fgWindow == 0 {
-		return fmt.Errorf("failed to retrieve the foreground window")
+	for _, hwnd := range handles {
+		windowText := ws.backend.GetWindowText(hwnd)
+		if windowText != "" && match(windowText) {
+			return hwnd, nil
+		}
 	}
 
-	fgThreadID, _ := GetWindowThreadProcessId(fgWindow)
-	targetThreadID, _ := GetWindowThreadProcessId(hwnd)
+	return 0, fmt.Errorf("no window found with title: %s", title)
+}
+
+// Monitors returns the monitors, the primary one first.
+func (ws *WindowService) Monitors() ([]Monitor, error) {
+	monitors, err := ws.backend.Monitors()
+	if err != nil {
+		return nil, err
+	}
+	if len(monitors) == 0 {
+		return nil, fmt.Errorf("no monitor found")
+	}
+	sort.SliceStable(monitors, func(i, j int) bool {
+		return monitors[i].Primary && !monitors[j].Primary
+	})
+	return monitors, nil
+}
 
-	if AttachThreadInput(fgThreadID, targetThreadID, true) {
-		defer AttachThreadInput(fgThreadID, targetThreadID, false)
-		if SetForegroundWindow(hwnd) {
-			return nil
+// MonitorAt returns the monitor containing the screen coordinates, or the
+// nearest one.
+func (ws *WindowService) MonitorAt(x, y int) (Monitor, error) {
+	monitors, err := ws.Monitors()
+	if err != nil {
+		return Monitor{}, err
+	}
+
+	nearest, best := monitors[0], math.MaxFloat64
+	for _, monitor := range monitors {
+		if monitor.Bounds.Contains(x, y) {
+			return monitor, nil
+		}
+		dx := float64(max(monitor.Bounds.Left-x, 0, x-monitor.Bounds.Right+1))
+		dy := float64(max(monitor.Bounds.Top-y, 0, y-monitor.Bounds.Bottom+1))
+		if distance := math.Hypot(dx, dy); distance < best {
+			nearest, best = monitor, distance
 		}
 	}
+	return nearest, nil
+}
 
-	return fmt.Errorf("failed to set window to the foreground")
+// MonitorOf returns the monitor showing the center of the window.
+func (ws *WindowService) MonitorOf(hwnd WindowHandle) (Monitor, error) {
+	window, err := ws.backend.GetWindowInfo(hwnd)
+	if err != nil {
+		return Monitor{}, err
+	}
+	return ws.MonitorAt((window.Rect.Left+window.Rect.Right)/2, (window.Rect.Top+window.Rect.Bottom)/2)
 }
 
-func (ws *WindowService) FindWindowByPartialTitle(partialTitle string) (syscall.Handle, error) {
-	var hwndFound syscall.Handle
-	enumFunc := func(hwnd syscall.Handle, lParam uintptr) uintptr {
-		length := GetWindowTextLength(hwnd)
-		if length > 0 {
-			windowText := GetWindowText(hwnd)
+func (ws *WindowService) GetForegroundWindow() WindowHandle {
+	return ws.backend.GetForegroundWindow()
+}
 
-			if strings.Contains(windowText, partialTitle) {
-				hwndFound = hwnd
-				return 0 // Stop enumeration
-			}
-		}
-		return 1 // Continue enumeration
+func (ws *WindowService) GetWindowText(hwnd WindowHandle) string {
+	return ws.backend.GetWindowText(hwnd)
+}
+
+// RelativePoint is a position in the client area of a window, normalized to
+// its size: (0, 0) is the top left corner and (1, 1) the bottom right one.
+type RelativePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ScreenToRelative converts screen coordinates to a position relative to the
+// client area of hwnd. The position is outside [0, 1) when the point is not
+// over the client area.
+func (ws *WindowService) ScreenToRelative(hwnd WindowHandle, x, y int) (RelativePoint, error) {
+	window, err := ws.backend.GetWindowInfo(hwnd)
+	if err != nil {
+		return RelativePoint{}, err
+	}
+	if window.ClientWidth <= 0 || window.ClientHeight <= 0 {
+		return RelativePoint{}, fmt.Errorf("window %d has an empty client area", hwnd)
+	}
+	clientX, clientY, err := ws.backend.ScreenToClient(hwnd, x, y)
+	if err != nil {
+		return RelativePoint{}, err
 	}
+	// Pixel centers, so that a point maps back to the same pixel in a
+	// window of the same size.
+	return RelativePoint{
+		X: (float64(clientX) + 0.5) / float64(window.ClientWidth),
+		Y: (float64(clientY) + 0.5) / float64(window.ClientHeight),
+	}, nil
+}
 
-	if err := EnumWindows(enumFunc, 0); err != nil {
-		return 0, fmt.Errorf("error enumerating windows: %v", err)
+// RelativeToClient converts a relative position to client coordinates of hwnd.
+func (ws *WindowService) RelativeToClient(hwnd WindowHandle, point RelativePoint) (int, int, error) {
+	window, err := ws.backend.GetWindowInfo(hwnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if window.ClientWidth <= 0 || window.ClientHeight <= 0 {
+		return 0, 0, fmt.Errorf("window %d has an empty client area", hwnd)
 	}
+	return int(math.Floor(point.X * float64(window.ClientWidth))),
+		int(math.Floor(point.Y * float64(window.ClientHeight))), nil
+}
 
-	if hwndFound == 0 {
-		return 0, fmt.Errorf("no window found with title: %s", partialTitle)
+// RelativeToScreen converts a relative position to screen coordinates over
+// the client area of hwnd.
+func (ws *WindowService) RelativeToScreen(hwnd WindowHandle, point RelativePoint) (int, int, error) {
+	clientX, clientY, err := ws.RelativeToClient(hwnd, point)
+	if err != nil {
+		return 0, 0, err
 	}
+	return ws.backend.ClientToScreen(hwnd, clientX, clientY)
+}
 
-	return hwndFound, nil
+// Inside reports whether the relative position is over the client area.
+func (p RelativePoint) Inside() bool {
+	return p.X >= 0 && p.X < 1 && p.Y >= 0 && p.Y < 1
 }
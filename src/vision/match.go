@@ -0,0 +1,257 @@
+// Package vision finds reference images, such as the buttons of the game,
+// inside captured window frames. It is written in pure Go, so that it builds
+// without OpenCV on every platform.
+package vision
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+const (
+	// DefaultThreshold is the minimum score of a match when none is given.
+	DefaultThreshold = 0.8
+	// MinScale and MaxScale bound the scales a template is looked for at.
+	MinScale = 0.25
+	MaxScale = 4
+)
+
+const (
+	// coarseMargin lowers the threshold down the pyramid, where details are
+	// blurred and scores lower.
+	coarseMargin = 0.25
+	// maxCandidates bounds the positions refined per scale.
+	maxCandidates = 64
+)
+
+// Options restricts and tunes a search.
+type Options struct {
+	// Region is the part of the frame searched, in frame coordinates, the
+	// whole frame when empty.
+	Region image.Rectangle
+	// Scales are the sizes of the template tried, relative to the reference
+	// image, e.g. 0.9, 1 and 1.1 when the window may be resized slightly.
+	// Only 1 is tried when empty.
+	Scales []float64
+	// Threshold is the minimum score of a match, from 0 to 1,
+	// DefaultThreshold when zero.
+	Threshold float64
+	// MaxMatches bounds the matches returned by FindAll, all of them when
+	// zero.
+	MaxMatches int
+}
+
+// checked validates the options and fills in the defaults.
+func (options Options) checked(frame image.Rectangle) (Options, error) {
+	if options.Region.Empty() {
+		options.Region = frame
+	}
+	if options.Region = options.Region.Intersect(frame); options.Region.Empty() {
+		return options, fmt.Errorf("%w: region is outside of the frame", ErrInvalidOptions)
+	}
+	if len(options.Scales) == 0 {
+		options.Scales = []float64{1}
+	}
+	for _, scale := range options.Scales {
+		if !(scale >= MinScale && scale <= MaxScale) {
+			return options, fmt.Errorf("%w: scale %v is not between %v and %v", ErrInvalidOptions, scale, MinScale, MaxScale)
+		}
+	}
+	if options.Threshold == 0 {
+		options.Threshold = DefaultThreshold
+	}
+	if !(options.Threshold > 0 && options.Threshold <= 1) {
+		return options, fmt.Errorf("%w: threshold must be greater than 0 and at most 1", ErrInvalidOptions)
+	}
+	if options.MaxMatches < 0 {
+		return options, fmt.Errorf("%w: max matches is negative", ErrInvalidOptions)
+	}
+	return options, nil
+}
+
+// Match is an occurrence of a template in a frame.
+type Match struct {
+	Template string `json:"template"`
+	// Rect is the area of the frame matching the template, in frame
+	// coordinates.
+	Rect image.Rectangle `json:"rect"`
+	// Score is the normalized correlation of the area with the template,
+	// 1 for a perfect match.
+	Score float64 `json:"score"`
+	// Scale is the scale of the template that matched.
+	Scale float64 `json:"scale"`
+}
+
+// Center returns the center of the matching area, e.g. to click it.
+func (m Match) Center() image.Point {
+	return image.Pt((m.Rect.Min.X+m.Rect.Max.X)/2, (m.Rect.Min.Y+m.Rect.Max.Y)/2)
+}
+
+// Find returns the best match of the template in frame, ErrNotFound when
+// no area scores the threshold.
+func (t *Template) Find(frame image.Image, options Options) (Match, error) {
+	options.MaxMatches = 1
+	matches, err := t.FindAll(frame, options)
+	if err != nil {
+		return Match{}, err
+	}
+	if len(matches) == 0 {
+		return Match{}, fmt.Errorf("%w: %s", ErrNotFound, t.Name)
+	}
+	return matches[0], nil
+}
+
+// FindAll returns the non-overlapping matches of the template in frame,
+// best first. The search is coarse to fine: the template is correlated
+// with every position of a downsampled frame, then the best positions are
+// refined level after level up to the full resolution.
+func (t *Template) FindAll(frame image.Image, options Options) ([]Match, error) {
+	options, err := options.checked(frame.Bounds())
+	if err != nil {
+		return nil, err
+	}
+
+	// The frame pyramid is shared by the scales, and built lazily.
+	frames := []*plane{grayPlane(frame, options.Region)}
+	level := func(n int) *plane {
+		for len(frames) <= n {
+			frames = append(frames, frames[len(frames)-1].half())
+		}
+		return frames[n]
+	}
+
+	var matches []Match
+	for _, scale := range options.Scales {
+		patterns := t.pyramid(scale)
+		// The search starts from the coarsest level where the template, and
+		// the finer ones, fit the region.
+		top := -1
+		for n, p := range patterns {
+			if p.width > level(n).width || p.height > level(n).height {
+				break
+			}
+			top = n
+		}
+		if top < 0 {
+			continue
+		}
+
+		threshold := options.Threshold
+		if top > 0 {
+			threshold -= coarseMargin
+		}
+		for _, candidate := range candidates(patterns[top], level(top), threshold) {
+			for n := top - 1; n >= 0; n-- {
+				candidate = refine(patterns[n], level(n), image.Pt(2*candidate.X, 2*candidate.Y))
+			}
+			score := patterns[0].score(level(0), candidate.X, candidate.Y)
+			if score < options.Threshold {
+				continue
+			}
+			origin := options.Region.Min.Add(candidate)
+			matches = append(matches, Match{
+				Template: t.Name,
+				Rect:     image.Rectangle{Min: origin, Max: origin.Add(image.Pt(patterns[0].width, patterns[0].height))},
+				Score:    score,
+				Scale:    scale,
+			})
+		}
+	}
+	return suppress(matches, options.MaxMatches), nil
+}
+
+// candidates returns the local maxima of the correlation of p with frame
+// scoring at least threshold, best first.
+func candidates(p *pattern, frame *plane, threshold float64) []image.Point {
+	width, height := frame.width-p.width+1, frame.height-p.height+1
+	scores := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			scores[y*width+x] = p.score(frame, x, y)
+		}
+	}
+
+	var points []image.Point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			score := scores[y*width+x]
+			if score < threshold || !localMaximum(scores, width, height, x, y) {
+				continue
+			}
+			points = append(points, image.Pt(x, y))
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return scores[points[i].Y*width+points[i].X] > scores[points[j].Y*width+points[j].X]
+	})
+	return points[:min(len(points), maxCandidates)]
+}
+
+// localMaximum reports whether no neighbour of (x, y) scores higher, ties
+// going to the first position in reading order.
+func localMaximum(scores []float64, width, height, x, y int) bool {
+	score := scores[y*width+x]
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= width || ny >= height {
+				continue
+			}
+			neighbour := scores[ny*width+nx]
+			if neighbour > score || (neighbour == score && (dy < 0 || (dy == 0 && dx < 0))) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// refine returns the best position of p in frame around guess, the position
+// of a coarser level doubled.
+func refine(p *pattern, frame *plane, guess image.Point) image.Point {
+	guess.X = min(guess.X, frame.width-p.width)
+	guess.Y = min(guess.Y, frame.height-p.height)
+	best, bestScore := guess, -2.0
+	for y := max(guess.Y-2, 0); y <= min(guess.Y+2, frame.height-p.height); y++ {
+		for x := max(guess.X-2, 0); x <= min(guess.X+2, frame.width-p.width); x++ {
+			if score := p.score(frame, x, y); score > bestScore {
+				best, bestScore = image.Pt(x, y), score
+			}
+		}
+	}
+	return best
+}
+
+// suppress keeps the best of the matches overlapping by more than half of
+// the smaller one, best first, at most limit of them when it is not zero.
+func suppress(matches []Match, limit int) []Match {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	var kept []Match
+	for _, match := range matches {
+		overlapping := false
+		for _, other := range kept {
+			overlap := match.Rect.Intersect(other.Rect)
+			smaller := min(area(match.Rect), area(other.Rect))
+			if 2*area(overlap) > smaller {
+				overlapping = true
+				break
+			}
+		}
+		if overlapping {
+			continue
+		}
+		kept = append(kept, match)
+		if len(kept) == limit {
+			break
+		}
+	}
+	return kept
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
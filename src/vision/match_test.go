@@ -0,0 +1,170 @@
+package vision
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// The fixtures under testdata are the button to end a turn, pass.png,
+// pasted on synthetic frames, and a ring-shaped icon transparent inside and
+// outside, pasted on different backgrounds.
+
+func loadTemplate(t *testing.T, name string) *Template {
+	t.Helper()
+	template, err := LoadTemplate(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("LoadTemplate(%s): %v", name, err)
+	}
+	return template
+}
+
+func loadFrame(t *testing.T, name string) image.Image {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	frame, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return frame
+}
+
+// origins returns the top-left corners of the matches, top to bottom.
+func origins(matches []Match) []image.Point {
+	points := make([]image.Point, 0, len(matches))
+	for _, match := range matches {
+		points = append(points, match.Rect.Min)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Y < points[j].Y
+	})
+	return points
+}
+
+func TestLoadTemplateIsNamedAfterTheFile(t *testing.T) {
+	template := loadTemplate(t, "button.png")
+	if template.Name != "button" || template.Size() != image.Pt(174, 53) {
+		t.Fatalf("template %q of %v, want button of 174x53", template.Name, template.Size())
+	}
+}
+
+func TestFindAllExactMatches(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+	frame := loadFrame(t, "frame.png")
+
+	matches, err := button.FindAll(frame, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Point{{60, 110}, {280, 240}}
+	got := origins(matches)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("matches at %v, want %v", got, want)
+	}
+	for _, match := range matches {
+		if match.Score < 0.99 || match.Scale != 1 || match.Rect.Size() != button.Size() {
+			t.Errorf("match = %+v, want a perfect match of the size of the template", match)
+		}
+	}
+}
+
+func TestFindInRegion(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+	frame := loadFrame(t, "frame.png")
+
+	match, err := button.Find(frame, Options{Region: image.Rect(240, 200, 480, 320)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Rect.Min != image.Pt(280, 240) {
+		t.Fatalf("match at %v, want (280,240) in frame coordinates", match.Rect.Min)
+	}
+	if center := match.Center(); center != image.Pt(367, 266) {
+		t.Fatalf("center = %v, want (367,266)", center)
+	}
+
+	// The region is searched as a whole: a match must fit in it.
+	_, err = button.Find(frame, Options{Region: image.Rect(240, 200, 400, 320)})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find in a region cutting the button: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFindAcrossScales(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+	frame := loadFrame(t, "frame_scaled.png")
+
+	match, err := button.Find(frame, Options{Scales: []float64{0.8, 1, 1.25}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Scale != 1.25 || match.Score < 0.9 {
+		t.Fatalf("match = %+v, want the template scaled by 1.25", match)
+	}
+	if d := match.Rect.Min.Sub(image.Pt(120, 100)); d.X < -1 || d.X > 1 || d.Y < -1 || d.Y > 1 {
+		t.Fatalf("match at %v, want (120,100)", match.Rect.Min)
+	}
+}
+
+func TestFindIgnoresTransparentPixels(t *testing.T) {
+	ring := loadTemplate(t, "ring.png")
+	frame := loadFrame(t, "ring_frame.png")
+
+	matches, err := ring.FindAll(frame, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Point{{40, 50}, {231, 69}}
+	got := origins(matches)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("matches at %v, want %v whatever the background", got, want)
+	}
+}
+
+func TestFindReportsMissingTemplates(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+
+	_, err := button.Find(loadFrame(t, "ring_frame.png"), Options{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find: got %v, want ErrNotFound", err)
+	}
+	matches, err := button.FindAll(loadFrame(t, "ring_frame.png"), Options{})
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("FindAll = %v, %v, want no match", matches, err)
+	}
+}
+
+func TestFindInFrameSmallerThanTemplate(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+	frame := loadFrame(t, "frame.png").(interface {
+		SubImage(image.Rectangle) image.Image
+	}).SubImage(image.Rect(60, 110, 160, 150))
+
+	if _, err := button.Find(frame, Options{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestFindRejectsInvalidOptions(t *testing.T) {
+	button := loadTemplate(t, "button.png")
+	frame := loadFrame(t, "frame.png")
+
+	for _, options := range []Options{
+		{Region: image.Rect(500, 0, 600, 100)},
+		{Scales: []float64{0.1}},
+		{Threshold: 1.5},
+		{MaxMatches: -1},
+	} {
+		if _, err := button.FindAll(frame, options); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("FindAll(%+v): got %v, want ErrInvalidOptions", options, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package vision
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// plane is a single channel image of floating point samples.
+type plane struct {
+	width, height int
+	pix           []float32
+}
+
+func newPlane(width, height int) *plane {
+	return &plane{width: width, height: height, pix: make([]float32, width*height)}
+}
+
+// luma weights of ITU-R BT.601, as used by most grayscale conversions.
+func luma(r, g, b uint8) float32 {
+	return 0.299*float32(r) + 0.587*float32(g) + 0.114*float32(b)
+}
+
+// grayPlane converts the part rect of img to grayscale. Captures being
+// *image.RGBA, and decoded PNG files *image.RGBA or *image.NRGBA, those are
+// read directly.
+func grayPlane(img image.Image, rect image.Rectangle) *plane {
+	p := newPlane(rect.Dx(), rect.Dy())
+	switch src := img.(type) {
+	case *image.RGBA:
+		for y := 0; y < p.height; y++ {
+			row := src.Pix[src.PixOffset(rect.Min.X, rect.Min.Y+y):]
+			for x := 0; x < p.width; x++ {
+				p.pix[y*p.width+x] = luma(row[4*x], row[4*x+1], row[4*x+2])
+			}
+		}
+	case *image.NRGBA:
+		for y := 0; y < p.height; y++ {
+			row := src.Pix[src.PixOffset(rect.Min.X, rect.Min.Y+y):]
+			for x := 0; x < p.width; x++ {
+				p.pix[y*p.width+x] = luma(row[4*x], row[4*x+1], row[4*x+2])
+			}
+		}
+	default:
+		for y := 0; y < p.height; y++ {
+			for x := 0; x < p.width; x++ {
+				c := color.NRGBAModel.Convert(img.At(rect.Min.X+x, rect.Min.Y+y)).(color.NRGBA)
+				p.pix[y*p.width+x] = luma(c.R, c.G, c.B)
+			}
+		}
+	}
+	return p
+}
+
+// alphaPlane returns the opacity of img, from 0 to 1, or nil when img is
+// opaque.
+func alphaPlane(img image.Image) *plane {
+	bounds := img.Bounds()
+	p := newPlane(bounds.Dx(), bounds.Dy())
+	opaque := true
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			_, _, _, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			p.pix[y*p.width+x] = float32(a) / 0xFFFF
+			opaque = opaque && a == 0xFFFF
+		}
+	}
+	if opaque {
+		return nil
+	}
+	return p
+}
+
+// half downsamples p by two, each sample being the average of four.
+func (p *plane) half() *plane {
+	h := newPlane(p.width/2, p.height/2)
+	for y := 0; y < h.height; y++ {
+		top, bottom := p.pix[2*y*p.width:], p.pix[(2*y+1)*p.width:]
+		for x := 0; x < h.width; x++ {
+			h.pix[y*h.width+x] = (top[2*x] + top[2*x+1] + bottom[2*x] + bottom[2*x+1]) / 4
+		}
+	}
+	return h
+}
+
+// halfMask downsamples the mask p by two, each sample being the least
+// opaque of the four it covers and of their neighbors. Samples of a
+// downsampled frame at the edges of the opaque pixels account for the
+// background too, all the more as a coarse level cannot tell apart matches
+// offset by one pixel.
+func (p *plane) halfMask() *plane {
+	h := newPlane(p.width/2, p.height/2)
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			opacity := float32(1)
+			for j := max(2*y-1, 0); j <= min(2*y+2, p.height-1); j++ {
+				for i := max(2*x-1, 0); i <= min(2*x+2, p.width-1); i++ {
+					opacity = min(opacity, p.pix[j*p.width+i])
+				}
+			}
+			h.pix[y*h.width+x] = opacity
+		}
+	}
+	return h
+}
+
+// resize scales p to width x height with bilinear interpolation, halving it
+// first while it is more than twice as large, so that every sample still
+// accounts for the samples it covers.
+func (p *plane) resize(width, height int) *plane {
+	for p.width >= 2*width && p.height >= 2*height && p.width > 1 && p.height > 1 {
+		p = p.half()
+	}
+	if p.width == width && p.height == height {
+		return p
+	}
+
+	r := newPlane(width, height)
+	scaleX, scaleY := float64(p.width)/float64(width), float64(p.height)/float64(height)
+	for y := 0; y < height; y++ {
+		sy := math.Max((float64(y)+0.5)*scaleY-0.5, 0)
+		y0 := min(int(sy), p.height-1)
+		y1 := min(y0+1, p.height-1)
+		fy := float32(sy - float64(y0))
+		for x := 0; x < width; x++ {
+			sx := math.Max((float64(x)+0.5)*scaleX-0.5, 0)
+			x0 := min(int(sx), p.width-1)
+			x1 := min(x0+1, p.width-1)
+			fx := float32(sx - float64(x0))
+
+			top := p.pix[y0*p.width+x0]*(1-fx) + p.pix[y0*p.width+x1]*fx
+			bottom := p.pix[y1*p.width+x0]*(1-fx) + p.pix[y1*p.width+x1]*fx
+			r.pix[y*width+x] = top*(1-fy) + bottom*fy
+		}
+	}
+	return r
+}
@@ -0,0 +1,213 @@
+package vision
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid template")
+	ErrInvalidOptions  = errors.New("invalid match options")
+	ErrNotFound        = errors.New("template not found")
+)
+
+const (
+	// minPatternSide is the smallest side of a template down the pyramid.
+	minPatternSide = 12
+	// maxLevels bounds the pyramid, 8 times smaller than the frame at most.
+	maxLevels = 3
+	// maxTemplateSide bounds the size of templates, which are meant to be
+	// parts of a frame.
+	maxTemplateSide = 4096
+	// flatness is the variance, per sample, below which an area has no
+	// contrast to correlate.
+	flatness = 1e-3
+)
+
+// Template is a reference image looked for in frames. Its transparent
+// pixels are left out of the comparison, so that a button can be found
+// whatever is drawn around it.
+type Template struct {
+	Name string
+
+	gray *plane
+	mask *plane // nil when opaque
+
+	mu       sync.Mutex
+	pyramids map[float64][]*pattern // keyed by scale
+}
+
+// pattern is a template prepared for correlation at one scale and pyramid
+// level.
+type pattern struct {
+	width, height int
+	// weights are the samples minus their mean, times the opacity.
+	weights []float32
+	mask    []float32 // nil when opaque
+	// count is the total opacity and norm the standard deviation of the
+	// weighted samples times the square root of count.
+	count, norm float64
+}
+
+// NewTemplate prepares a template from a reference image.
+func NewTemplate(name string, img image.Image) (*Template, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("%w: %s is empty", ErrInvalidTemplate, name)
+	}
+	if bounds.Dx() > maxTemplateSide || bounds.Dy() > maxTemplateSide {
+		return nil, fmt.Errorf("%w: %s is larger than %d pixels", ErrInvalidTemplate, name, maxTemplateSide)
+	}
+
+	t := &Template{
+		Name:     name,
+		gray:     grayPlane(img, bounds),
+		mask:     alphaPlane(img),
+		pyramids: make(map[float64][]*pattern),
+	}
+	if newPattern(t.gray, t.mask) == nil {
+		return nil, fmt.Errorf("%w: %s has no contrast", ErrInvalidTemplate, name)
+	}
+	return t, nil
+}
+
+// LoadTemplate reads a template from a PNG file, named after the file
+// without its extension.
+func LoadTemplate(path string) (*Template, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, path, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return NewTemplate(name, img)
+}
+
+// Size returns the size of the reference image.
+func (t *Template) Size() image.Point {
+	return image.Pt(t.gray.width, t.gray.height)
+}
+
+// pyramid returns the template scaled by scale, then halved level after
+// level while it keeps enough contrast and samples.
+func (t *Template) pyramid(scale float64) []*pattern {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if levels, ok := t.pyramids[scale]; ok {
+		return levels
+	}
+
+	gray, mask := t.gray, t.mask
+	if scale != 1 {
+		width := max(int(math.Round(float64(gray.width)*scale)), 1)
+		height := max(int(math.Round(float64(gray.height)*scale)), 1)
+		gray = gray.resize(width, height)
+		if mask != nil {
+			mask = mask.resize(width, height)
+		}
+	}
+
+	var levels []*pattern
+	for {
+		level := newPattern(gray, mask)
+		if level == nil {
+			break
+		}
+		levels = append(levels, level)
+		if len(levels) > maxLevels || min(gray.width, gray.height)/2 < minPatternSide {
+			break
+		}
+		gray = gray.half()
+		if mask != nil {
+			mask = mask.halfMask()
+		}
+	}
+	t.pyramids[scale] = levels
+	return levels
+}
+
+// newPattern prepares gray for correlation, nil when it has no contrast.
+func newPattern(gray, mask *plane) *pattern {
+	p := &pattern{width: gray.width, height: gray.height, weights: make([]float32, len(gray.pix))}
+	if mask != nil {
+		p.mask = mask.pix
+	}
+
+	var sum float64
+	for i, value := range gray.pix {
+		weight := 1.0
+		if mask != nil {
+			weight = float64(mask.pix[i])
+		}
+		p.count += weight
+		sum += weight * float64(value)
+	}
+	if p.count < 1 {
+		return nil
+	}
+
+	mean := sum / p.count
+	var variance float64
+	for i, value := range gray.pix {
+		weight := 1.0
+		if mask != nil {
+			weight = float64(mask.pix[i])
+		}
+		centered := float64(value) - mean
+		p.weights[i] = float32(weight * centered)
+		variance += weight * centered * centered
+	}
+	if variance < flatness*p.count {
+		return nil
+	}
+	p.norm = math.Sqrt(variance)
+	return p
+}
+
+// score correlates the pattern with the area of frame at (x, y): 1 for the
+// same image, whatever its brightness and contrast, 0 for unrelated
+// images or an area without contrast.
+func (p *pattern) score(frame *plane, x, y int) float64 {
+	var cross, sum, squares float64
+	for j := 0; j < p.height; j++ {
+		row := frame.pix[(y+j)*frame.width+x:][:p.width]
+		weights := p.weights[j*p.width:][:p.width]
+		var rowCross, rowSum, rowSquares float32
+		if p.mask == nil {
+			for i, value := range row {
+				rowCross += weights[i] * value
+				rowSum += value
+				rowSquares += value * value
+			}
+		} else {
+			mask := p.mask[j*p.width:][:p.width]
+			for i, value := range row {
+				rowCross += weights[i] * value
+				rowSum += mask[i] * value
+				rowSquares += mask[i] * value * value
+			}
+		}
+		cross += float64(rowCross)
+		sum += float64(rowSum)
+		squares += float64(rowSquares)
+	}
+
+	variance := squares - sum*sum/p.count
+	if variance < flatness*p.count {
+		return 0
+	}
+	// Rounding may take perfect matches slightly above 1.
+	return min(cross/(p.norm*math.Sqrt(variance)), 1)
+}